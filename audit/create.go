@@ -2,9 +2,12 @@ package audit
 
 import (
 	"bytes"
+	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
+	"strconv"
+	"strings"
 
 	dstu2pb "github.com/google/fhir/go/proto/google/fhir/proto/dstu2/resources_go_proto"
 	stu3pb "github.com/google/fhir/go/proto/google/fhir/proto/stu3/resources_go_proto"
@@ -15,6 +18,10 @@ func (c *Client) CreateAuditEvent(event *dstu2pb.AuditEvent) (*stu3pb.ContainedR
 	if err != nil {
 		return nil, nil, err
 	}
+	return c.createAuditEventJSON(eventJSON)
+}
+
+func (c *Client) createAuditEventJSON(eventJSON []byte) (*stu3pb.ContainedResource, *Response, error) {
 	req, err := c.newAuditRequest("POST", "core/audit/AuditEvent", eventJSON, nil)
 	if err != nil {
 		return nil, nil, fmt.Errorf("audit.CreateAuditEvent: %w", err)
@@ -39,3 +46,78 @@ func (c *Client) CreateAuditEvent(event *dstu2pb.AuditEvent) (*stu3pb.ContainedR
 	}
 	return contained, resp, doErr
 }
+
+// auditBatch is a FHIR batch Bundle of audit events
+type auditBatch struct {
+	ResourceType string            `json:"resourceType"`
+	Type         string            `json:"type"`
+	Entry        []auditBatchEntry `json:"entry"`
+}
+
+type auditBatchEntry struct {
+	Resource json.RawMessage `json:"resource"`
+	Request  struct {
+		Method string `json:"method"`
+		URL    string `json:"url"`
+	} `json:"request"`
+}
+
+// createAuditEventsJSON posts events as a batch Bundle and returns the HTTP status of
+// each entry. ErrBadRequest is returned when the Bundle is rejected as a whole and
+// ErrNonHttp20xResponse when the response does not report the status of every entry,
+// as the events cannot be considered delivered then.
+func (c *Client) createAuditEventsJSON(events [][]byte) ([]int, error) {
+	batch := auditBatch{ResourceType: "Bundle", Type: "batch", Entry: make([]auditBatchEntry, len(events))}
+	for i, event := range events {
+		batch.Entry[i].Resource = event
+		batch.Entry[i].Request.Method = "POST"
+		batch.Entry[i].Request.URL = "AuditEvent"
+	}
+	bodyBytes, err := json.Marshal(batch)
+	if err != nil {
+		return nil, err
+	}
+	req, err := c.newAuditRequest("POST", "core/audit/AuditEvent", bodyBytes, nil)
+	if err != nil {
+		return nil, fmt.Errorf("audit.createAuditEvents: %w", err)
+	}
+	_ = c.httpSigner.SignRequest(req)
+	var batchResponse bytes.Buffer
+	resp, err := c.do(req, &batchResponse)
+	if resp == nil {
+		if err == nil {
+			err = ErrEmptyResult
+		}
+		return nil, err
+	}
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+	var response struct {
+		Entry []struct {
+			Response struct {
+				Status string `json:"status"`
+			} `json:"response"`
+		} `json:"entry"`
+	}
+	if err := json.Unmarshal(batchResponse.Bytes(), &response); err != nil {
+		return nil, fmt.Errorf("batch response: %v: %w", err, ErrNonHttp20xResponse)
+	}
+	if len(response.Entry) != len(events) {
+		return nil, fmt.Errorf("batch response has %d entries for %d events: %w", len(response.Entry), len(events), ErrNonHttp20xResponse)
+	}
+	statuses := make([]int, len(events))
+	for i, entry := range response.Entry {
+		// The status starts with the status code, e.g. "201 Created"
+		fields := strings.Fields(entry.Response.Status)
+		if len(fields) == 0 {
+			return nil, fmt.Errorf("batch response entry %d has no status: %w", i, ErrNonHttp20xResponse)
+		}
+		code, err := strconv.Atoi(fields[0])
+		if err != nil {
+			return nil, fmt.Errorf("batch response entry %d status %q: %w", i, entry.Response.Status, ErrNonHttp20xResponse)
+		}
+		statuses[i] = code
+	}
+	return statuses, nil
+}
@@ -0,0 +1,249 @@
+package audit
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	apb "github.com/google/fhir/go/proto/google/fhir/proto/annotations_go_proto"
+	dstu2pb "github.com/google/fhir/go/proto/google/fhir/proto/dstu2/resources_go_proto"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// UnmarshalAuditEvent decodes a FHIR DSTU2 JSON AuditEvent. The jsonformat
+// Unmarshaller only supports STU3 and R4, so the DSTU2 protos are populated
+// using the same FHIR annotations it relies on. Unknown fields are ignored.
+func UnmarshalAuditEvent(data []byte) (*dstu2pb.AuditEvent, error) {
+	var object map[string]json.RawMessage
+	if err := json.Unmarshal(data, &object); err != nil {
+		return nil, err
+	}
+	var resourceType string
+	_ = json.Unmarshal(object["resourceType"], &resourceType)
+	if resourceType != "AuditEvent" {
+		return nil, fmt.Errorf("%w: %q", ErrUnexpectedResourceType, resourceType)
+	}
+	event := &dstu2pb.AuditEvent{}
+	if err := mergeObject(object, event.ProtoReflect()); err != nil {
+		return nil, err
+	}
+	return event, nil
+}
+
+// mergeObject sets the fields of pb from a FHIR JSON object
+func mergeObject(object map[string]json.RawMessage, pb protoreflect.Message) error {
+	fields := pb.Descriptor().Fields()
+	for key, value := range object {
+		if key == "resourceType" || key == "contained" || strings.HasPrefix(key, "_") {
+			continue
+		}
+		if fd := fields.ByJSONName(key); fd != nil {
+			if err := mergeField(pb, fd, value); err != nil {
+				return fmt.Errorf("%s: %w", key, err)
+			}
+			continue
+		}
+		// Choice fields, e.g. valueString is the string field of the value choice
+		for i := 0; i < fields.Len(); i++ {
+			fd := fields.Get(i)
+			if fd.Kind() != protoreflect.MessageKind || !isChoice(fd.Message()) || !strings.HasPrefix(key, fd.JSONName()) {
+				continue
+			}
+			name := strings.TrimPrefix(key, fd.JSONName())
+			if cf := fd.Message().Fields().ByJSONName(strings.ToLower(name[:1]) + name[1:]); cf != nil {
+				if err := mergeField(pb.Mutable(fd).Message(), cf, value); err != nil {
+					return fmt.Errorf("%s: %w", key, err)
+				}
+				break
+			}
+		}
+	}
+	return nil
+}
+
+func mergeField(pb protoreflect.Message, fd protoreflect.FieldDescriptor, value json.RawMessage) error {
+	if fd.Kind() != protoreflect.MessageKind {
+		return nil
+	}
+	if fd.IsList() {
+		var elements []json.RawMessage
+		if err := json.Unmarshal(value, &elements); err != nil {
+			return err
+		}
+		list := pb.Mutable(fd).List()
+		for _, e := range elements {
+			element := list.NewElement()
+			if err := mergeValue(element.Message(), e); err != nil {
+				return err
+			}
+			list.Append(element)
+		}
+		return nil
+	}
+	return mergeValue(pb.Mutable(fd).Message(), value)
+}
+
+// mergeValue sets pb from a JSON object or, for primitive types, a JSON value
+func mergeValue(pb protoreflect.Message, value json.RawMessage) error {
+	value = bytes.TrimSpace(value)
+	if len(value) > 0 && value[0] == '{' {
+		var object map[string]json.RawMessage
+		if err := json.Unmarshal(value, &object); err != nil {
+			return err
+		}
+		return mergeObject(object, pb)
+	}
+	fields := pb.Descriptor().Fields()
+	if fd := fields.ByName("value_us"); fd != nil {
+		return mergeTime(pb, value)
+	}
+	fd := fields.ByName("value")
+	if fd == nil {
+		return nil
+	}
+	switch fd.Kind() {
+	case protoreflect.StringKind:
+		var s string
+		if err := json.Unmarshal(value, &s); err != nil {
+			// Decimals are kept as their JSON representation
+			s = string(value)
+		}
+		pb.Set(fd, protoreflect.ValueOfString(s))
+	case protoreflect.BoolKind:
+		var b bool
+		if err := json.Unmarshal(value, &b); err != nil {
+			return err
+		}
+		pb.Set(fd, protoreflect.ValueOfBool(b))
+	case protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Sfixed32Kind:
+		var i int32
+		if err := json.Unmarshal(value, &i); err != nil {
+			return err
+		}
+		pb.Set(fd, protoreflect.ValueOfInt32(i))
+	case protoreflect.Uint32Kind, protoreflect.Fixed32Kind:
+		var i uint32
+		if err := json.Unmarshal(value, &i); err != nil {
+			return err
+		}
+		pb.Set(fd, protoreflect.ValueOfUint32(i))
+	case protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind:
+		var i int64
+		if err := json.Unmarshal(value, &i); err != nil {
+			return err
+		}
+		pb.Set(fd, protoreflect.ValueOfInt64(i))
+	case protoreflect.BytesKind:
+		var s string
+		if err := json.Unmarshal(value, &s); err != nil {
+			return err
+		}
+		data, err := base64.StdEncoding.DecodeString(s)
+		if err != nil {
+			return err
+		}
+		pb.Set(fd, protoreflect.ValueOfBytes(data))
+	case protoreflect.EnumKind:
+		var code string
+		if err := json.Unmarshal(value, &code); err != nil {
+			return err
+		}
+		ev := enumValue(fd.Enum(), code)
+		if ev == nil {
+			return fmt.Errorf("%w: %q is not a %s", ErrInvalidCode, code, fd.Enum().FullName().Parent().Name())
+		}
+		pb.Set(fd, protoreflect.ValueOfEnum(ev.Number()))
+	}
+	return nil
+}
+
+// enumValue finds the enum value of a FHIR code by name or original code
+func enumValue(ed protoreflect.EnumDescriptor, code string) protoreflect.EnumValueDescriptor {
+	name := strings.Replace(strings.ToUpper(code), "-", "_", -1)
+	if ev := ed.Values().ByName(protoreflect.Name(name)); ev != nil && ev.Number() != 0 {
+		return ev
+	}
+	values := ed.Values()
+	for i := 0; i < values.Len(); i++ {
+		ev := values.Get(i)
+		if original, ok := proto.GetExtension(ev.Options(), apb.E_FhirOriginalCode).(string); ok && original == code {
+			return ev
+		}
+	}
+	return nil
+}
+
+// mergeTime sets a date, dateTime, instant or time primitive
+func mergeTime(pb protoreflect.Message, value json.RawMessage) error {
+	var s string
+	if err := json.Unmarshal(value, &s); err != nil {
+		return err
+	}
+	fields := pb.Descriptor().Fields()
+	var t time.Time
+	var precision string
+	var err error
+	switch {
+	case len(s) == 4:
+		t, err = time.Parse("2006", s)
+		precision = "YEAR"
+	case len(s) == 7:
+		t, err = time.Parse("2006-01", s)
+		precision = "MONTH"
+	case len(s) == 10:
+		t, err = time.Parse("2006-01-02", s)
+		precision = "DAY"
+	case !strings.Contains(s, "-"):
+		// Time of day
+		t, err = time.Parse("15:04:05.999999", s)
+		t = t.AddDate(1970, 0, 0)
+		precision = fractionPrecision(s)
+	default:
+		t, err = time.Parse(time.RFC3339Nano, s)
+		precision = fractionPrecision(s)
+	}
+	if err != nil {
+		return err
+	}
+	pb.Set(fields.ByName("value_us"), protoreflect.ValueOfInt64(t.UnixNano()/1000))
+	if fd := fields.ByName("timezone"); fd != nil {
+		zone := "UTC"
+		if i := strings.LastIndexAny(s, "+-"); len(s) > 10 && i > 10 {
+			zone = s[i:]
+		}
+		pb.Set(fd, protoreflect.ValueOfString(zone))
+	}
+	if fd := fields.ByName("precision"); fd != nil {
+		if ev := fd.Enum().Values().ByName(protoreflect.Name(precision)); ev != nil {
+			pb.Set(fd, protoreflect.ValueOfEnum(ev.Number()))
+		}
+	}
+	return nil
+}
+
+// fractionPrecision returns the precision of a time based on its fractional seconds
+func fractionPrecision(s string) string {
+	i := strings.Index(s, ".")
+	if i < 0 {
+		return "SECOND"
+	}
+	digits := 0
+	for _, c := range s[i+1:] {
+		if c < '0' || c > '9' {
+			break
+		}
+		digits++
+	}
+	if digits <= 3 {
+		return "MILLISECOND"
+	}
+	return "MICROSECOND"
+}
+
+func isChoice(md protoreflect.MessageDescriptor) bool {
+	return md != nil && proto.HasExtension(md.Options(), apb.E_IsChoiceType)
+}
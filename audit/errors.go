@@ -6,8 +6,13 @@ import (
 
 // Errors
 var (
-	ErrBaseURLCannotBeEmpty = errors.New("base URL cannot be empty")
-	ErrEmptyResult          = errors.New("empty result")
-	ErrBadRequest           = errors.New("bad request")
-	ErrNonHttp20xResponse   = errors.New("non http 20x Audit response")
+	ErrBaseURLCannotBeEmpty   = errors.New("base URL cannot be empty")
+	ErrEmptyResult            = errors.New("empty result")
+	ErrBadRequest             = errors.New("bad request")
+	ErrNonHttp20xResponse     = errors.New("non http 20x Audit response")
+	ErrMissingClient          = errors.New("missing audit client")
+	ErrSubmitterClosed        = errors.New("submitter closed")
+	ErrNoMorePages            = errors.New("no more pages")
+	ErrUnexpectedResourceType = errors.New("unexpected resource type")
+	ErrInvalidCode            = errors.New("invalid code")
 )
@@ -0,0 +1,19 @@
+package dstu2
+
+import (
+	"errors"
+)
+
+// Errors
+var (
+	ErrMissingProductKey  = errors.New("missing productKey source extension")
+	ErrMissingTenant      = errors.New("missing tenant source extension")
+	ErrMissingEventType   = errors.New("missing event type")
+	ErrMissingAction      = errors.New("missing event action")
+	ErrMissingDateTime    = errors.New("missing event dateTime")
+	ErrMissingParticipant = errors.New("missing participant")
+	ErrMissingRequestor   = errors.New("missing participant requestor")
+	ErrMissingUserID      = errors.New("missing participant userId")
+	ErrMissingSource      = errors.New("missing source identifier")
+	ErrMissingObject      = errors.New("missing object identifier")
+)
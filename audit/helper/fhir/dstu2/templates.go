@@ -0,0 +1,280 @@
+package dstu2
+
+import (
+	"fmt"
+	"net"
+	"time"
+
+	dstu2ct "github.com/google/fhir/go/proto/google/fhir/proto/dstu2/codes_go_proto"
+	dstu2dt "github.com/google/fhir/go/proto/google/fhir/proto/dstu2/datatypes_go_proto"
+	dstu2pb "github.com/google/fhir/go/proto/google/fhir/proto/dstu2/resources_go_proto"
+)
+
+// Code systems used by the templates
+const (
+	DCMSystem        = "http://dicom.nema.org/resources/ontology/DCM"
+	ObjectTypeSystem = "http://hl7.org/fhir/object-type"
+	ObjectRoleSystem = "http://hl7.org/fhir/object-role"
+	LifecycleSystem  = "http://hl7.org/fhir/object-lifecycle"
+)
+
+// DICOM codes of event types, subtypes and participant roles as used by IHE ATNA
+const (
+	CodeUserAuthentication   = "110114"
+	CodeLogin                = "110122"
+	CodeLogout               = "110123"
+	CodePatientRecord        = "110110"
+	CodeExport               = "110106"
+	CodeSecurityRolesChanged = "110136"
+	CodeSourceRoleID         = "110153"
+	CodeDestinationRoleID    = "110152"
+)
+
+var dcmDisplay = map[string]string{
+	CodeUserAuthentication:   "User Authentication",
+	CodeLogin:                "Login",
+	CodeLogout:               "Logout",
+	CodePatientRecord:        "Patient Record",
+	CodeExport:               "Export",
+	CodeSecurityRolesChanged: "Security Roles Changed",
+	CodeSourceRoleID:         "Source Role ID",
+	CodeDestinationRoleID:    "Destination Role ID",
+}
+
+// Template holds the elements shared by the pre-built audit events
+type Template struct {
+	// ProductKey and Tenant are required for publishing to the Host Auditing service
+	ProductKey string
+	Tenant     string
+	// ApplicationName is set as source extension when not empty
+	ApplicationName string
+	// SourceID identifies the application reporting the event and is required
+	SourceID string
+	// Site is the logical location of the source, e.g. the hospital
+	Site string
+	// UserID identifies the user performing the action and is required
+	UserID   string
+	UserName string
+	// NetworkAddress is the IP address or machine name the user acted from
+	NetworkAddress string
+	// Time of the event. Defaults to now
+	Time time.Time
+	// Outcome of the event. Defaults to success
+	Outcome     dstu2ct.AuditEventOutcomeCode_Value
+	OutcomeDesc string
+}
+
+// NewLoginEvent returns a user authentication event for a login
+func NewLoginEvent(t Template, options ...OptionFunc) (*dstu2pb.AuditEvent, error) {
+	return t.build(CodeUserAuthentication, CodeLogin, dstu2ct.AuditEventActionCode_E, options)
+}
+
+// NewLogoutEvent returns a user authentication event for a logout
+func NewLogoutEvent(t Template, options ...OptionFunc) (*dstu2pb.AuditEvent, error) {
+	return t.build(CodeUserAuthentication, CodeLogout, dstu2ct.AuditEventActionCode_E, options)
+}
+
+// NewPatientReadEvent returns a patient record event for reading the record of patientID
+func NewPatientReadEvent(t Template, patientID string, options ...OptionFunc) (*dstu2pb.AuditEvent, error) {
+	return t.patientRecord(dstu2ct.AuditEventActionCode_R, "6", "Access / Use", patientID, options)
+}
+
+// NewPatientCreateEvent returns a patient record event for creating the record of patientID
+func NewPatientCreateEvent(t Template, patientID string, options ...OptionFunc) (*dstu2pb.AuditEvent, error) {
+	return t.patientRecord(dstu2ct.AuditEventActionCode_C, "1", "Origination / Creation", patientID, options)
+}
+
+// NewPatientUpdateEvent returns a patient record event for updating the record of patientID
+func NewPatientUpdateEvent(t Template, patientID string, options ...OptionFunc) (*dstu2pb.AuditEvent, error) {
+	return t.patientRecord(dstu2ct.AuditEventActionCode_U, "3", "Amendment", patientID, options)
+}
+
+// NewPatientDeleteEvent returns a patient record event for deleting the record of patientID
+func NewPatientDeleteEvent(t Template, patientID string, options ...OptionFunc) (*dstu2pb.AuditEvent, error) {
+	return t.patientRecord(dstu2ct.AuditEventActionCode_D, "14", "Logical deletion", patientID, options)
+}
+
+// NewDataExportEvent returns an export event for data of patientIDs sent to destination.
+// The user is recorded as source and destination as the receiving participant
+func NewDataExportEvent(t Template, destination string, patientIDs []string, options ...OptionFunc) (*dstu2pb.AuditEvent, error) {
+	options = append([]OptionFunc{
+		AddParticipant(&dstu2pb.AuditEvent_Participant{
+			Role:      []*dstu2dt.CodeableConcept{{Coding: []*dstu2dt.Coding{dcm(CodeDestinationRoleID)}}},
+			UserId:    &dstu2dt.Identifier{Value: &dstu2dt.String{Value: destination}},
+			Requestor: &dstu2dt.Boolean{Value: false},
+		}),
+	}, options...)
+	for _, id := range patientIDs {
+		options = append(options, AddObject(patientObject(id, "10", "Export / Copy to target")))
+	}
+	event, err := t.build(CodeExport, "", dstu2ct.AuditEventActionCode_R, options)
+	if err != nil {
+		return nil, err
+	}
+	event.Participant[0].Role = append(event.Participant[0].Role, &dstu2dt.CodeableConcept{Coding: []*dstu2dt.Coding{dcm(CodeSourceRoleID)}})
+	return event, nil
+}
+
+// NewPermissionChangeEvent returns a security roles changed event for the
+// permissions of targetUserID. description describes the change, e.g. "granted role ADMIN"
+func NewPermissionChangeEvent(t Template, targetUserID, description string, options ...OptionFunc) (*dstu2pb.AuditEvent, error) {
+	object := &dstu2pb.AuditEvent_Object{
+		Identifier: &dstu2dt.Identifier{Value: &dstu2dt.String{Value: targetUserID}},
+		Type:       coding(ObjectTypeSystem, "2", "System Object"),
+		Role:       coding(ObjectRoleSystem, "11", "Security User Entity"),
+	}
+	if description != "" {
+		object.Description = &dstu2dt.String{Value: description}
+	}
+	options = append([]OptionFunc{AddObject(object)}, options...)
+	return t.build(CodeSecurityRolesChanged, "", dstu2ct.AuditEventActionCode_U, options)
+}
+
+// Validate checks that event contains the elements required by DSTU2 and the Host Auditing service
+func Validate(event *dstu2pb.AuditEvent) error {
+	if sourceExtension(event, "productKey") == "" {
+		return ErrMissingProductKey
+	}
+	if sourceExtension(event, "tenant") == "" {
+		return ErrMissingTenant
+	}
+	e := event.Event
+	if e == nil || e.Type == nil || e.Type.Code == nil || e.Type.Code.Value == "" {
+		return ErrMissingEventType
+	}
+	if e.Action == nil || e.Action.Value == dstu2ct.AuditEventActionCode_INVALID_UNINITIALIZED {
+		return ErrMissingAction
+	}
+	if e.DateTime == nil || e.DateTime.ValueUs == 0 {
+		return ErrMissingDateTime
+	}
+	if len(event.Participant) == 0 {
+		return ErrMissingParticipant
+	}
+	for i, p := range event.Participant {
+		if p.Requestor == nil {
+			return fmt.Errorf("participant %d: %w", i, ErrMissingRequestor)
+		}
+		if p.UserId == nil || p.UserId.Value == nil || p.UserId.Value.Value == "" {
+			return fmt.Errorf("participant %d: %w", i, ErrMissingUserID)
+		}
+	}
+	if event.Source == nil || event.Source.Identifier == nil ||
+		event.Source.Identifier.Value == nil || event.Source.Identifier.Value.Value == "" {
+		return ErrMissingSource
+	}
+	for i, o := range event.Object {
+		if (o.Identifier == nil || o.Identifier.Value == nil || o.Identifier.Value.Value == "") && o.Reference == nil {
+			return fmt.Errorf("object %d: %w", i, ErrMissingObject)
+		}
+	}
+	return nil
+}
+
+func (t Template) patientRecord(action dstu2ct.AuditEventActionCode_Value, lifecycle, lifecycleDisplay, patientID string, options []OptionFunc) (*dstu2pb.AuditEvent, error) {
+	options = append([]OptionFunc{AddObject(patientObject(patientID, lifecycle, lifecycleDisplay))}, options...)
+	return t.build(CodePatientRecord, "", action, options)
+}
+
+// build creates the event with the user as requesting participant, applies options and validates the result
+func (t Template) build(eventType, subtype string, action dstu2ct.AuditEventActionCode_Value, options []OptionFunc) (*dstu2pb.AuditEvent, error) {
+	at := t.Time
+	if at.IsZero() {
+		at = time.Now()
+	}
+	outcome := t.Outcome
+	if outcome == dstu2ct.AuditEventOutcomeCode_INVALID_UNINITIALIZED {
+		outcome = dstu2ct.AuditEventOutcomeCode_SUCCESS
+	}
+	e := &dstu2pb.AuditEvent_Event{
+		Type:     dcm(eventType),
+		Action:   &dstu2ct.AuditEventActionCode{Value: action},
+		DateTime: DateTime(at),
+		Outcome:  &dstu2ct.AuditEventOutcomeCode{Value: outcome},
+	}
+	if subtype != "" {
+		e.Subtype = []*dstu2dt.Coding{dcm(subtype)}
+	}
+	if t.OutcomeDesc != "" {
+		e.OutcomeDesc = &dstu2dt.String{Value: t.OutcomeDesc}
+	}
+	participant := &dstu2pb.AuditEvent_Participant{
+		Requestor: &dstu2dt.Boolean{Value: true},
+	}
+	if t.UserID != "" {
+		participant.UserId = &dstu2dt.Identifier{Value: &dstu2dt.String{Value: t.UserID}}
+	}
+	if t.UserName != "" {
+		participant.Name = &dstu2dt.String{Value: t.UserName}
+	}
+	if t.NetworkAddress != "" {
+		networkType := dstu2ct.AuditEventAgentNetworkTypeCode_MACHINE_NAME
+		if net.ParseIP(t.NetworkAddress) != nil {
+			networkType = dstu2ct.AuditEventAgentNetworkTypeCode_IP_ADDRESS
+		}
+		participant.Network = &dstu2pb.AuditEvent_Participant_Network{
+			Address: &dstu2dt.String{Value: t.NetworkAddress},
+			Type:    &dstu2ct.AuditEventAgentNetworkTypeCode{Value: networkType},
+		}
+	}
+	base := []OptionFunc{WithEvent(e), AddParticipant(participant)}
+	if t.SourceID != "" {
+		base = append(base, WithSourceIdentifier(&dstu2dt.Identifier{Value: &dstu2dt.String{Value: t.SourceID}}))
+	}
+	if t.ApplicationName != "" {
+		base = append(base, AddSourceExtensionUriValue("applicationName", t.ApplicationName))
+	}
+	event, err := NewAuditEvent(t.ProductKey, t.Tenant, append(base, options...)...)
+	if err != nil {
+		return nil, err
+	}
+	if t.Site != "" && event.Source != nil {
+		event.Source.Site = &dstu2dt.String{Value: t.Site}
+	}
+	if err := Validate(event); err != nil {
+		return nil, err
+	}
+	return event, nil
+}
+
+func patientObject(patientID, lifecycle, lifecycleDisplay string) *dstu2pb.AuditEvent_Object {
+	if patientID == "" {
+		return &dstu2pb.AuditEvent_Object{}
+	}
+	return &dstu2pb.AuditEvent_Object{
+		Identifier: &dstu2dt.Identifier{Value: &dstu2dt.String{Value: patientID}},
+		Type:       coding(ObjectTypeSystem, "1", "Person"),
+		Role:       coding(ObjectRoleSystem, "1", "Patient"),
+		Lifecycle:  coding(LifecycleSystem, lifecycle, lifecycleDisplay),
+	}
+}
+
+// sourceExtension returns the value of a /fhir/device source extension
+func sourceExtension(event *dstu2pb.AuditEvent, uri string) string {
+	if event.Source == nil {
+		return ""
+	}
+	for _, ext := range event.Source.Extension {
+		if ext.Url == nil || ext.Url.Value != "/fhir/device" {
+			continue
+		}
+		for _, e := range ext.Extension {
+			if e.Url != nil && e.Url.Value == uri {
+				return e.Value.GetStringValue().GetValue()
+			}
+		}
+	}
+	return ""
+}
+
+func coding(system, code, display string) *dstu2dt.Coding {
+	return &dstu2dt.Coding{
+		System:  &dstu2dt.Uri{Value: system},
+		Code:    &dstu2dt.Code{Value: code},
+		Display: &dstu2dt.String{Value: display},
+	}
+}
+
+func dcm(code string) *dstu2dt.Coding {
+	return coding(DCMSystem, code, dcmDisplay[code])
+}
@@ -0,0 +1,140 @@
+package dstu2_test
+
+import (
+	"testing"
+	"time"
+
+	dstu2ct "github.com/google/fhir/go/proto/google/fhir/proto/dstu2/codes_go_proto"
+	dstu2pb "github.com/google/fhir/go/proto/google/fhir/proto/dstu2/resources_go_proto"
+
+	"github.com/philips-software/go-hsdp-api/audit/helper/fhir/dstu2"
+	"github.com/stretchr/testify/assert"
+)
+
+func template() dstu2.Template {
+	return dstu2.Template{
+		ProductKey:      "key",
+		Tenant:          "tenant",
+		ApplicationName: "patientapp",
+		SourceID:        "patientapp-server",
+		UserID:          "user@example.com",
+		NetworkAddress:  "10.0.0.1",
+		Time:            time.Date(2021, 6, 1, 12, 0, 0, 0, time.UTC),
+	}
+}
+
+func TestLoginLogoutEvents(t *testing.T) {
+	event, err := dstu2.NewLoginEvent(template())
+	if !assert.Nil(t, err) {
+		return
+	}
+	assert.Equal(t, dstu2.DCMSystem, event.Event.Type.System.Value)
+	assert.Equal(t, dstu2.CodeUserAuthentication, event.Event.Type.Code.Value)
+	if assert.Len(t, event.Event.Subtype, 1) {
+		assert.Equal(t, dstu2.CodeLogin, event.Event.Subtype[0].Code.Value)
+	}
+	assert.Equal(t, dstu2ct.AuditEventActionCode_E, event.Event.Action.Value)
+	assert.Equal(t, dstu2ct.AuditEventOutcomeCode_SUCCESS, event.Event.Outcome.Value)
+	assert.Equal(t, time.Date(2021, 6, 1, 12, 0, 0, 0, time.UTC).UnixNano()/1000, event.Event.DateTime.ValueUs)
+	if assert.Len(t, event.Participant, 1) {
+		p := event.Participant[0]
+		assert.Equal(t, "user@example.com", p.UserId.Value.Value)
+		assert.True(t, p.Requestor.Value)
+		assert.Equal(t, "10.0.0.1", p.Network.Address.Value)
+		assert.Equal(t, dstu2ct.AuditEventAgentNetworkTypeCode_IP_ADDRESS, p.Network.Type.Value)
+	}
+	assert.Equal(t, "patientapp-server", event.Source.Identifier.Value.Value)
+
+	tmpl := template()
+	tmpl.Outcome = dstu2ct.AuditEventOutcomeCode_MINOR_FAILURE
+	tmpl.OutcomeDesc = "invalid password"
+	event, err = dstu2.NewLogoutEvent(tmpl)
+	if !assert.Nil(t, err) {
+		return
+	}
+	assert.Equal(t, dstu2.CodeLogout, event.Event.Subtype[0].Code.Value)
+	assert.Equal(t, dstu2ct.AuditEventOutcomeCode_MINOR_FAILURE, event.Event.Outcome.Value)
+	assert.Equal(t, "invalid password", event.Event.OutcomeDesc.Value)
+}
+
+func TestPatientRecordEvents(t *testing.T) {
+	constructors := map[dstu2ct.AuditEventActionCode_Value]func(dstu2.Template, string, ...dstu2.OptionFunc) (*dstu2pb.AuditEvent, error){
+		dstu2ct.AuditEventActionCode_C: dstu2.NewPatientCreateEvent,
+		dstu2ct.AuditEventActionCode_R: dstu2.NewPatientReadEvent,
+		dstu2ct.AuditEventActionCode_U: dstu2.NewPatientUpdateEvent,
+		dstu2ct.AuditEventActionCode_D: dstu2.NewPatientDeleteEvent,
+	}
+	for action, newEvent := range constructors {
+		event, err := newEvent(template(), "patient-1")
+		if !assert.Nil(t, err) {
+			return
+		}
+		assert.Equal(t, dstu2.CodePatientRecord, event.Event.Type.Code.Value)
+		assert.Equal(t, action, event.Event.Action.Value)
+		if assert.Len(t, event.Object, 1) {
+			assert.Equal(t, "patient-1", event.Object[0].Identifier.Value.Value)
+			assert.Equal(t, "1", event.Object[0].Role.Code.Value)
+		}
+		_, err = newEvent(template(), "")
+		assert.ErrorIs(t, err, dstu2.ErrMissingObject)
+	}
+}
+
+func TestDataExportEvent(t *testing.T) {
+	event, err := dstu2.NewDataExportEvent(template(), "s3://archive", []string{"patient-1", "patient-2"})
+	if !assert.Nil(t, err) {
+		return
+	}
+	assert.Equal(t, dstu2.CodeExport, event.Event.Type.Code.Value)
+	if assert.Len(t, event.Participant, 2) {
+		assert.Equal(t, dstu2.CodeSourceRoleID, event.Participant[0].Role[0].Coding[0].Code.Value)
+		assert.Equal(t, "s3://archive", event.Participant[1].UserId.Value.Value)
+		assert.False(t, event.Participant[1].Requestor.Value)
+		assert.Equal(t, dstu2.CodeDestinationRoleID, event.Participant[1].Role[0].Coding[0].Code.Value)
+	}
+	if assert.Len(t, event.Object, 2) {
+		for _, object := range event.Object {
+			assert.Equal(t, dstu2.LifecycleSystem, object.Lifecycle.System.Value)
+			assert.Equal(t, "10", object.Lifecycle.Code.Value)
+			assert.Equal(t, "Export / Copy to target", object.Lifecycle.Display.Value)
+		}
+	}
+}
+
+func TestPermissionChangeEvent(t *testing.T) {
+	event, err := dstu2.NewPermissionChangeEvent(template(), "other@example.com", "granted role ADMIN")
+	if !assert.Nil(t, err) {
+		return
+	}
+	assert.Equal(t, dstu2.CodeSecurityRolesChanged, event.Event.Type.Code.Value)
+	assert.Equal(t, dstu2ct.AuditEventActionCode_U, event.Event.Action.Value)
+	if assert.Len(t, event.Object, 1) {
+		assert.Equal(t, "other@example.com", event.Object[0].Identifier.Value.Value)
+		assert.Equal(t, "granted role ADMIN", event.Object[0].Description.Value)
+	}
+}
+
+func TestTemplateValidation(t *testing.T) {
+	tmpl := template()
+	tmpl.UserID = ""
+	_, err := dstu2.NewLoginEvent(tmpl)
+	assert.ErrorIs(t, err, dstu2.ErrMissingUserID)
+
+	tmpl = template()
+	tmpl.SourceID = ""
+	_, err = dstu2.NewLoginEvent(tmpl)
+	assert.ErrorIs(t, err, dstu2.ErrMissingSource)
+
+	tmpl = template()
+	tmpl.ProductKey = ""
+	_, err = dstu2.NewLoginEvent(tmpl)
+	assert.ErrorIs(t, err, dstu2.ErrMissingProductKey)
+
+	_, err = dstu2.NewDataExportEvent(template(), "", nil)
+	assert.ErrorIs(t, err, dstu2.ErrMissingUserID)
+
+	event, err := dstu2.NewAuditEvent("key", "tenant")
+	if assert.Nil(t, err) {
+		assert.ErrorIs(t, dstu2.Validate(event), dstu2.ErrMissingEventType)
+	}
+}
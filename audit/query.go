@@ -0,0 +1,161 @@
+package audit
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"time"
+
+	dstu2pb "github.com/google/fhir/go/proto/google/fhir/proto/dstu2/resources_go_proto"
+	"github.com/google/go-querystring/query"
+)
+
+const (
+	auditEventPath = "core/audit/AuditEvent"
+	// DateFormat is the format of the date search parameter
+	DateFormat = "2006-01-02T15:04:05.000Z07:00"
+)
+
+// SearchOptions describes the fields on which you can search for audit events
+type SearchOptions struct {
+	// From and To limit the search to events with a date in [From, To]
+	From *time.Time `url:"-"`
+	To   *time.Time `url:"-"`
+	// User is the user ID of a participant
+	User *string `url:"user,omitempty"`
+	// Action is the action code, e.g. C, R, U, D or E
+	Action *string `url:"action,omitempty"`
+	// Outcome is the outcome code, e.g. 0 for success
+	Outcome    *string `url:"outcome,omitempty"`
+	ProductKey *string `url:"productKey,omitempty"`
+	Tenant     *string `url:"tenant,omitempty"`
+	Count      *int    `url:"_count,omitempty"`
+}
+
+// searchQuery adds the date range to the SearchOptions query parameters
+type searchQuery struct {
+	SearchOptions
+	Date []string `url:"date,omitempty"`
+}
+
+// SearchResult is a page of audit events
+type SearchResult struct {
+	Total  int
+	Events []*dstu2pb.AuditEvent
+	// Raw holds the JSON of each event in Events as returned by the Audit service
+	Raw []json.RawMessage
+	// NextLink is the link to the next page, empty on the last page
+	NextLink string
+}
+
+// HasNextPage returns true if there are more results
+func (r *SearchResult) HasNextPage() bool {
+	return r != nil && r.NextLink != ""
+}
+
+type searchBundle struct {
+	ResourceType string `json:"resourceType"`
+	Total        int    `json:"total"`
+	Link         []struct {
+		Relation string `json:"relation"`
+		URL      string `json:"url"`
+	} `json:"link"`
+	Entry []struct {
+		Resource json.RawMessage `json:"resource"`
+	} `json:"entry"`
+}
+
+// SearchAuditEvents returns the first page of audit events matching opt
+func (c *Client) SearchAuditEvents(opt *SearchOptions, options ...OptionFunc) (*SearchResult, *Response, error) {
+	q := searchQuery{}
+	if opt != nil {
+		q.SearchOptions = *opt
+		if opt.From != nil {
+			q.Date = append(q.Date, "ge"+opt.From.UTC().Format(DateFormat))
+		}
+		if opt.To != nil {
+			q.Date = append(q.Date, "le"+opt.To.UTC().Format(DateFormat))
+		}
+	}
+	values, err := query.Values(q)
+	if err != nil {
+		return nil, nil, err
+	}
+	return c.searchAuditEvents(values.Encode(), options)
+}
+
+// NextAuditEvents returns the page following result. ErrNoMorePages is returned on the last page
+func (c *Client) NextAuditEvents(result *SearchResult, options ...OptionFunc) (*SearchResult, *Response, error) {
+	if !result.HasNextPage() {
+		return nil, nil, ErrNoMorePages
+	}
+	next, err := url.Parse(result.NextLink)
+	if err != nil {
+		return nil, nil, err
+	}
+	return c.searchAuditEvents(next.RawQuery, options)
+}
+
+// ExportAuditEvents writes all audit events matching opt to w as NDJSON, one
+// event per line as returned by the Audit service. It returns the number of events written
+func (c *Client) ExportAuditEvents(w io.Writer, opt *SearchOptions, options ...OptionFunc) (int, error) {
+	buf := bufio.NewWriter(w)
+	count := 0
+	result, _, err := c.SearchAuditEvents(opt, options...)
+	for err == nil {
+		for _, raw := range result.Raw {
+			var line bytes.Buffer
+			if err := json.Compact(&line, raw); err != nil {
+				return count, err
+			}
+			line.WriteByte('\n')
+			if _, err := buf.Write(line.Bytes()); err != nil {
+				return count, err
+			}
+			count++
+		}
+		if !result.HasNextPage() {
+			break
+		}
+		result, _, err = c.NextAuditEvents(result, options...)
+	}
+	if err != nil {
+		return count, err
+	}
+	return count, buf.Flush()
+}
+
+func (c *Client) searchAuditEvents(rawQuery string, options []OptionFunc) (*SearchResult, *Response, error) {
+	req, err := c.newAuditRequest("GET", auditEventPath, nil, options)
+	if err != nil {
+		return nil, nil, fmt.Errorf("audit.SearchAuditEvents: %w", err)
+	}
+	req.URL.RawQuery = rawQuery
+	_ = c.httpSigner.SignRequest(req)
+	var bundle searchBundle
+	resp, err := c.do(req, &bundle)
+	if err != nil {
+		return nil, resp, err
+	}
+	if bundle.ResourceType != "Bundle" {
+		return nil, resp, fmt.Errorf("%w: %q", ErrUnexpectedResourceType, bundle.ResourceType)
+	}
+	result := &SearchResult{Total: bundle.Total}
+	for _, link := range bundle.Link {
+		if link.Relation == "next" {
+			result.NextLink = link.URL
+		}
+	}
+	for _, e := range bundle.Entry {
+		event, err := UnmarshalAuditEvent(e.Resource)
+		if err != nil {
+			return nil, resp, err
+		}
+		result.Events = append(result.Events, event)
+		result.Raw = append(result.Raw, e.Resource)
+	}
+	return result, resp, nil
+}
@@ -0,0 +1,177 @@
+package audit_test
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	dstu2ct "github.com/google/fhir/go/proto/google/fhir/proto/dstu2/codes_go_proto"
+	dstu2dt "github.com/google/fhir/go/proto/google/fhir/proto/dstu2/datatypes_go_proto"
+	dstu2pb "github.com/google/fhir/go/proto/google/fhir/proto/dstu2/resources_go_proto"
+
+	"github.com/philips-software/go-hsdp-api/audit"
+	"github.com/philips-software/go-hsdp-api/audit/helper/fhir/dstu2"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func auditEventJSON(t *testing.T, id string, at time.Time) string {
+	event, err := dstu2.NewAuditEvent("productKey", "tenant",
+		dstu2.AddSourceExtensionUriValue("applicationName", "patientapp"),
+		dstu2.AddParticipant(&dstu2pb.AuditEvent_Participant{
+			UserId:    &dstu2dt.Identifier{Value: &dstu2dt.String{Value: "user@example.com"}},
+			Requestor: &dstu2dt.Boolean{Value: true},
+		}),
+		dstu2.WithEvent(&dstu2pb.AuditEvent_Event{
+			Action:   &dstu2ct.AuditEventActionCode{Value: dstu2ct.AuditEventActionCode_R},
+			DateTime: dstu2.DateTime(at),
+			Type: &dstu2dt.Coding{
+				System: &dstu2dt.Uri{Value: "http://hl7.org/fhir/ValueSet/audit-event-type"},
+				Code:   &dstu2dt.Code{Value: "110110"},
+			},
+			Outcome: &dstu2ct.AuditEventOutcomeCode{Value: dstu2ct.AuditEventOutcomeCode_MINOR_FAILURE},
+		}))
+	if !assert.Nil(t, err) {
+		t.FailNow()
+	}
+	event.Id = &dstu2dt.Id{Value: id}
+	data, err := ma.MarshalResource(event)
+	if !assert.Nil(t, err) {
+		t.FailNow()
+	}
+	return string(data)
+}
+
+func TestUnmarshalAuditEvent(t *testing.T) {
+	teardown := setup(t)
+	defer teardown()
+
+	at := time.Date(2021, 6, 1, 12, 30, 15, 123456000, time.UTC)
+	event, err := audit.UnmarshalAuditEvent([]byte(auditEventJSON(t, "event-1", at)))
+	if !assert.Nil(t, err) {
+		return
+	}
+	assert.Equal(t, "event-1", event.Id.Value)
+	assert.Equal(t, dstu2ct.AuditEventActionCode_R, event.Event.Action.Value)
+	assert.Equal(t, dstu2ct.AuditEventOutcomeCode_MINOR_FAILURE, event.Event.Outcome.Value)
+	assert.Equal(t, at.UnixNano()/1000, event.Event.DateTime.ValueUs)
+	assert.Equal(t, dstu2dt.Instant_MICROSECOND, event.Event.DateTime.Precision)
+	assert.Equal(t, "110110", event.Event.Type.Code.Value)
+	if assert.Len(t, event.Participant, 1) {
+		assert.Equal(t, "user@example.com", event.Participant[0].UserId.Value.Value)
+		assert.True(t, event.Participant[0].Requestor.Value)
+	}
+	if assert.Len(t, event.Source.Extension, 1) {
+		device := event.Source.Extension[0]
+		assert.Equal(t, "/fhir/device", device.Url.Value)
+		values := map[string]string{}
+		for _, e := range device.Extension {
+			values[e.Url.Value] = e.Value.GetStringValue().Value
+		}
+		assert.Equal(t, "productKey", values["productKey"])
+		assert.Equal(t, "tenant", values["tenant"])
+		assert.Equal(t, "patientapp", values["applicationName"])
+	}
+
+	_, err = audit.UnmarshalAuditEvent([]byte(`{"resourceType":"Patient"}`))
+	assert.ErrorIs(t, err, audit.ErrUnexpectedResourceType)
+	_, err = audit.UnmarshalAuditEvent([]byte(`{"resourceType":"AuditEvent","event":{"action":"X"}}`))
+	assert.ErrorIs(t, err, audit.ErrInvalidCode)
+}
+
+func TestSearchAuditEvents(t *testing.T) {
+	teardown := setup(t)
+	defer teardown()
+
+	at := time.Date(2021, 6, 1, 12, 0, 0, 0, time.UTC)
+	muxAudit.HandleFunc("/core/audit/AuditEvent", func(w http.ResponseWriter, r *http.Request) {
+		if !assert.Equal(t, "GET", r.Method) {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if r.URL.Query().Get("page") == "2" {
+			_, _ = fmt.Fprintf(w, `{"resourceType":"Bundle","type":"searchset","total":3,"entry":[{"resource":%s}]}`,
+				auditEventJSON(t, "event-3", at))
+			return
+		}
+		q := r.URL.Query()
+		assert.Equal(t, []string{"ge2021-06-01T00:00:00.000Z", "le2021-06-02T00:00:00.000Z"}, q["date"])
+		assert.Equal(t, "user@example.com", q.Get("user"))
+		assert.Equal(t, "R", q.Get("action"))
+		assert.Equal(t, "productKey", q.Get("productKey"))
+		assert.Equal(t, "tenant", q.Get("tenant"))
+		_, _ = fmt.Fprintf(w, `{"resourceType":"Bundle","type":"searchset","total":3,
+"link":[{"relation":"self","url":"%[1]s"},{"relation":"next","url":"%[1]s?page=2"}],
+"entry":[{"resource":%[2]s},{"resource":%[3]s}]}`,
+			serverAudit.URL+"/core/audit/AuditEvent", auditEventJSON(t, "event-1", at), auditEventJSON(t, "event-2", at))
+	})
+
+	from := at.Add(-12 * time.Hour)
+	to := at.Add(12 * time.Hour)
+	opt := &audit.SearchOptions{
+		From:       &from,
+		To:         &to,
+		User:       strPtr("user@example.com"),
+		Action:     strPtr("R"),
+		ProductKey: strPtr("productKey"),
+		Tenant:     strPtr("tenant"),
+	}
+	result, resp, err := auditClient.SearchAuditEvents(opt)
+	if !assert.Nil(t, err) || !assert.NotNil(t, resp) {
+		return
+	}
+	assert.Equal(t, 3, result.Total)
+	if assert.Len(t, result.Events, 2) {
+		assert.Equal(t, "event-1", result.Events[0].Id.Value)
+		assert.Equal(t, "event-2", result.Events[1].Id.Value)
+	}
+	assert.True(t, result.HasNextPage())
+
+	next, _, err := auditClient.NextAuditEvents(result)
+	if !assert.Nil(t, err) {
+		return
+	}
+	if assert.Len(t, next.Events, 1) {
+		assert.Equal(t, "event-3", next.Events[0].Id.Value)
+	}
+	assert.False(t, next.HasNextPage())
+	_, _, err = auditClient.NextAuditEvents(next)
+	assert.Equal(t, audit.ErrNoMorePages, err)
+
+	var buf bytes.Buffer
+	count, err := auditClient.ExportAuditEvents(&buf, opt)
+	if !assert.Nil(t, err) {
+		return
+	}
+	assert.Equal(t, 3, count)
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if assert.Len(t, lines, 3) {
+		for i, line := range lines {
+			event, err := audit.UnmarshalAuditEvent([]byte(line))
+			if assert.Nil(t, err) {
+				assert.Equal(t, fmt.Sprintf("event-%d", i+1), event.Id.Value)
+			}
+		}
+	}
+}
+
+func TestSearchAuditEventsError(t *testing.T) {
+	teardown := setup(t)
+	defer teardown()
+
+	muxAudit.HandleFunc("/core/audit/AuditEvent", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusForbidden)
+		_, _ = w.Write([]byte(`{"resourceType":"OperationOutcome"}`))
+	})
+	_, _, err := auditClient.SearchAuditEvents(nil)
+	assert.ErrorIs(t, err, audit.ErrNonHttp20xResponse)
+}
+
+func strPtr(s string) *string {
+	return &s
+}
@@ -0,0 +1,552 @@
+package audit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/google/uuid"
+
+	dstu2dt "github.com/google/fhir/go/proto/google/fhir/proto/dstu2/datatypes_go_proto"
+	dstu2pb "github.com/google/fhir/go/proto/google/fhir/proto/dstu2/resources_go_proto"
+)
+
+const (
+	// DefaultSubmitterQueueSize is the default number of events the Submitter buffers
+	DefaultSubmitterQueueSize = 1000
+	// DefaultSubmitterWorkers is the default number of batches submitted concurrently
+	DefaultSubmitterWorkers = 4
+	// DefaultSubmitterBatchSize is the default maximum number of events per request.
+	// Events are posted one at a time as batch Bundles are not a documented Audit capability
+	DefaultSubmitterBatchSize = 1
+	// DefaultSubmitterMaxRetries is the default number of resubmissions of a failed event
+	DefaultSubmitterMaxRetries = 5
+	// DefaultSubmitterRetryBackoff is the default wait time before the first resubmission
+	DefaultSubmitterRetryBackoff = 500 * time.Millisecond
+	// DefaultSubmitterMaxBackoff is the default maximum wait time between resubmissions
+	DefaultSubmitterMaxBackoff = 30 * time.Second
+	// DefaultSubmitterReplayInterval is the default interval at which undelivered spooled events are resubmitted
+	DefaultSubmitterReplayInterval = time.Minute
+
+	spoolExt         = ".json"
+	spoolRejectedExt = ".rejected"
+	spoolTempPrefix  = ".tmp-"
+)
+
+// SubmitterConfig configures a Submitter. Zero values are replaced by their defaults
+type SubmitterConfig struct {
+	QueueSize int
+	Workers   int
+	// BatchSize is the maximum number of queued events a worker submits in a single
+	// batch Bundle. Only raise it for Audit services which answer batch Bundles with
+	// the status of every entry. Defaults to one event per request
+	BatchSize int
+	// MaxRetries is the number of resubmissions after a failed call. Use a negative value to retry forever
+	MaxRetries   int
+	RetryBackoff time.Duration
+	MaxBackoff   time.Duration
+	// SpoolDir enables guaranteed delivery. Events are written to this directory before
+	// Submit returns and removed once delivered. Events which failed after all retries
+	// are submitted again every ReplayInterval, events left behind by a previous process
+	// by NewSubmitter. Events rejected by the Audit service are renamed with a .rejected extension.
+	SpoolDir       string
+	ReplayInterval time.Duration
+	// OnFailure is called with the ID of an event which could not be delivered
+	OnFailure func(id string, err error)
+}
+
+// SubmitterStats holds the counters of a Submitter
+type SubmitterStats struct {
+	Submitted uint64
+	Delivered uint64
+	Retried   uint64
+	Failed    uint64
+	Pending   int64
+}
+
+// Submitter asynchronously submits audit events in batches with retries and optional
+// disk spooling. Delivery is at-least-once: every event carries an ID which is kept
+// across resubmissions so duplicates can be recognized.
+type Submitter struct {
+	// counters are accessed atomically and kept first for alignment
+	submitted uint64
+	delivered uint64
+	retried   uint64
+	failed    uint64
+
+	client *Client
+	config SubmitterConfig
+
+	queue chan submission
+	done  chan struct{}
+
+	mu      sync.Mutex
+	pending int64
+	// idle is closed while no events are pending
+	idle chan struct{}
+	// spooled holds the spool files of queued events and events being delivered
+	spooled map[string]bool
+	closed  bool
+	workers sync.WaitGroup
+}
+
+type submission struct {
+	id        string
+	eventJSON []byte
+	spoolFile string
+}
+
+// NewSubmitter starts a Submitter which submits events through client.
+// Events found in SpoolDir are queued for submission first.
+func NewSubmitter(client *Client, config SubmitterConfig) (*Submitter, error) {
+	if client == nil {
+		return nil, ErrMissingClient
+	}
+	if config.QueueSize <= 0 {
+		config.QueueSize = DefaultSubmitterQueueSize
+	}
+	if config.Workers <= 0 {
+		config.Workers = DefaultSubmitterWorkers
+	}
+	if config.BatchSize <= 0 {
+		config.BatchSize = DefaultSubmitterBatchSize
+	}
+	if config.MaxRetries == 0 {
+		config.MaxRetries = DefaultSubmitterMaxRetries
+	}
+	if config.RetryBackoff <= 0 {
+		config.RetryBackoff = DefaultSubmitterRetryBackoff
+	}
+	if config.MaxBackoff <= 0 {
+		config.MaxBackoff = DefaultSubmitterMaxBackoff
+	}
+	if config.ReplayInterval <= 0 {
+		config.ReplayInterval = DefaultSubmitterReplayInterval
+	}
+	var recovered []submission
+	if config.SpoolDir != "" {
+		if err := os.MkdirAll(config.SpoolDir, 0700); err != nil {
+			return nil, err
+		}
+		var err error
+		if recovered, err = readSpool(config.SpoolDir, true); err != nil {
+			return nil, err
+		}
+	}
+	queueSize := config.QueueSize
+	if len(recovered) > queueSize {
+		queueSize = len(recovered)
+	}
+	s := &Submitter{
+		client:  client,
+		config:  config,
+		queue:   make(chan submission, queueSize),
+		done:    make(chan struct{}),
+		idle:    make(chan struct{}),
+		spooled: make(map[string]bool),
+	}
+	close(s.idle)
+	for _, sub := range recovered {
+		s.begin(sub)
+		s.queue <- sub
+	}
+	for i := 0; i < config.Workers; i++ {
+		s.workers.Add(1)
+		go s.run()
+	}
+	if config.SpoolDir != "" {
+		s.workers.Add(1)
+		go s.replay()
+	}
+	return s, nil
+}
+
+// Submit queues an event and returns its ID. Events without an ID are assigned one.
+// Submit blocks while the queue is full until ctx is done or the Submitter is closed.
+// With a SpoolDir the event is on disk when Submit returns without error.
+func (s *Submitter) Submit(ctx context.Context, event *dstu2pb.AuditEvent) (string, error) {
+	if event.Id == nil || event.Id.Value == "" {
+		event.Id = &dstu2dt.Id{Value: uuid.New().String()}
+	}
+	id := event.Id.Value
+	eventJSON, err := s.client.ma.MarshalResource(event)
+	if err != nil {
+		return id, err
+	}
+	sub := submission{id: id, eventJSON: eventJSON}
+	if s.config.SpoolDir != "" {
+		sub.spoolFile = spoolPath(s.config.SpoolDir, id)
+	}
+
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return id, ErrSubmitterClosed
+	}
+	s.begin(sub)
+	s.mu.Unlock()
+
+	if sub.spoolFile != "" {
+		if err := writeSpoolFile(sub.spoolFile, eventJSON); err != nil {
+			s.complete(sub)
+			return id, err
+		}
+	}
+	// A spooled event which is not queued is resubmitted from the spool
+	select {
+	case s.queue <- sub:
+		atomic.AddUint64(&s.submitted, 1)
+		return id, nil
+	case <-s.done:
+		s.complete(sub)
+		return id, ErrSubmitterClosed
+	case <-ctx.Done():
+		s.complete(sub)
+		return id, ctx.Err()
+	}
+}
+
+// Flush waits until all submitted events are delivered or have failed, or ctx is done
+func (s *Submitter) Flush(ctx context.Context) error {
+	s.mu.Lock()
+	idle := s.idle
+	s.mu.Unlock()
+	select {
+	case <-idle:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Close stops accepting events and waits until queued events are processed or ctx is done.
+// Events still spooled when ctx is done are submitted by the next Submitter.
+func (s *Submitter) Close(ctx context.Context) error {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return ErrSubmitterClosed
+	}
+	s.closed = true
+	s.mu.Unlock()
+
+	err := s.Flush(ctx)
+	close(s.done)
+	stopped := make(chan struct{})
+	go func() {
+		// Ends once the workers finish their current request
+		s.workers.Wait()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+	case <-ctx.Done():
+		if err == nil {
+			err = ctx.Err()
+		}
+	}
+	return err
+}
+
+// Stats returns a snapshot of the Submitter counters
+func (s *Submitter) Stats() SubmitterStats {
+	s.mu.Lock()
+	pending := s.pending
+	s.mu.Unlock()
+	return SubmitterStats{
+		Submitted: atomic.LoadUint64(&s.submitted),
+		Delivered: atomic.LoadUint64(&s.delivered),
+		Retried:   atomic.LoadUint64(&s.retried),
+		Failed:    atomic.LoadUint64(&s.failed),
+		Pending:   pending,
+	}
+}
+
+func (s *Submitter) run() {
+	defer s.workers.Done()
+	for {
+		select {
+		case sub := <-s.queue:
+			s.deliver(s.collect(sub))
+		case <-s.done:
+			return
+		}
+	}
+}
+
+// collect adds queued events to first up to BatchSize without waiting for more
+func (s *Submitter) collect(first submission) []submission {
+	batch := []submission{first}
+	for len(batch) < s.config.BatchSize {
+		select {
+		case sub := <-s.queue:
+			batch = append(batch, sub)
+		default:
+			return batch
+		}
+	}
+	return batch
+}
+
+// deliver submits a batch, retrying the events which failed with backoff
+func (s *Submitter) deliver(batch []submission) {
+	backoff := s.config.RetryBackoff
+	for attempt := 0; ; attempt++ {
+		var err error
+		if batch, err = s.post(batch); len(batch) == 0 {
+			return
+		}
+		if s.config.MaxRetries > 0 && attempt >= s.config.MaxRetries {
+			s.fail(batch, err)
+			return
+		}
+		atomic.AddUint64(&s.retried, uint64(len(batch)))
+		select {
+		case <-time.After(backoff):
+		case <-s.done:
+			// Shutting down, spooled events are kept for the next Submitter
+			s.fail(batch, err)
+			return
+		}
+		backoff *= 2
+		if backoff > s.config.MaxBackoff {
+			backoff = s.config.MaxBackoff
+		}
+	}
+}
+
+// post submits batch and returns the events to resubmit with the error which
+// caused it. A single event is posted as is and larger batches as a batch Bundle.
+// When the Bundle is rejected as a whole its events are posted one by one.
+func (s *Submitter) post(batch []submission) ([]submission, error) {
+	if len(batch) == 1 {
+		_, _, err := s.client.createAuditEventJSON(batch[0].eventJSON)
+		switch {
+		case err == nil:
+			s.succeed(batch[0])
+			return nil, nil
+		case errors.Is(err, ErrBadRequest):
+			s.reject(batch[0], err)
+			return nil, nil
+		}
+		return batch, err
+	}
+	events := make([][]byte, len(batch))
+	for i, sub := range batch {
+		events[i] = sub.eventJSON
+	}
+	statuses, err := s.client.createAuditEventsJSON(events)
+	if errors.Is(err, ErrBadRequest) {
+		var retry []submission
+		var retryErr error
+		for _, sub := range batch {
+			if failed, err := s.post([]submission{sub}); len(failed) > 0 {
+				retry = append(retry, failed...)
+				retryErr = err
+			}
+		}
+		return retry, retryErr
+	}
+	if err != nil {
+		return batch, err
+	}
+	var retry []submission
+	for i, sub := range batch {
+		switch status := statuses[i]; {
+		case status >= 200 && status < 300:
+			s.succeed(sub)
+		case status >= 400 && status < 500 && status != http.StatusRequestTimeout && status != http.StatusTooManyRequests:
+			s.reject(sub, fmt.Errorf("entry status %d: %w", status, ErrBadRequest))
+		default:
+			retry = append(retry, sub)
+			err = fmt.Errorf("entry status %d: %w", status, ErrNonHttp20xResponse)
+		}
+	}
+	return retry, err
+}
+
+func (s *Submitter) succeed(sub submission) {
+	atomic.AddUint64(&s.delivered, 1)
+	if sub.spoolFile != "" {
+		_ = os.Remove(sub.spoolFile)
+	}
+	s.complete(sub)
+}
+
+// reject keeps a spooled event which the Audit service rejected aside
+func (s *Submitter) reject(sub submission, err error) {
+	if sub.spoolFile != "" {
+		_ = os.Rename(sub.spoolFile, strings.TrimSuffix(sub.spoolFile, spoolExt)+spoolRejectedExt)
+	}
+	s.fail([]submission{sub}, err)
+}
+
+func (s *Submitter) fail(batch []submission, err error) {
+	for _, sub := range batch {
+		atomic.AddUint64(&s.failed, 1)
+		if s.config.OnFailure != nil {
+			s.config.OnFailure(sub.id, err)
+		}
+		s.complete(sub)
+	}
+}
+
+// begin marks a submission as pending. It must be called with mu held
+func (s *Submitter) begin(sub submission) {
+	if s.pending == 0 {
+		s.idle = make(chan struct{})
+	}
+	s.pending++
+	if sub.spoolFile != "" {
+		s.spooled[sub.spoolFile] = true
+	}
+}
+
+// complete marks a submission as processed
+func (s *Submitter) complete(sub submission) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if sub.spoolFile != "" {
+		delete(s.spooled, sub.spoolFile)
+	}
+	s.pending--
+	if s.pending == 0 {
+		close(s.idle)
+	}
+}
+
+// replay periodically queues spooled events which are neither queued nor being
+// delivered, such as events which failed after all retries
+func (s *Submitter) replay() {
+	defer s.workers.Done()
+	ticker := time.NewTicker(s.config.ReplayInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			s.requeueSpooled()
+		case <-s.done:
+			return
+		}
+	}
+}
+
+func (s *Submitter) requeueSpooled() {
+	spooled, err := readSpool(s.config.SpoolDir, false)
+	if err != nil {
+		return
+	}
+	for _, sub := range spooled {
+		s.mu.Lock()
+		if s.closed {
+			s.mu.Unlock()
+			return
+		}
+		// Delivered events are removed from disk before they are completed
+		if _, err := os.Stat(sub.spoolFile); s.spooled[sub.spoolFile] || err != nil {
+			s.mu.Unlock()
+			continue
+		}
+		s.begin(sub)
+		s.mu.Unlock()
+		select {
+		case s.queue <- sub:
+		case <-s.done:
+			s.complete(sub)
+			return
+		}
+	}
+}
+
+// spoolPath returns the spool file of an event. The file name starts with
+// a timestamp so events are recovered in submission order
+func spoolPath(dir, id string) string {
+	return filepath.Join(dir, fmt.Sprintf("%020d-%s%s", time.Now().UnixNano(), sanitizeID(id), spoolExt))
+}
+
+// writeSpoolFile atomically writes an event to path
+func writeSpoolFile(path string, eventJSON []byte) error {
+	tmp, err := ioutil.TempFile(filepath.Dir(path), spoolTempPrefix)
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(eventJSON); err != nil {
+		_ = tmp.Close()
+		_ = os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		_ = tmp.Close()
+		_ = os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		_ = os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// readSpool loads the spooled events of dir in submission order. Files which
+// disappear while reading are skipped. With removeTemp, temporary files of
+// interrupted writes are removed, which is only safe before events are submitted.
+func readSpool(dir string, removeTemp bool) ([]submission, error) {
+	entries, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, e := range entries {
+		switch {
+		case e.IsDir():
+		case strings.HasPrefix(e.Name(), spoolTempPrefix):
+			if removeTemp {
+				_ = os.Remove(filepath.Join(dir, e.Name()))
+			}
+		case strings.HasSuffix(e.Name(), spoolExt):
+			names = append(names, e.Name())
+		}
+	}
+	sort.Strings(names)
+	var spooled []submission
+	for _, name := range names {
+		parts := strings.SplitN(strings.TrimSuffix(name, spoolExt), "-", 2)
+		if len(parts) != 2 {
+			continue
+		}
+		if _, err := strconv.ParseInt(parts[0], 10, 64); err != nil {
+			continue
+		}
+		path := filepath.Join(dir, name)
+		data, err := ioutil.ReadFile(path)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		spooled = append(spooled, submission{id: parts[1], eventJSON: data, spoolFile: path})
+	}
+	return spooled, nil
+}
+
+// sanitizeID makes an event ID safe for use in a file name
+func sanitizeID(id string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '-', r == '.':
+			return r
+		}
+		return '_'
+	}, id)
+}
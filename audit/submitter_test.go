@@ -0,0 +1,381 @@
+package audit_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	dstu2dt "github.com/google/fhir/go/proto/google/fhir/proto/dstu2/datatypes_go_proto"
+	dstu2pb "github.com/google/fhir/go/proto/google/fhir/proto/dstu2/resources_go_proto"
+
+	"github.com/philips-software/go-hsdp-api/audit"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// auditRecorder records the IDs of posted events and fails the first failFor posts
+type auditRecorder struct {
+	sync.Mutex
+	ids     []string
+	failFor int
+	status  int
+}
+
+func (a *auditRecorder) handler(w http.ResponseWriter, r *http.Request) {
+	a.Lock()
+	defer a.Unlock()
+	body, _ := ioutil.ReadAll(r.Body)
+	var event struct {
+		ID string `json:"id"`
+	}
+	_ = json.Unmarshal(body, &event)
+	a.ids = append(a.ids, event.ID)
+	w.Header().Set("Content-Type", "application/json")
+	if a.failFor > 0 {
+		a.failFor--
+		w.WriteHeader(a.status)
+		_, _ = w.Write([]byte(`{}`))
+		return
+	}
+	w.WriteHeader(http.StatusCreated)
+}
+
+func (a *auditRecorder) IDs() []string {
+	a.Lock()
+	defer a.Unlock()
+	return append([]string(nil), a.ids...)
+}
+
+func TestSubmitter(t *testing.T) {
+	teardown := setup(t)
+	defer teardown()
+
+	recorder := &auditRecorder{failFor: 2, status: http.StatusServiceUnavailable}
+	muxAudit.HandleFunc("/core/audit/AuditEvent", recorder.handler)
+
+	_, err := audit.NewSubmitter(nil, audit.SubmitterConfig{})
+	assert.Equal(t, audit.ErrMissingClient, err)
+
+	submitter, err := audit.NewSubmitter(auditClient, audit.SubmitterConfig{
+		Workers:      1,
+		RetryBackoff: time.Millisecond,
+	})
+	if !assert.Nil(t, err) {
+		return
+	}
+	event := &dstu2pb.AuditEvent{}
+	id, err := submitter.Submit(context.Background(), event)
+	assert.Nil(t, err)
+	assert.NotEmpty(t, id)
+	assert.Equal(t, id, event.Id.Value)
+
+	_, err = submitter.Submit(context.Background(), &dstu2pb.AuditEvent{Id: &dstu2dt.Id{Value: "fixed"}})
+	assert.Nil(t, err)
+	assert.Nil(t, submitter.Close(context.Background()))
+
+	// Resubmissions keep the event ID
+	assert.Equal(t, []string{id, id, id, "fixed"}, recorder.IDs())
+	stats := submitter.Stats()
+	assert.Equal(t, uint64(2), stats.Submitted)
+	assert.Equal(t, uint64(2), stats.Delivered)
+	assert.Equal(t, uint64(2), stats.Retried)
+	assert.Equal(t, int64(0), stats.Pending)
+
+	_, err = submitter.Submit(context.Background(), event)
+	assert.Equal(t, audit.ErrSubmitterClosed, err)
+}
+
+func TestSubmitterSpool(t *testing.T) {
+	teardown := setup(t)
+	defer teardown()
+
+	recorder := &auditRecorder{failFor: 100, status: http.StatusBadGateway}
+	muxAudit.HandleFunc("/core/audit/AuditEvent", recorder.handler)
+
+	dir := t.TempDir()
+	var failed []string
+	submitter, err := audit.NewSubmitter(auditClient, audit.SubmitterConfig{
+		MaxRetries:   1,
+		RetryBackoff: time.Millisecond,
+		SpoolDir:     dir,
+		OnFailure: func(id string, err error) {
+			failed = append(failed, id)
+		},
+	})
+	if !assert.Nil(t, err) {
+		return
+	}
+	_, _ = submitter.Submit(context.Background(), &dstu2pb.AuditEvent{Id: &dstu2dt.Id{Value: "spooled"}})
+	assert.Nil(t, submitter.Close(context.Background()))
+	assert.Equal(t, []string{"spooled"}, failed)
+	files, _ := filepath.Glob(filepath.Join(dir, "*.json"))
+	assert.Len(t, files, 1)
+
+	// The next Submitter delivers the spooled event
+	recorder.Lock()
+	recorder.failFor = 0
+	recorder.ids = nil
+	recorder.Unlock()
+	submitter, err = audit.NewSubmitter(auditClient, audit.SubmitterConfig{SpoolDir: dir})
+	if !assert.Nil(t, err) {
+		return
+	}
+	assert.Nil(t, submitter.Flush(context.Background()))
+	assert.Equal(t, []string{"spooled"}, recorder.IDs())
+	files, _ = filepath.Glob(filepath.Join(dir, "*.json"))
+	assert.Len(t, files, 0)
+
+	// Rejected events are kept aside
+	recorder.Lock()
+	recorder.failFor = 1
+	recorder.status = http.StatusBadRequest
+	recorder.Unlock()
+	_, _ = submitter.Submit(context.Background(), &dstu2pb.AuditEvent{Id: &dstu2dt.Id{Value: "invalid"}})
+	assert.Nil(t, submitter.Close(context.Background()))
+	assert.Equal(t, uint64(1), submitter.Stats().Failed)
+	files, _ = filepath.Glob(filepath.Join(dir, "*.rejected"))
+	assert.Len(t, files, 1)
+}
+
+// batchRecorder records the posted requests and answers batch Bundles with the entry status of statusFor
+type batchRecorder struct {
+	sync.Mutex
+	requests  [][]string
+	statusFor func(id string) int
+	started   chan struct{}
+	release   chan struct{}
+	// noEntries answers batch Bundles without the status of the entries
+	noEntries bool
+}
+
+func (b *batchRecorder) handler(w http.ResponseWriter, r *http.Request) {
+	if b.started != nil {
+		b.started <- struct{}{}
+		<-b.release
+	}
+	body, _ := ioutil.ReadAll(r.Body)
+	var request struct {
+		ResourceType string `json:"resourceType"`
+		ID           string `json:"id"`
+		Entry        []struct {
+			Resource struct {
+				ID string `json:"id"`
+			} `json:"resource"`
+		} `json:"entry"`
+	}
+	_ = json.Unmarshal(body, &request)
+	w.Header().Set("Content-Type", "application/json")
+
+	b.Lock()
+	defer b.Unlock()
+	if request.ResourceType != "Bundle" {
+		b.requests = append(b.requests, []string{request.ID})
+		w.WriteHeader(b.statusFor(request.ID))
+		_, _ = w.Write([]byte(`{}`))
+		return
+	}
+	var ids, entries []string
+	if b.noEntries {
+		for _, entry := range request.Entry {
+			ids = append(ids, entry.Resource.ID)
+		}
+		b.requests = append(b.requests, ids)
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write([]byte(`{}`))
+		return
+	}
+	for _, entry := range request.Entry {
+		ids = append(ids, entry.Resource.ID)
+		status := b.statusFor(entry.Resource.ID)
+		entries = append(entries, fmt.Sprintf(`{"response":{"status":"%d %s"}}`, status, http.StatusText(status)))
+	}
+	b.requests = append(b.requests, ids)
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write([]byte(`{"resourceType":"Bundle","type":"batch-response","entry":[` + strings.Join(entries, ",") + `]}`))
+}
+
+func (b *batchRecorder) Requests() [][]string {
+	b.Lock()
+	defer b.Unlock()
+	return append([][]string(nil), b.requests...)
+}
+
+func TestSubmitterBatches(t *testing.T) {
+	teardown := setup(t)
+	defer teardown()
+
+	flaky := 1
+	recorder := &batchRecorder{
+		started: make(chan struct{}),
+		release: make(chan struct{}),
+		statusFor: func(id string) int {
+			switch id {
+			case "bad":
+				return http.StatusBadRequest
+			case "flaky":
+				if flaky > 0 {
+					flaky--
+					return http.StatusServiceUnavailable
+				}
+			}
+			return http.StatusCreated
+		},
+	}
+	muxAudit.HandleFunc("/core/audit/AuditEvent", recorder.handler)
+
+	var failed []string
+	submitter, err := audit.NewSubmitter(auditClient, audit.SubmitterConfig{
+		Workers:      1,
+		BatchSize:    25,
+		RetryBackoff: time.Millisecond,
+		OnFailure: func(id string, err error) {
+			failed = append(failed, id)
+			assert.ErrorIs(t, err, audit.ErrBadRequest)
+		},
+	})
+	if !assert.Nil(t, err) {
+		return
+	}
+	_, _ = submitter.Submit(context.Background(), &dstu2pb.AuditEvent{Id: &dstu2dt.Id{Value: "first"}})
+	// Events queued while the first request is in flight are submitted together
+	<-recorder.started
+	for _, id := range []string{"a", "bad", "flaky", "b"} {
+		_, err := submitter.Submit(context.Background(), &dstu2pb.AuditEvent{Id: &dstu2dt.Id{Value: id}})
+		assert.Nil(t, err)
+	}
+	recorder.started = nil
+	close(recorder.release)
+	assert.Nil(t, submitter.Close(context.Background()))
+
+	assert.Equal(t, [][]string{{"first"}, {"a", "bad", "flaky", "b"}, {"flaky"}}, recorder.Requests())
+	assert.Equal(t, []string{"bad"}, failed)
+	stats := submitter.Stats()
+	assert.Equal(t, uint64(5), stats.Submitted)
+	assert.Equal(t, uint64(4), stats.Delivered)
+	assert.Equal(t, uint64(1), stats.Failed)
+	assert.Equal(t, uint64(1), stats.Retried)
+}
+
+func TestSubmitterReplay(t *testing.T) {
+	teardown := setup(t)
+	defer teardown()
+
+	recorder := &auditRecorder{failFor: 4, status: http.StatusBadGateway}
+	muxAudit.HandleFunc("/core/audit/AuditEvent", recorder.handler)
+
+	dir := t.TempDir()
+	submitter, err := audit.NewSubmitter(auditClient, audit.SubmitterConfig{
+		MaxRetries:     1,
+		RetryBackoff:   time.Millisecond,
+		SpoolDir:       dir,
+		ReplayInterval: 10 * time.Millisecond,
+	})
+	if !assert.Nil(t, err) {
+		return
+	}
+	_, err = submitter.Submit(context.Background(), &dstu2pb.AuditEvent{Id: &dstu2dt.Id{Value: "replayed"}})
+	assert.Nil(t, err)
+
+	// The failed event is resubmitted from the spool while the Submitter runs
+	assert.Eventually(t, func() bool {
+		return submitter.Stats().Delivered == 1
+	}, 5*time.Second, 10*time.Millisecond)
+	assert.Nil(t, submitter.Close(context.Background()))
+	assert.Equal(t, []string{"replayed", "replayed", "replayed", "replayed", "replayed"}, recorder.IDs())
+	assert.Equal(t, uint64(2), submitter.Stats().Failed)
+	files, _ := filepath.Glob(filepath.Join(dir, "*.json"))
+	assert.Len(t, files, 0)
+}
+
+func TestSubmitterCloseUnblocksSubmit(t *testing.T) {
+	teardown := setup(t)
+	defer teardown()
+
+	release := make(chan struct{})
+	muxAudit.HandleFunc("/core/audit/AuditEvent", func(w http.ResponseWriter, r *http.Request) {
+		<-release
+		w.WriteHeader(http.StatusCreated)
+	})
+	defer close(release)
+
+	submitter, err := audit.NewSubmitter(auditClient, audit.SubmitterConfig{
+		QueueSize: 1,
+		Workers:   1,
+	})
+	if !assert.Nil(t, err) {
+		return
+	}
+	// The worker takes the first event, the second one fills the queue
+	for i := 0; i < 2; i++ {
+		_, err := submitter.Submit(context.Background(), &dstu2pb.AuditEvent{})
+		assert.Nil(t, err)
+	}
+
+	blocked := make(chan error)
+	go func() {
+		_, err := submitter.Submit(context.Background(), &dstu2pb.AuditEvent{})
+		blocked <- err
+	}()
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	assert.Equal(t, context.DeadlineExceeded, submitter.Close(ctx))
+	select {
+	case err := <-blocked:
+		assert.Equal(t, audit.ErrSubmitterClosed, err)
+	case <-time.After(time.Second):
+		t.Error("Submit still blocked after Close")
+	}
+}
+
+func TestSubmitterBatchWithoutEntries(t *testing.T) {
+	teardown := setup(t)
+	defer teardown()
+
+	recorder := &batchRecorder{
+		started:   make(chan struct{}),
+		release:   make(chan struct{}),
+		noEntries: true,
+		statusFor: func(id string) int { return http.StatusCreated },
+	}
+	muxAudit.HandleFunc("/core/audit/AuditEvent", recorder.handler)
+
+	dir := t.TempDir()
+	var failed []string
+	submitter, err := audit.NewSubmitter(auditClient, audit.SubmitterConfig{
+		Workers:      1,
+		BatchSize:    25,
+		MaxRetries:   1,
+		RetryBackoff: time.Millisecond,
+		SpoolDir:     dir,
+		OnFailure: func(id string, err error) {
+			failed = append(failed, id)
+			assert.ErrorIs(t, err, audit.ErrNonHttp20xResponse)
+		},
+	})
+	if !assert.Nil(t, err) {
+		return
+	}
+	_, _ = submitter.Submit(context.Background(), &dstu2pb.AuditEvent{Id: &dstu2dt.Id{Value: "first"}})
+	<-recorder.started
+	for _, id := range []string{"a", "b"} {
+		_, err := submitter.Submit(context.Background(), &dstu2pb.AuditEvent{Id: &dstu2dt.Id{Value: id}})
+		assert.Nil(t, err)
+	}
+	recorder.started = nil
+	close(recorder.release)
+	assert.Nil(t, submitter.Close(context.Background()))
+
+	// Without the status of every entry the events are not considered delivered
+	assert.Equal(t, [][]string{{"first"}, {"a", "b"}, {"a", "b"}}, recorder.Requests())
+	assert.Equal(t, []string{"a", "b"}, failed)
+	assert.Equal(t, uint64(1), submitter.Stats().Delivered)
+	files, _ := filepath.Glob(filepath.Join(dir, "*.json"))
+	assert.Len(t, files, 2)
+}
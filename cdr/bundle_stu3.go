@@ -0,0 +1,331 @@
+package cdr
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/google/fhir/go/proto/google/fhir/proto/stu3/codes_go_proto"
+	stu3dt "github.com/google/fhir/go/proto/google/fhir/proto/stu3/datatypes_go_proto"
+	stu3pb "github.com/google/fhir/go/proto/google/fhir/proto/stu3/resources_go_proto"
+	"github.com/google/uuid"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// BundleBuilderSTU3 builds transaction and batch bundles. Resources created in the
+// bundle get a urn:uuid fullUrl which other entries can reference before the
+// server assigns IDs.
+type BundleBuilderSTU3 struct {
+	bundleType codes_go_proto.BundleTypeCode_Value
+	entries    []*stu3pb.Bundle_Entry
+	err        error
+}
+
+// NewTransactionSTU3 returns a builder for a transaction bundle. All entries succeed or fail together
+func NewTransactionSTU3() *BundleBuilderSTU3 {
+	return &BundleBuilderSTU3{bundleType: codes_go_proto.BundleTypeCode_TRANSACTION}
+}
+
+// NewBatchSTU3 returns a builder for a batch bundle. Entries succeed or fail independently
+func NewBatchSTU3() *BundleBuilderSTU3 {
+	return &BundleBuilderSTU3{bundleType: codes_go_proto.BundleTypeCode_BATCH}
+}
+
+// Create adds an entry creating resource and returns a reference to it
+func (b *BundleBuilderSTU3) Create(resource proto.Message) *stu3dt.Reference {
+	return b.ConditionalCreate(resource, "")
+}
+
+// ConditionalCreate adds an entry creating resource unless a resource matching
+// ifNoneExist, e.g. "identifier=http://example.com|123", exists. It returns a reference to the resource
+func (b *BundleBuilderSTU3) ConditionalCreate(resource proto.Message, ifNoneExist string) *stu3dt.Reference {
+	request := &stu3pb.Bundle_Entry_Request{
+		Method: &codes_go_proto.HTTPVerbCode{Value: codes_go_proto.HTTPVerbCode_POST},
+		Url:    &stu3dt.Uri{Value: resourceTypeOf(resource)},
+	}
+	if ifNoneExist != "" {
+		request.IfNoneExist = &stu3dt.String{Value: ifNoneExist}
+	}
+	return b.add(resource, "urn:uuid:"+uuid.New().String(), request)
+}
+
+// Update adds an entry updating resource, which must have an ID, and returns a
+// reference to it. The reference is the relative "Type/id" of the resource, as
+// its ID is known before the bundle is submitted
+func (b *BundleBuilderSTU3) Update(resource proto.Message) *stu3dt.Reference {
+	id := resourceIDOf(resource)
+	if id == "" {
+		b.setErr(fmt.Errorf("entry %d: %w", len(b.entries), ErrMissingResourceID))
+	}
+	path := resourceTypeOf(resource) + "/" + id
+	// The fullUrl must be absolute, so the builder, which does not know the FHIR store, uses a urn
+	b.add(resource, "urn:uuid:"+uuid.New().String(), &stu3pb.Bundle_Entry_Request{
+		Method: &codes_go_proto.HTTPVerbCode{Value: codes_go_proto.HTTPVerbCode_PUT},
+		Url:    &stu3dt.Uri{Value: path},
+	})
+	return &stu3dt.Reference{Reference: &stu3dt.Reference_Uri{Uri: &stu3dt.String{Value: path}}}
+}
+
+// ConditionalUpdate adds an entry updating the resource matching criteria, e.g.
+// "identifier=http://example.com|123", or creating it if none matches. It returns a reference to the resource
+func (b *BundleBuilderSTU3) ConditionalUpdate(resource proto.Message, criteria string) *stu3dt.Reference {
+	return b.add(resource, "urn:uuid:"+uuid.New().String(), &stu3pb.Bundle_Entry_Request{
+		Method: &codes_go_proto.HTTPVerbCode{Value: codes_go_proto.HTTPVerbCode_PUT},
+		Url:    &stu3dt.Uri{Value: resourceTypeOf(resource) + "?" + criteria},
+	})
+}
+
+// Delete adds an entry deleting the resource at path, e.g. "Patient/123" or "Patient?identifier=123"
+func (b *BundleBuilderSTU3) Delete(path string) {
+	b.entries = append(b.entries, &stu3pb.Bundle_Entry{
+		Request: &stu3pb.Bundle_Entry_Request{
+			Method: &codes_go_proto.HTTPVerbCode{Value: codes_go_proto.HTTPVerbCode_DELETE},
+			Url:    &stu3dt.Uri{Value: path},
+		},
+	})
+}
+
+// Len returns the number of entries
+func (b *BundleBuilderSTU3) Len() int {
+	return len(b.entries)
+}
+
+// Bundle returns the bundle or the first error encountered while adding entries
+func (b *BundleBuilderSTU3) Bundle() (*stu3pb.Bundle, error) {
+	if b.err != nil {
+		return nil, b.err
+	}
+	return &stu3pb.Bundle{
+		Type:  &codes_go_proto.BundleTypeCode{Value: b.bundleType},
+		Entry: b.entries,
+	}, nil
+}
+
+func (b *BundleBuilderSTU3) add(resource proto.Message, fullURL string, request *stu3pb.Bundle_Entry_Request) *stu3dt.Reference {
+	contained, err := ContainSTU3(resource)
+	if err != nil {
+		b.setErr(fmt.Errorf("entry %d: %w", len(b.entries), err))
+	}
+	b.entries = append(b.entries, &stu3pb.Bundle_Entry{
+		FullUrl:  &stu3dt.Uri{Value: fullURL},
+		Resource: contained,
+		Request:  request,
+	})
+	return &stu3dt.Reference{Reference: &stu3dt.Reference_Uri{Uri: &stu3dt.String{Value: fullURL}}}
+}
+
+func (b *BundleBuilderSTU3) setErr(err error) {
+	if b.err == nil {
+		b.err = err
+	}
+}
+
+// ContainSTU3 wraps a STU3 resource such as *stu3pb.Patient in a ContainedResource
+func ContainSTU3(resource proto.Message) (*stu3pb.ContainedResource, error) {
+	if contained, ok := resource.(*stu3pb.ContainedResource); ok {
+		return contained, nil
+	}
+	if resource == nil {
+		return nil, ErrUnsupportedResource
+	}
+	contained := &stu3pb.ContainedResource{}
+	m := contained.ProtoReflect()
+	name := resource.ProtoReflect().Descriptor().FullName()
+	oneof := m.Descriptor().Oneofs().Get(0)
+	for i := 0; i < oneof.Fields().Len(); i++ {
+		fd := oneof.Fields().Get(i)
+		if fd.Message() != nil && fd.Message().FullName() == name {
+			m.Set(fd, protoreflect.ValueOfMessage(resource.ProtoReflect()))
+			return contained, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: %s", ErrUnsupportedResource, name)
+}
+
+// resourceTypeOf returns the FHIR resource type of resource, e.g. "Patient"
+func resourceTypeOf(resource proto.Message) string {
+	if resource == nil {
+		return ""
+	}
+	if contained, ok := resource.(*stu3pb.ContainedResource); ok {
+		m := contained.ProtoReflect()
+		if fd := m.WhichOneof(m.Descriptor().Oneofs().Get(0)); fd != nil {
+			return string(fd.Message().Name())
+		}
+		return ""
+	}
+	return string(resource.ProtoReflect().Descriptor().Name())
+}
+
+// resourceIDOf returns the logical ID of resource, empty if it has none
+func resourceIDOf(resource proto.Message) string {
+	if resource == nil {
+		return ""
+	}
+	m := resource.ProtoReflect()
+	if contained, ok := resource.(*stu3pb.ContainedResource); ok {
+		cm := contained.ProtoReflect()
+		fd := cm.WhichOneof(cm.Descriptor().Oneofs().Get(0))
+		if fd == nil {
+			return ""
+		}
+		m = cm.Get(fd).Message()
+	}
+	fd := m.Descriptor().Fields().ByName("id")
+	if fd == nil || fd.Message() == nil || !m.Has(fd) {
+		return ""
+	}
+	id := m.Get(fd).Message()
+	value := id.Descriptor().Fields().ByName("value")
+	if value == nil {
+		return ""
+	}
+	return id.Get(value).String()
+}
+
+// BundleEntryResultSTU3 is the outcome of a single bundle entry
+type BundleEntryResultSTU3 struct {
+	// Entry is the submitted entry
+	Entry *stu3pb.Bundle_Entry
+	// Status is the HTTP status line of the entry, e.g. "201 Created"
+	Status     string
+	StatusCode int
+	Location   string
+	ETag       string
+	// Resource is set when the server returned the resulting resource
+	Resource *stu3pb.ContainedResource
+	// Outcome holds the issues reported for the entry, if any
+	Outcome *stu3pb.OperationOutcome
+}
+
+// BundleResultSTU3 is the outcome of a transaction or batch
+type BundleResultSTU3 struct {
+	// Bundle is the response bundle
+	Bundle *stu3pb.Bundle
+	// Entries are the outcomes in the order of the submitted entries
+	Entries []BundleEntryResultSTU3
+}
+
+// Transaction submits a transaction bundle. Either all entries succeed or the
+// transaction fails and an error is returned
+func (o *OperationsSTU3Service) Transaction(bundle *stu3pb.Bundle, options ...OptionFunc) (*BundleResultSTU3, *Response, error) {
+	return o.postBundle("Transaction", codes_go_proto.BundleTypeCode_TRANSACTION, bundle, options)
+}
+
+// Batch submits a batch bundle. Entries are processed independently, so check
+// the StatusCode of each entry result
+func (o *OperationsSTU3Service) Batch(bundle *stu3pb.Bundle, options ...OptionFunc) (*BundleResultSTU3, *Response, error) {
+	return o.postBundle("Batch", codes_go_proto.BundleTypeCode_BATCH, bundle, options)
+}
+
+func (o *OperationsSTU3Service) postBundle(operation string, bundleType codes_go_proto.BundleTypeCode_Value, bundle *stu3pb.Bundle, options []OptionFunc) (*BundleResultSTU3, *Response, error) {
+	if bundle.Type == nil {
+		// Set the type on a copy so the caller's bundle is left as it is
+		bundle = proto.Clone(bundle).(*stu3pb.Bundle)
+		bundle.Type = &codes_go_proto.BundleTypeCode{Value: bundleType}
+	}
+	if bundle.Type.Value != bundleType {
+		return nil, nil, fmt.Errorf("%w: %s", ErrInvalidBundleType, bundle.Type.Value)
+	}
+	bundleJSON, err := o.ma.MarshalResource(bundle)
+	if err != nil {
+		return nil, nil, err
+	}
+	req, err := o.client.newCDRRequest(http.MethodPost, "", bundleJSON, options)
+	if err != nil {
+		return nil, nil, err
+	}
+	req.Header.Set("Content-Type", "application/fhir+json")
+	req.Header.Set("Accept", fhirJSONSTU3)
+	var bundleResponse bytes.Buffer
+	resp, err := o.client.do(req, &bundleResponse)
+	if (err != nil && err != io.EOF) || resp == nil {
+		if resp == nil && err != nil {
+			err = fmt.Errorf("OperationsSTU3Service.%s: %w", operation, ErrEmptyResult)
+		}
+		if resp != nil {
+			err = o.outcomeError(resp, err)
+		}
+		return nil, resp, err
+	}
+	unmarshalled, err := o.um.Unmarshal(bundleResponse.Bytes())
+	if err != nil {
+		return nil, resp, fmt.Errorf("FHIR unmarshal: %w", err)
+	}
+	responseBundle := unmarshalled.(*stu3pb.ContainedResource).GetBundle()
+	if responseBundle == nil {
+		return nil, resp, ErrUnexpectedResourceType
+	}
+	// Response entries are in the order of the request entries
+	result := &BundleResultSTU3{Bundle: responseBundle}
+	for i, entry := range bundle.Entry {
+		entryResult := BundleEntryResultSTU3{Entry: entry}
+		if i < len(responseBundle.Entry) {
+			e := responseBundle.Entry[i]
+			entryResult.Resource = e.Resource
+			if r := e.Response; r != nil {
+				entryResult.Status = r.Status.GetValue()
+				entryResult.StatusCode = parseStatusCode(entryResult.Status)
+				entryResult.Location = r.Location.GetValue()
+				entryResult.ETag = r.Etag.GetValue()
+				entryResult.Outcome = r.Outcome.GetOperationOutcome()
+			}
+		}
+		result.Entries = append(result.Entries, entryResult)
+	}
+	return result, resp, nil
+}
+
+// OutcomeErrorSTU3 is returned when the CDR rejects a request with an OperationOutcome
+type OutcomeErrorSTU3 struct {
+	Err     error
+	Outcome *stu3pb.OperationOutcome
+}
+
+func (e *OutcomeErrorSTU3) Error() string {
+	for _, issue := range e.Outcome.GetIssue() {
+		if d := issue.Diagnostics.GetValue(); d != "" {
+			return fmt.Sprintf("%v: %s", e.Err, d)
+		}
+	}
+	return e.Err.Error()
+}
+
+// Unwrap returns the underlying error
+func (e *OutcomeErrorSTU3) Unwrap() error {
+	return e.Err
+}
+
+// outcomeError reads the OperationOutcome from the body of a failed response
+// and returns it with err. err is returned as is when there is none
+func (o *OperationsSTU3Service) outcomeError(resp *Response, err error) error {
+	defer resp.Body.Close()
+	body, readErr := ioutil.ReadAll(resp.Body)
+	if readErr != nil || len(body) == 0 {
+		return err
+	}
+	unmarshalled, umErr := o.um.Unmarshal(body)
+	if umErr != nil {
+		return err
+	}
+	outcome := unmarshalled.(*stu3pb.ContainedResource).GetOperationOutcome()
+	if outcome == nil {
+		return err
+	}
+	return &OutcomeErrorSTU3{Err: err, Outcome: outcome}
+}
+
+// parseStatusCode returns the code of an HTTP status line such as "201 Created"
+func parseStatusCode(status string) int {
+	fields := strings.Fields(status)
+	if len(fields) == 0 {
+		return 0
+	}
+	code, _ := strconv.Atoi(fields[0])
+	return code
+}
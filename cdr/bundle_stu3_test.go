@@ -0,0 +1,122 @@
+package cdr_test
+
+import (
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/google/fhir/go/proto/google/fhir/proto/stu3/codes_go_proto"
+	stu3dt "github.com/google/fhir/go/proto/google/fhir/proto/stu3/datatypes_go_proto"
+	stu3pb "github.com/google/fhir/go/proto/google/fhir/proto/stu3/resources_go_proto"
+
+	"github.com/philips-software/go-hsdp-api/cdr"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBundleBuilderSTU3(t *testing.T) {
+	b := cdr.NewTransactionSTU3()
+	patientRef := b.ConditionalCreate(&stu3pb.Patient{
+		Identifier: []*stu3dt.Identifier{{
+			System: &stu3dt.Uri{Value: "http://example.com"},
+			Value:  &stu3dt.String{Value: "123"},
+		}},
+	}, "identifier=http://example.com|123")
+	assert.True(t, strings.HasPrefix(patientRef.GetUri().Value, "urn:uuid:"))
+	b.Create(&stu3pb.Observation{Subject: patientRef})
+	orgRef := b.Update(&stu3pb.Organization{Id: &stu3dt.Id{Value: "org-1"}})
+	assert.Equal(t, "Organization/org-1", orgRef.GetUri().Value)
+	b.ConditionalUpdate(&stu3pb.Practitioner{}, "identifier=456")
+	b.Delete("Patient/old")
+	assert.Equal(t, 5, b.Len())
+
+	bundle, err := b.Bundle()
+	if !assert.Nil(t, err) {
+		return
+	}
+	assert.Equal(t, codes_go_proto.BundleTypeCode_TRANSACTION, bundle.Type.Value)
+	assert.Equal(t, "identifier=http://example.com|123", bundle.Entry[0].Request.IfNoneExist.Value)
+	assert.Equal(t, "Patient", bundle.Entry[0].Request.Url.Value)
+	assert.Equal(t, patientRef.GetUri().Value, bundle.Entry[1].Resource.GetObservation().Subject.GetUri().Value)
+	assert.Equal(t, codes_go_proto.HTTPVerbCode_PUT, bundle.Entry[2].Request.Method.Value)
+	assert.Equal(t, "Organization/org-1", bundle.Entry[2].Request.Url.Value)
+	assert.True(t, strings.HasPrefix(bundle.Entry[2].FullUrl.Value, "urn:uuid:"))
+	assert.Equal(t, "Practitioner?identifier=456", bundle.Entry[3].Request.Url.Value)
+	assert.Equal(t, codes_go_proto.HTTPVerbCode_DELETE, bundle.Entry[4].Request.Method.Value)
+
+	b = cdr.NewBatchSTU3()
+	b.Update(&stu3pb.Patient{})
+	_, err = b.Bundle()
+	assert.ErrorIs(t, err, cdr.ErrMissingResourceID)
+
+	b = cdr.NewBatchSTU3()
+	b.Create(&stu3dt.String{Value: "not a resource"})
+	_, err = b.Bundle()
+	assert.ErrorIs(t, err, cdr.ErrUnsupportedResource)
+}
+
+func TestTransactionSTU3(t *testing.T) {
+	teardown := setup(t)
+	defer teardown()
+
+	muxCDR.HandleFunc("/store/fhir/"+cdrOrgID, func(w http.ResponseWriter, r *http.Request) {
+		if !assert.Equal(t, "POST", r.Method) {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		body, _ := ioutil.ReadAll(r.Body)
+		unmarshalled, err := um.Unmarshal(body)
+		if !assert.Nil(t, err) {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		bundle := unmarshalled.(*stu3pb.ContainedResource).GetBundle()
+		if !assert.NotNil(t, bundle) || !assert.Len(t, bundle.Entry, 2) {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("Content-Type", "application/fhir+json")
+		w.WriteHeader(http.StatusOK)
+		_, _ = io.WriteString(w, `{
+  "resourceType": "Bundle",
+  "type": "batch-response",
+  "entry": [
+    {"response": {"status": "201 Created", "location": "Patient/p1/_history/1", "etag": "W/\"1\""}},
+    {"response": {"status": "400 Bad Request", "outcome": {"resourceType": "OperationOutcome", "issue": [{"severity": "error", "code": "invalid", "diagnostics": "bad observation"}]}}}
+  ]
+}`)
+	})
+
+	b := cdr.NewBatchSTU3()
+	ref := b.Create(&stu3pb.Organization{Name: &stu3dt.String{Value: "Hospital"}})
+	b.Create(&stu3pb.Patient{ManagingOrganization: ref})
+	bundle, err := b.Bundle()
+	if !assert.Nil(t, err) {
+		return
+	}
+	result, resp, err := cdrClient.OperationsSTU3.Batch(bundle)
+	if !assert.Nil(t, err) || !assert.NotNil(t, resp) {
+		return
+	}
+	if !assert.Len(t, result.Entries, 2) {
+		return
+	}
+	assert.Equal(t, 201, result.Entries[0].StatusCode)
+	assert.Equal(t, "Patient/p1/_history/1", result.Entries[0].Location)
+	assert.Equal(t, `W/"1"`, result.Entries[0].ETag)
+	assert.Equal(t, ref.GetUri().Value, result.Entries[0].Entry.FullUrl.Value)
+	assert.Equal(t, 400, result.Entries[1].StatusCode)
+	if assert.NotNil(t, result.Entries[1].Outcome) {
+		assert.Equal(t, "bad observation", result.Entries[1].Outcome.Issue[0].Diagnostics.Value)
+	}
+
+	_, _, err = cdrClient.OperationsSTU3.Transaction(bundle)
+	assert.ErrorIs(t, err, cdr.ErrInvalidBundleType)
+
+	// A bundle without type is submitted as a batch and left as it is
+	bundle.Type = nil
+	_, _, err = cdrClient.OperationsSTU3.Batch(bundle)
+	assert.Nil(t, err)
+	assert.Nil(t, bundle.Type)
+}
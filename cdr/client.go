@@ -10,6 +10,7 @@ import (
 	"net/http"
 	"net/url"
 	"strings"
+	"time"
 
 	"github.com/philips-software/go-hsdp-api/internal"
 
@@ -21,6 +22,10 @@ import (
 const (
 	userAgent  = "go-hsdp-api/cdr/" + internal.LibraryVersion
 	APIVersion = "1"
+
+	// The FHIR version of a request is negotiated using these media types
+	fhirJSONSTU3 = "application/fhir+json;fhirVersion=3.0"
+	fhirJSONR4   = "application/fhir+json;fhirVersion=4.0"
 )
 
 // OptionFunc is the function signature function for options
@@ -50,8 +55,12 @@ type Client struct {
 	// User agent used when communicating with the HSDP CDR API
 	UserAgent string
 
-	TenantSTU3     *TenantSTU3Service
-	OperationsSTU3 *OperationsSTU3Service
+	TenantSTU3       *TenantSTU3Service
+	OperationsSTU3   *OperationsSTU3Service
+	TenantR4         *TenantR4Service
+	OperationsR4     *OperationsR4Service
+	SubscriptionSTU3 *SubscriptionSTU3Service
+	BulkExport       *BulkExportService
 }
 
 // NewClient returns a new HSDP CDR API client. Configured console and IAM clients
@@ -80,6 +89,19 @@ func newClient(iamClient *iam.Client, config *Config) (*Client, error) {
 
 	c.TenantSTU3 = &TenantSTU3Service{timeZone: config.TimeZone, client: c, ma: ma, um: um}
 	c.OperationsSTU3 = &OperationsSTU3Service{timeZone: config.TimeZone, client: c, ma: ma, um: um}
+	c.SubscriptionSTU3 = &SubscriptionSTU3Service{timeZone: config.TimeZone, client: c, ma: ma, um: um}
+
+	maR4, err := jsonformat.NewMarshaller(false, "", "", jsonformat.R4)
+	if err != nil {
+		return nil, fmt.Errorf("cdr.NewClient create FHIR R4 marshaller: %w", err)
+	}
+	umR4, err := jsonformat.NewUnmarshaller(config.TimeZone, jsonformat.R4)
+	if err != nil {
+		return nil, fmt.Errorf("cdr.NewClient create FHIR R4 unmarshaller (timezone=[%s]): %w", config.TimeZone, err)
+	}
+	c.TenantR4 = &TenantR4Service{timeZone: config.TimeZone, client: c, ma: maR4, um: umR4}
+	c.OperationsR4 = &OperationsR4Service{timeZone: config.TimeZone, client: c, ma: maR4, um: umR4}
+	c.BulkExport = &BulkExportService{timeZone: config.TimeZone, client: c, umSTU3: um, umR4: umR4}
 
 	return c, nil
 }
@@ -149,7 +171,11 @@ func (c *Client) SetEndpointURL(urlStr string) error {
 func (c *Client) newCDRRequest(method, path string, bodyBytes []byte, options []OptionFunc) (*http.Request, error) {
 	u := *c.fhirStoreURL
 	// Set the encoded opaque data
-	u.Opaque = c.fhirStoreURL.Path + c.config.RootOrgID + "/" + path
+	u.Opaque = c.fhirStoreURL.Path + c.config.RootOrgID
+	if path != "" {
+		// An empty path addresses the base of the FHIR store, e.g. for transactions
+		u.Opaque += "/" + path
+	}
 
 	req := &http.Request{
 		Method:     method,
@@ -193,6 +219,33 @@ type Response struct {
 	*http.Response
 }
 
+// ETag returns the ETag header of the response, e.g. W/"1"
+func (r *Response) ETag() string {
+	if r == nil || r.Response == nil {
+		return ""
+	}
+	return r.Header.Get("ETag")
+}
+
+// VersionID returns the resource version of the ETag header, e.g. 1 for W/"1"
+func (r *Response) VersionID() string {
+	etag := strings.TrimPrefix(r.ETag(), "W/")
+	return strings.Trim(etag, `"`)
+}
+
+// LastModified returns the Last-Modified header of the response. The zero time is
+// returned when the header is missing or invalid
+func (r *Response) LastModified() time.Time {
+	if r == nil || r.Response == nil {
+		return time.Time{}
+	}
+	t, err := http.ParseTime(r.Header.Get("Last-Modified"))
+	if err != nil {
+		return time.Time{}
+	}
+	return t
+}
+
 // newResponse creates a new Response for the provided http.Response.
 func newResponse(r *http.Response) *Response {
 	response := &Response{Response: r}
@@ -0,0 +1,165 @@
+package cdr
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/google/fhir/go/proto/google/fhir/proto/stu3/codes_go_proto"
+	stu3pb "github.com/google/fhir/go/proto/google/fhir/proto/stu3/resources_go_proto"
+)
+
+// ConditionalOutcome describes what a conditional operation did
+type ConditionalOutcome int
+
+const (
+	// ConditionalCreated means no resource matched and a new one was created
+	ConditionalCreated ConditionalOutcome = iota + 1
+	// ConditionalUpdated means the matching resource was updated
+	ConditionalUpdated
+	// ConditionalMatched means a matching resource exists so nothing was created
+	ConditionalMatched
+	// ConditionalDeleted means the matching resources were deleted
+	ConditionalDeleted
+	// ConditionalNoMatch means no resource matched so nothing was deleted
+	ConditionalNoMatch
+)
+
+func (c ConditionalOutcome) String() string {
+	switch c {
+	case ConditionalCreated:
+		return "created"
+	case ConditionalUpdated:
+		return "updated"
+	case ConditionalMatched:
+		return "matched"
+	case ConditionalDeleted:
+		return "deleted"
+	case ConditionalNoMatch:
+		return "no match"
+	}
+	return "unknown"
+}
+
+// ConditionalResultSTU3 is the result of a conditional operation
+type ConditionalResultSTU3 struct {
+	Outcome ConditionalOutcome
+	// Resource is the created, updated or matched resource if the server returned it
+	Resource *stu3pb.ContainedResource
+}
+
+// ConditionalCreate creates a resource of resourceType unless one matching criteria
+// exists. ErrMultipleMatches is returned when criteria matches more than one resource
+func (o *OperationsSTU3Service) ConditionalCreate(resourceType string, criteria *SearchOptions, jsonBody []byte, options ...OptionFunc) (*ConditionalResultSTU3, *Response, error) {
+	query := criteria.Values().Encode()
+	if query == "" {
+		return nil, nil, ErrMissingCriteria
+	}
+	ifNoneExist := func(req *http.Request) error {
+		req.Header.Set("If-None-Exist", query)
+		return nil
+	}
+	contained, resp, err := o.Post(resourceType, jsonBody, withOption(options, ifNoneExist)...)
+	if err != nil {
+		return nil, resp, multipleMatches(resourceType, resp, err)
+	}
+	outcome := ConditionalMatched
+	if resp.StatusCode == http.StatusCreated {
+		outcome = ConditionalCreated
+	}
+	return &ConditionalResultSTU3{Outcome: outcome, Resource: contained}, resp, nil
+}
+
+// ConditionalUpdate updates the resource of resourceType matching criteria or creates
+// it if none matches. ErrMultipleMatches is returned when criteria matches more than one resource
+func (o *OperationsSTU3Service) ConditionalUpdate(resourceType string, criteria *SearchOptions, jsonBody []byte, options ...OptionFunc) (*ConditionalResultSTU3, *Response, error) {
+	query := criteria.Values().Encode()
+	if query == "" {
+		return nil, nil, ErrMissingCriteria
+	}
+	withCriteria := func(req *http.Request) error {
+		req.URL.RawQuery = query
+		return nil
+	}
+	contained, resp, err := o.Put(resourceType, jsonBody, withOption(options, withCriteria)...)
+	if err != nil {
+		return nil, resp, multipleMatches(resourceType, resp, err)
+	}
+	outcome := ConditionalUpdated
+	if resp.StatusCode == http.StatusCreated {
+		outcome = ConditionalCreated
+	}
+	return &ConditionalResultSTU3{Outcome: outcome, Resource: contained}, resp, nil
+}
+
+// ConditionalDelete deletes the resource of resourceType matching criteria. Deleting
+// when nothing matches succeeds with the ConditionalNoMatch outcome. ErrMultipleMatches
+// is returned when criteria matches more than one resource and the server does not
+// support deleting multiple resources
+func (o *OperationsSTU3Service) ConditionalDelete(resourceType string, criteria *SearchOptions, options ...OptionFunc) (*ConditionalResultSTU3, *Response, error) {
+	query := criteria.Values().Encode()
+	if query == "" {
+		return nil, nil, ErrMissingCriteria
+	}
+	withCriteria := func(req *http.Request) error {
+		req.URL.RawQuery = query
+		return nil
+	}
+	req, err := o.client.newCDRRequest(http.MethodDelete, resourceType, nil, withOption(options, withCriteria))
+	if err != nil {
+		return nil, nil, err
+	}
+	req.Header.Set("Content-Type", "application/fhir+json")
+	req.Header.Set("Accept", fhirJSONSTU3)
+	var operationResponse bytes.Buffer
+	resp, err := o.client.do(req, &operationResponse)
+	if resp == nil {
+		if err == nil || err == io.EOF {
+			err = fmt.Errorf("OperationsSTU3Service.ConditionalDelete: %w", ErrEmptyResult)
+		}
+		return nil, nil, err
+	}
+	if resp.StatusCode == http.StatusNotFound {
+		return &ConditionalResultSTU3{Outcome: ConditionalNoMatch}, resp, nil
+	}
+	if err != nil && err != io.EOF {
+		return nil, resp, multipleMatches(resourceType, resp, err)
+	}
+	outcome := ConditionalDeleted
+	if o.nothingDeleted(operationResponse.Bytes()) {
+		outcome = ConditionalNoMatch
+	}
+	return &ConditionalResultSTU3{Outcome: outcome}, resp, nil
+}
+
+// nothingDeleted reports whether the OperationOutcome in body says no resource
+// matched the criteria of a conditional delete
+func (o *OperationsSTU3Service) nothingDeleted(body []byte) bool {
+	if len(body) == 0 {
+		return false
+	}
+	unmarshalled, err := o.um.Unmarshal(body)
+	if err != nil {
+		return false
+	}
+	for _, issue := range unmarshalled.(*stu3pb.ContainedResource).GetOperationOutcome().GetIssue() {
+		if issue.GetCode().GetValue() == codes_go_proto.IssueTypeCode_NOT_FOUND {
+			return true
+		}
+		// HAPI based servers report an informational issue instead
+		if strings.Contains(issue.GetDiagnostics().GetValue(), "Nothing has been deleted") {
+			return true
+		}
+	}
+	return false
+}
+
+// multipleMatches replaces err with ErrMultipleMatches when the precondition failed
+func multipleMatches(resourceType string, resp *Response, err error) error {
+	if resp != nil && resp.StatusCode == http.StatusPreconditionFailed {
+		return fmt.Errorf("%s: %w", resourceType, ErrMultipleMatches)
+	}
+	return err
+}
@@ -0,0 +1,129 @@
+package cdr_test
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/philips-software/go-hsdp-api/cdr"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConditionalOperationsSTU3(t *testing.T) {
+	teardown := setup(t)
+	defer teardown()
+
+	orgID := "f5fe538f-c3b5-4454-8774-cd3789f59b9f"
+	existing := map[string]int{
+		"http://example.com|existing": 1,
+		"http://example.com|multiple": 2,
+	}
+
+	muxCDR.HandleFunc("/store/fhir/"+cdrOrgID+"/Organization", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/fhir+json")
+		identifier := r.URL.Query().Get("identifier")
+		if r.Method == "POST" {
+			if r.URL.RawQuery != "" {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			ifNoneExist := r.Header.Get("If-None-Exist")
+			if ifNoneExist == "" {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			q, _ := url.ParseQuery(ifNoneExist)
+			identifier = q.Get("identifier")
+		}
+		switch existing[identifier] {
+		case 0:
+			switch r.Method {
+			case "POST", "PUT":
+				w.WriteHeader(http.StatusCreated)
+				_, _ = io.WriteString(w, stu3Organization(orgID, "Created"))
+			case "DELETE":
+				// Nothing matched so nothing was deleted
+				w.WriteHeader(http.StatusOK)
+				_, _ = io.WriteString(w, `{"resourceType":"OperationOutcome","issue":[{"severity":"information",
+					"code":"informational","diagnostics":"Unable to find resource matching URL. Nothing has been deleted."}]}`)
+			}
+		case 1:
+			switch r.Method {
+			case "POST", "PUT":
+				w.WriteHeader(http.StatusOK)
+				_, _ = io.WriteString(w, stu3Organization(orgID, "Existing"))
+			case "DELETE":
+				w.WriteHeader(http.StatusNoContent)
+			}
+		default:
+			w.WriteHeader(http.StatusPreconditionFailed)
+			_, _ = io.WriteString(w, `{"resourceType":"OperationOutcome"}`)
+		}
+	})
+
+	criteria := func(value string) *cdr.SearchOptions {
+		return &cdr.SearchOptions{Params: []cdr.SearchParam{cdr.Param("identifier", "http://example.com|"+value)}}
+	}
+	body := []byte(stu3Organization("", "Hospital"))
+
+	// The criteria option is not written into spare capacity of the caller's options
+	options := make([]cdr.OptionFunc, 0, 1)
+	result, resp, err := cdrClient.OperationsSTU3.ConditionalCreate("Organization", criteria("new"), body, options...)
+	if !assert.Nil(t, err) || !assert.NotNil(t, resp) || !assert.NotNil(t, result) {
+		return
+	}
+	assert.Nil(t, options[:1][0])
+	assert.Equal(t, cdr.ConditionalCreated, result.Outcome)
+	assert.Equal(t, "Created", result.Resource.GetOrganization().GetName().GetValue())
+
+	result, _, err = cdrClient.OperationsSTU3.ConditionalCreate("Organization", criteria("existing"), body)
+	if !assert.Nil(t, err) || !assert.NotNil(t, result) {
+		return
+	}
+	assert.Equal(t, cdr.ConditionalMatched, result.Outcome)
+	assert.Equal(t, "matched", result.Outcome.String())
+
+	_, resp, err = cdrClient.OperationsSTU3.ConditionalCreate("Organization", criteria("multiple"), body)
+	assert.True(t, errors.Is(err, cdr.ErrMultipleMatches))
+	if assert.NotNil(t, resp) {
+		assert.Equal(t, http.StatusPreconditionFailed, resp.StatusCode)
+	}
+
+	result, _, err = cdrClient.OperationsSTU3.ConditionalUpdate("Organization", criteria("existing"), body)
+	if !assert.Nil(t, err) || !assert.NotNil(t, result) {
+		return
+	}
+	assert.Equal(t, cdr.ConditionalUpdated, result.Outcome)
+
+	result, _, err = cdrClient.OperationsSTU3.ConditionalUpdate("Organization", criteria("new"), body)
+	if !assert.Nil(t, err) || !assert.NotNil(t, result) {
+		return
+	}
+	assert.Equal(t, cdr.ConditionalCreated, result.Outcome)
+
+	_, _, err = cdrClient.OperationsSTU3.ConditionalUpdate("Organization", criteria("multiple"), body)
+	assert.True(t, errors.Is(err, cdr.ErrMultipleMatches))
+
+	result, _, err = cdrClient.OperationsSTU3.ConditionalDelete("Organization", criteria("existing"))
+	if !assert.Nil(t, err) || !assert.NotNil(t, result) {
+		return
+	}
+	assert.Equal(t, cdr.ConditionalDeleted, result.Outcome)
+
+	result, _, err = cdrClient.OperationsSTU3.ConditionalDelete("Organization", criteria("new"))
+	if !assert.Nil(t, err) || !assert.NotNil(t, result) {
+		return
+	}
+	assert.Equal(t, cdr.ConditionalNoMatch, result.Outcome)
+	assert.Equal(t, "no match", result.Outcome.String())
+
+	_, _, err = cdrClient.OperationsSTU3.ConditionalDelete("Organization", criteria("multiple"))
+	assert.True(t, errors.Is(err, cdr.ErrMultipleMatches))
+
+	_, _, err = cdrClient.OperationsSTU3.ConditionalDelete("Organization", nil)
+	assert.Equal(t, cdr.ErrMissingCriteria, err)
+	_, _, err = cdrClient.OperationsSTU3.ConditionalUpdate("Organization", &cdr.SearchOptions{}, body)
+	assert.Equal(t, cdr.ErrMissingCriteria, err)
+}
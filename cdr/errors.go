@@ -11,4 +11,19 @@ var (
 	ErrCouldNoReadResourceAfterCreate = errors.New("could not read resource after create")
 	ErrNotImplementedYet              = errors.New("not implemented yet")
 	ErrNonHttp20xResponse             = errors.New("non http 20x CDR response")
+	ErrNoMorePages                    = errors.New("no more pages")
+	ErrInvalidNextLink                = errors.New("next link outside of FHIR store")
+	ErrUnexpectedResourceType         = errors.New("unexpected resource type")
+	ErrUnsupportedResource            = errors.New("unsupported resource")
+	ErrMissingResourceID              = errors.New("missing resource id")
+	ErrInvalidBundleType              = errors.New("invalid bundle type")
+	ErrVersionConflict                = errors.New("version conflict")
+	ErrMissingCriteria                = errors.New("missing search criteria")
+	ErrMultipleMatches                = errors.New("search criteria match multiple resources")
+	ErrInvalidNotification            = errors.New("invalid notification")
+	ErrInvalidPatchResult             = errors.New("patch does not result in a valid resource")
+	ErrMissingContentLocation         = errors.New("missing content location")
+	ErrExportFailed                   = errors.New("export failed")
+	ErrMissingClient                  = errors.New("missing client")
+	ErrInvalidExportFile              = errors.New("invalid export file")
 )
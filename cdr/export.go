@@ -0,0 +1,554 @@
+package cdr
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/fhir/go/jsonformat"
+	r4pb "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/resources/bundle_and_contained_resource_go_proto"
+	stu3pb "github.com/google/fhir/go/proto/google/fhir/proto/stu3/resources_go_proto"
+)
+
+const (
+	// DefaultExportMinPollInterval is the default wait time before the first status poll
+	DefaultExportMinPollInterval = 2 * time.Second
+	// DefaultExportMaxPollInterval is the default maximum wait time between status polls
+	DefaultExportMaxPollInterval = time.Minute
+	// DefaultExportDownloadWorkers is the default number of files downloaded concurrently
+	DefaultExportDownloadWorkers = 4
+	// DefaultExportDownloadRetries is the default number of times an interrupted download is resumed
+	DefaultExportDownloadRetries = 3
+	// DefaultExportDownloadBackoff is the wait time before the first resumption of a download
+	DefaultExportDownloadBackoff = 500 * time.Millisecond
+
+	// NDJSONFormat is the default output format of an export
+	NDJSONFormat = "application/fhir+ndjson"
+
+	partialExt = ".part"
+)
+
+// BulkExportService exports data from the CDR using the FHIR Bulk Data $export operation
+type BulkExportService struct {
+	client   *Client
+	timeZone string
+	umSTU3   *jsonformat.Unmarshaller
+	umR4     *jsonformat.Unmarshaller
+}
+
+// ExportOptions limits the data of an export
+type ExportOptions struct {
+	// Types are the resource types to export, e.g. "Patient" and "Observation". All types when empty
+	Types []string
+	// Since only exports resources changed at or after this time
+	Since *time.Time
+	// OutputFormat defaults to NDJSONFormat
+	OutputFormat string
+}
+
+// Values returns the query parameters of the export request
+func (o *ExportOptions) Values() url.Values {
+	values := url.Values{}
+	if o == nil {
+		return values
+	}
+	if o.OutputFormat != "" {
+		values.Set("_outputFormat", o.OutputFormat)
+	}
+	if len(o.Types) > 0 {
+		values.Set("_type", strings.Join(o.Types, ","))
+	}
+	if o.Since != nil {
+		values.Set("_since", o.Since.UTC().Format(time.RFC3339))
+	}
+	return values
+}
+
+// ExportJob is a running export
+type ExportJob struct {
+	// StatusURL is the content location returned when the export was started
+	StatusURL string
+	// MinPollInterval and MaxPollInterval bound the wait time between status polls
+	// when the CDR does not send a Retry-After header
+	MinPollInterval time.Duration
+	MaxPollInterval time.Duration
+}
+
+// ExportFile is an output file of a completed export
+type ExportFile struct {
+	Type  string `json:"type"`
+	URL   string `json:"url"`
+	Count int    `json:"count,omitempty"`
+}
+
+// ExportManifest describes the output of a completed export
+type ExportManifest struct {
+	TransactionTime     string       `json:"transactionTime"`
+	Request             string       `json:"request"`
+	RequiresAccessToken bool         `json:"requiresAccessToken"`
+	Output              []ExportFile `json:"output"`
+	Error               []ExportFile `json:"error"`
+}
+
+// ExportStatus is the state of an export
+type ExportStatus struct {
+	// Done is true when the export completed. Manifest is set in that case
+	Done     bool
+	Manifest *ExportManifest
+	// Progress is the progress reported by the CDR, e.g. "50% complete"
+	Progress string
+	// RetryAfter is the wait time requested by the CDR before the next poll, zero if none
+	RetryAfter time.Duration
+}
+
+// DownloadedFile is an output file stored on disk
+type DownloadedFile struct {
+	ExportFile
+	Path string
+}
+
+// System starts an export of all data in the FHIR store
+func (s *BulkExportService) System(opt *ExportOptions, options ...OptionFunc) (*ExportJob, *Response, error) {
+	return s.kickoff("$export", opt, options)
+}
+
+// Group starts an export of the data of the patients in the Group with the given ID
+func (s *BulkExportService) Group(groupID string, opt *ExportOptions, options ...OptionFunc) (*ExportJob, *Response, error) {
+	return s.kickoff("Group/"+groupID+"/$export", opt, options)
+}
+
+// Patient starts an export of the data of all patients
+func (s *BulkExportService) Patient(opt *ExportOptions, options ...OptionFunc) (*ExportJob, *Response, error) {
+	return s.kickoff("Patient/$export", opt, options)
+}
+
+func (s *BulkExportService) kickoff(path string, opt *ExportOptions, options []OptionFunc) (*ExportJob, *Response, error) {
+	req, err := s.client.newCDRRequest(http.MethodGet, path, nil, options)
+	if err != nil {
+		return nil, nil, err
+	}
+	req.URL.RawQuery = opt.Values().Encode()
+	req.Header.Set("Accept", "application/fhir+json")
+	req.Header.Set("Prefer", "respond-async")
+	var kickoffResponse bytes.Buffer
+	resp, err := s.client.do(req, &kickoffResponse)
+	if (err != nil && err != io.EOF) || resp == nil {
+		if resp == nil && err != nil {
+			err = fmt.Errorf("BulkExportService.kickoff: %w", ErrEmptyResult)
+		}
+		return nil, resp, err
+	}
+	location := resp.Header.Get("Content-Location")
+	if resp.StatusCode != http.StatusAccepted || location == "" {
+		return nil, resp, ErrMissingContentLocation
+	}
+	return &ExportJob{
+		StatusURL:       location,
+		MinPollInterval: DefaultExportMinPollInterval,
+		MaxPollInterval: DefaultExportMaxPollInterval,
+	}, resp, nil
+}
+
+// Status polls the state of job once. A failed export returns an error wrapping ErrExportFailed
+func (s *BulkExportService) Status(ctx context.Context, job *ExportJob, options ...OptionFunc) (*ExportStatus, *Response, error) {
+	req, err := s.newStatusRequest(ctx, http.MethodGet, job, options)
+	if err != nil {
+		return nil, nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+	resp, err := s.client.iamClient.HttpClient().Do(req)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer resp.Body.Close()
+	response := newResponse(resp)
+	status := &ExportStatus{
+		Progress:   resp.Header.Get("X-Progress"),
+		RetryAfter: retryAfter(resp.Header.Get("Retry-After")),
+	}
+	switch resp.StatusCode {
+	case http.StatusAccepted:
+		return status, response, nil
+	case http.StatusOK:
+		var manifest ExportManifest
+		if err := json.NewDecoder(resp.Body).Decode(&manifest); err != nil {
+			return nil, response, err
+		}
+		status.Done = true
+		status.Manifest = &manifest
+		return status, response, nil
+	}
+	body, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 64*1024))
+	return nil, response, fmt.Errorf("%w: StatusCode %d: %s", ErrExportFailed, resp.StatusCode, strings.TrimSpace(string(body)))
+}
+
+// Wait polls the state of job until it completes, fails or ctx is done. It waits
+// as long as the Retry-After header requests and backs off exponentially otherwise
+func (s *BulkExportService) Wait(ctx context.Context, job *ExportJob, options ...OptionFunc) (*ExportManifest, error) {
+	interval := job.MinPollInterval
+	if interval <= 0 {
+		interval = DefaultExportMinPollInterval
+	}
+	maxInterval := job.MaxPollInterval
+	if maxInterval <= 0 {
+		maxInterval = DefaultExportMaxPollInterval
+	}
+	for {
+		status, _, err := s.Status(ctx, job, options...)
+		if err != nil {
+			return nil, err
+		}
+		if status.Done {
+			return status.Manifest, nil
+		}
+		wait := interval
+		if status.RetryAfter > 0 {
+			wait = status.RetryAfter
+		} else {
+			interval *= 2
+			if interval > maxInterval {
+				interval = maxInterval
+			}
+		}
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// Cancel stops job and removes its output
+func (s *BulkExportService) Cancel(job *ExportJob, options ...OptionFunc) (*Response, error) {
+	req, err := s.newStatusRequest(context.Background(), http.MethodDelete, job, options)
+	if err != nil {
+		return nil, err
+	}
+	var cancelResponse bytes.Buffer
+	resp, err := s.client.do(req, &cancelResponse)
+	if err == io.EOF {
+		err = nil
+	}
+	return resp, err
+}
+
+// Download stores the output files of manifest in dir using up to workers
+// concurrent downloads. Files already in dir are skipped and partially
+// downloaded files are resumed, so an interrupted Download can be called again.
+func (s *BulkExportService) Download(ctx context.Context, manifest *ExportManifest, dir string, workers int) ([]DownloadedFile, error) {
+	if workers <= 0 {
+		workers = DefaultExportDownloadWorkers
+	}
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+	files := make([]DownloadedFile, len(manifest.Output))
+	for i, f := range manifest.Output {
+		// The type is part of the file name so it must not leave dir
+		if strings.ContainsAny(f.Type, `/\`) {
+			return nil, fmt.Errorf("type %q: %w", f.Type, ErrInvalidExportFile)
+		}
+		files[i] = DownloadedFile{ExportFile: f, Path: filepath.Join(dir, fmt.Sprintf("%s-%d.ndjson", f.Type, i))}
+	}
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	var firstErr error
+	sem := make(chan struct{}, workers)
+	for _, f := range files {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(f DownloadedFile) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			if err := s.downloadFile(ctx, manifest, f); err != nil {
+				mu.Lock()
+				if firstErr == nil {
+					firstErr = err
+				}
+				mu.Unlock()
+			}
+		}(f)
+	}
+	wg.Wait()
+	if firstErr != nil {
+		return nil, firstErr
+	}
+	return files, nil
+}
+
+// downloadFile downloads f to a partial file, resuming with a Range request
+// after an interruption, and renames it when complete
+func (s *BulkExportService) downloadFile(ctx context.Context, manifest *ExportManifest, f DownloadedFile) error {
+	if _, err := os.Stat(f.Path); err == nil {
+		return nil
+	}
+	partial := f.Path + partialExt
+	var err error
+	backoff := DefaultExportDownloadBackoff
+	for attempt := 0; attempt <= DefaultExportDownloadRetries; attempt++ {
+		if attempt > 0 {
+			timer := time.NewTimer(backoff)
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return ctx.Err()
+			case <-timer.C:
+			}
+			backoff *= 2
+		}
+		if err = s.resumeDownload(ctx, manifest, f.URL, partial); err == nil {
+			return os.Rename(partial, f.Path)
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+	}
+	return fmt.Errorf("download %s: %w", f.URL, err)
+}
+
+func (s *BulkExportService) resumeDownload(ctx context.Context, manifest *ExportManifest, fileURL, partial string) error {
+	var offset int64
+	if info, err := os.Stat(partial); err == nil {
+		offset = info.Size()
+	}
+	resp, err := s.open(ctx, manifest, fileURL, offset)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	flags := os.O_CREATE | os.O_WRONLY | os.O_APPEND
+	switch resp.StatusCode {
+	case http.StatusRequestedRangeNotSatisfiable:
+		// The partial file is complete when the server reports its size, otherwise
+		// it does not belong to the file and the next attempt starts over
+		if size, ok := contentRangeSize(resp.Header.Get("Content-Range")); ok && size == offset {
+			return nil
+		}
+		if err := os.Remove(partial); err != nil {
+			return err
+		}
+		return fmt.Errorf("GET %s: partial file of %d bytes does not match: %w", fileURL, offset, ErrNonHttp20xResponse)
+	case http.StatusOK:
+		// The server ignored the range so start over
+		flags |= os.O_TRUNC
+	}
+	out, err := os.OpenFile(partial, flags, 0600)
+	if err != nil {
+		return err
+	}
+	if _, err = io.Copy(out, resp.Body); err != nil {
+		_ = out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+// Open streams an output file of manifest. The caller must close the returned reader
+func (s *BulkExportService) Open(ctx context.Context, manifest *ExportManifest, file ExportFile) (io.ReadCloser, error) {
+	resp, err := s.open(ctx, manifest, file.URL, 0)
+	if err != nil {
+		return nil, err
+	}
+	return resp.Body, nil
+}
+
+func (s *BulkExportService) open(ctx context.Context, manifest *ExportManifest, fileURL string, offset int64) (*http.Response, error) {
+	req, err := http.NewRequest(http.MethodGet, fileURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Accept", NDJSONFormat)
+	if manifest.RequiresAccessToken {
+		req.Header.Set("Authorization", "Bearer "+s.client.iamClient.Token())
+	}
+	if offset > 0 {
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+	}
+	resp, err := s.client.iamClient.HttpClient().Do(req)
+	if err != nil {
+		return nil, err
+	}
+	switch resp.StatusCode {
+	case http.StatusOK, http.StatusPartialContent:
+		return resp, nil
+	case http.StatusRequestedRangeNotSatisfiable:
+		if offset > 0 {
+			return resp, nil
+		}
+	}
+	_ = resp.Body.Close()
+	return nil, fmt.Errorf("GET %s: StatusCode %d: %w", fileURL, resp.StatusCode, ErrNonHttp20xResponse)
+}
+
+// newStatusRequest returns a request for the status URL of job. The token is
+// only sent when the status URL is on the FHIR store host
+func (s *BulkExportService) newStatusRequest(ctx context.Context, method string, job *ExportJob, options []OptionFunc) (*http.Request, error) {
+	statusURL, err := s.client.fhirStoreURL.Parse(job.StatusURL)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequest(method, statusURL.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	req = req.WithContext(ctx)
+	for _, fn := range options {
+		if fn == nil {
+			continue
+		}
+		if err := fn(req); err != nil {
+			return nil, err
+		}
+	}
+	if statusURL.Host == s.client.fhirStoreURL.Host {
+		req.Header.Set("Authorization", "Bearer "+s.client.iamClient.Token())
+	}
+	req.Header.Set("API-Version", APIVersion)
+	if s.client.UserAgent != "" {
+		req.Header.Set("User-Agent", s.client.UserAgent)
+	}
+	return req, nil
+}
+
+// contentRangeSize returns the complete length of a Content-Range header, e.g. "bytes */1234"
+func contentRangeSize(value string) (int64, bool) {
+	i := strings.LastIndex(value, "/")
+	if !strings.HasPrefix(value, "bytes ") || i < 0 {
+		return 0, false
+	}
+	size, err := strconv.ParseInt(value[i+1:], 10, 64)
+	return size, err == nil
+}
+
+// retryAfter parses a Retry-After header given in seconds or as an HTTP date
+func retryAfter(value string) time.Duration {
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	if at, err := http.ParseTime(value); err == nil {
+		if wait := time.Until(at); wait > 0 {
+			return wait
+		}
+	}
+	return 0
+}
+
+// ResourceIteratorSTU3 decodes the resources of an NDJSON stream one line at a time
+type ResourceIteratorSTU3 struct {
+	lines    *bufio.Reader
+	um       *jsonformat.Unmarshaller
+	resource *stu3pb.ContainedResource
+	line     int
+	err      error
+}
+
+// ResourcesSTU3 returns an iterator over the STU3 resources in r, e.g. a file returned by Open
+func (s *BulkExportService) ResourcesSTU3(r io.Reader) *ResourceIteratorSTU3 {
+	return &ResourceIteratorSTU3{lines: bufio.NewReader(r), um: s.umSTU3}
+}
+
+// Next decodes the next resource. It returns false at the end of the stream or
+// when an error occurred, which is returned by Err
+func (it *ResourceIteratorSTU3) Next() bool {
+	line, err := nextLine(it.lines, &it.line, &it.err)
+	if err != nil {
+		return false
+	}
+	unmarshalled, err := it.um.Unmarshal(line)
+	if err != nil {
+		it.err = fmt.Errorf("line %d: FHIR unmarshal: %w", it.line, err)
+		return false
+	}
+	it.resource = unmarshalled.(*stu3pb.ContainedResource)
+	return true
+}
+
+// Resource returns the current resource
+func (it *ResourceIteratorSTU3) Resource() *stu3pb.ContainedResource {
+	return it.resource
+}
+
+// Err returns the error which stopped the iteration, if any
+func (it *ResourceIteratorSTU3) Err() error {
+	return it.err
+}
+
+// ResourceIteratorR4 decodes the resources of an NDJSON stream one line at a time
+type ResourceIteratorR4 struct {
+	lines    *bufio.Reader
+	um       *jsonformat.Unmarshaller
+	resource *r4pb.ContainedResource
+	line     int
+	err      error
+}
+
+// ResourcesR4 returns an iterator over the R4 resources in r, e.g. a file returned by Open
+func (s *BulkExportService) ResourcesR4(r io.Reader) *ResourceIteratorR4 {
+	return &ResourceIteratorR4{lines: bufio.NewReader(r), um: s.umR4}
+}
+
+// Next decodes the next resource. It returns false at the end of the stream or
+// when an error occurred, which is returned by Err
+func (it *ResourceIteratorR4) Next() bool {
+	line, err := nextLine(it.lines, &it.line, &it.err)
+	if err != nil {
+		return false
+	}
+	unmarshalled, err := it.um.Unmarshal(line)
+	if err != nil {
+		it.err = fmt.Errorf("line %d: FHIR unmarshal: %w", it.line, err)
+		return false
+	}
+	it.resource = unmarshalled.(*r4pb.ContainedResource)
+	return true
+}
+
+// Resource returns the current resource
+func (it *ResourceIteratorR4) Resource() *r4pb.ContainedResource {
+	return it.resource
+}
+
+// Err returns the error which stopped the iteration, if any
+func (it *ResourceIteratorR4) Err() error {
+	return it.err
+}
+
+// nextLine returns the next non-empty line. At the end of the stream it returns
+// io.EOF, other errors are stored in errp
+func nextLine(r *bufio.Reader, lineNo *int, errp *error) ([]byte, error) {
+	if *errp != nil {
+		return nil, *errp
+	}
+	for {
+		line, err := r.ReadBytes('\n')
+		*lineNo++
+		if trimmed := bytes.TrimSpace(line); len(trimmed) > 0 {
+			return trimmed, nil
+		}
+		if err == io.EOF {
+			return nil, err
+		}
+		if err != nil {
+			*errp = err
+			return nil, err
+		}
+	}
+}
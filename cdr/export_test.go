@@ -0,0 +1,259 @@
+package cdr_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/philips-software/go-hsdp-api/cdr"
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	patientsNDJSON = `{"resourceType":"Patient","id":"1"}` + "\n" +
+		`{"resourceType":"Patient","id":"2"}` + "\n\n" +
+		`{"resourceType":"Patient","id":"3"}`
+	observationsNDJSON = `{"resourceType":"Observation","id":"o1","status":"final","code":{"text":"weight"}}` + "\n"
+)
+
+func TestBulkExport(t *testing.T) {
+	teardown := setup(t)
+	defer teardown()
+
+	var polls, truncated int32
+	statusPath := "/store/fhir/" + cdrOrgID + "/$export-poll-status/job1"
+
+	muxCDR.HandleFunc("/store/fhir/"+cdrOrgID+"/Group/group1/$export", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "respond-async", r.Header.Get("Prefer"))
+		assert.Equal(t, "Patient,Observation", r.URL.Query().Get("_type"))
+		assert.Equal(t, "2021-01-01T00:00:00Z", r.URL.Query().Get("_since"))
+		assert.Equal(t, "Bearer "+token, r.Header.Get("Authorization"))
+		w.Header().Set("Content-Location", serverCDR.URL+statusPath)
+		w.WriteHeader(http.StatusAccepted)
+	})
+	muxCDR.HandleFunc(statusPath, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "Bearer "+token, r.Header.Get("Authorization"))
+		if r.Method == "DELETE" {
+			w.WriteHeader(http.StatusAccepted)
+			return
+		}
+		switch atomic.AddInt32(&polls, 1) {
+		case 1:
+			w.Header().Set("X-Progress", "50% complete")
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusAccepted)
+		case 2:
+			w.WriteHeader(http.StatusAccepted)
+		default:
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusOK)
+			_, _ = io.WriteString(w, `{"transactionTime":"2021-06-01T12:00:00Z","request":"x","requiresAccessToken":true,"output":[
+				{"type":"Patient","url":"`+serverCDR.URL+`/files/patients.ndjson","count":3},
+				{"type":"Observation","url":"`+serverCDR.URL+`/files/observations.ndjson","count":1}],"error":[]}`)
+		}
+	})
+	serveFile := func(content string) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, "Bearer "+token, r.Header.Get("Authorization"))
+			start := 0
+			if rng := r.Header.Get("Range"); rng != "" {
+				start, _ = strconv.Atoi(strings.TrimSuffix(strings.TrimPrefix(rng, "bytes="), "-"))
+				w.Header().Set("Content-Range", fmt.Sprintf("bytes %d-%d/%d", start, len(content)-1, len(content)))
+				w.WriteHeader(http.StatusPartialContent)
+				_, _ = io.WriteString(w, content[start:])
+				return
+			}
+			if atomic.AddInt32(&truncated, 1) == 1 {
+				// Drop the connection halfway the first download
+				w.Header().Set("Content-Length", strconv.Itoa(len(content)))
+				w.WriteHeader(http.StatusOK)
+				_, _ = io.WriteString(w, content[:len(content)/2])
+				return
+			}
+			w.WriteHeader(http.StatusOK)
+			_, _ = io.WriteString(w, content)
+		}
+	}
+	muxCDR.HandleFunc("/files/patients.ndjson", serveFile(patientsNDJSON))
+	muxCDR.HandleFunc("/files/observations.ndjson", serveFile(observationsNDJSON))
+
+	since := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+	job, resp, err := cdrClient.BulkExport.Group("group1", &cdr.ExportOptions{
+		Types: []string{"Patient", "Observation"},
+		Since: &since,
+	})
+	if !assert.Nil(t, err) || !assert.NotNil(t, resp) || !assert.NotNil(t, job) {
+		return
+	}
+	assert.Equal(t, serverCDR.URL+statusPath, job.StatusURL)
+	job.MinPollInterval = time.Millisecond
+	job.MaxPollInterval = 5 * time.Millisecond
+
+	status, _, err := cdrClient.BulkExport.Status(context.Background(), job)
+	if !assert.Nil(t, err) || !assert.NotNil(t, status) {
+		return
+	}
+	assert.False(t, status.Done)
+	assert.Equal(t, "50% complete", status.Progress)
+
+	manifest, err := cdrClient.BulkExport.Wait(context.Background(), job)
+	if !assert.Nil(t, err) || !assert.NotNil(t, manifest) || !assert.Len(t, manifest.Output, 2) {
+		return
+	}
+	assert.Equal(t, int32(3), atomic.LoadInt32(&polls))
+
+	dir, err := ioutil.TempDir("", "export")
+	if !assert.Nil(t, err) {
+		return
+	}
+	defer os.RemoveAll(dir)
+	files, err := cdrClient.BulkExport.Download(context.Background(), manifest, dir, 2)
+	if !assert.Nil(t, err) || !assert.Len(t, files, 2) {
+		return
+	}
+	for _, f := range files {
+		data, err := ioutil.ReadFile(f.Path)
+		if !assert.Nil(t, err) {
+			return
+		}
+		if f.Type == "Patient" {
+			assert.Equal(t, patientsNDJSON, string(data))
+		} else {
+			assert.Equal(t, observationsNDJSON, string(data))
+		}
+		_, err = os.Stat(f.Path + ".part")
+		assert.True(t, os.IsNotExist(err))
+	}
+	partials, _ := filepath.Glob(filepath.Join(dir, "*.part"))
+	assert.Len(t, partials, 0)
+
+	f, err := os.Open(files[0].Path)
+	if !assert.Nil(t, err) {
+		return
+	}
+	defer f.Close()
+	it := cdrClient.BulkExport.ResourcesSTU3(f)
+	var ids []string
+	for it.Next() {
+		ids = append(ids, it.Resource().GetPatient().GetId().GetValue())
+	}
+	assert.Nil(t, it.Err())
+	assert.Equal(t, []string{"1", "2", "3"}, ids)
+
+	body, err := cdrClient.BulkExport.Open(context.Background(), manifest, manifest.Output[1])
+	if !assert.Nil(t, err) {
+		return
+	}
+	defer body.Close()
+	itR4 := cdrClient.BulkExport.ResourcesR4(body)
+	assert.True(t, itR4.Next())
+	assert.Equal(t, "o1", itR4.Resource().GetObservation().GetId().GetValue())
+	assert.False(t, itR4.Next())
+	assert.Nil(t, itR4.Err())
+
+	resp, err = cdrClient.BulkExport.Cancel(job)
+	assert.Nil(t, err)
+	if assert.NotNil(t, resp) {
+		assert.Equal(t, http.StatusAccepted, resp.StatusCode)
+	}
+}
+
+func TestBulkExportFailures(t *testing.T) {
+	teardown := setup(t)
+	defer teardown()
+
+	muxCDR.HandleFunc("/store/fhir/"+cdrOrgID+"/$export", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Location", "https://elsewhere.example.com/status/1")
+		w.WriteHeader(http.StatusAccepted)
+	})
+	muxCDR.HandleFunc("/store/fhir/"+cdrOrgID+"/Patient/$export", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	muxCDR.HandleFunc("/status/failed", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "", r.Header.Get("Authorization"))
+		w.WriteHeader(http.StatusInternalServerError)
+		_, _ = io.WriteString(w, `{"resourceType":"OperationOutcome"}`)
+	})
+	muxCDR.HandleFunc("/status/slow", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Retry-After", "120")
+		w.WriteHeader(http.StatusAccepted)
+	})
+
+	job, _, err := cdrClient.BulkExport.System(nil)
+	if !assert.Nil(t, err) || !assert.NotNil(t, job) {
+		return
+	}
+	_, _, err = cdrClient.BulkExport.Patient(nil)
+	assert.Equal(t, cdr.ErrMissingContentLocation, err)
+
+	// The token is not sent to other hosts
+	failed := &cdr.ExportJob{StatusURL: strings.Replace(serverCDR.URL, "127.0.0.1", "localhost", 1) + "/status/failed"}
+	_, err = cdrClient.BulkExport.Wait(context.Background(), failed)
+	assert.True(t, errors.Is(err, cdr.ErrExportFailed))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	_, err = cdrClient.BulkExport.Wait(ctx, &cdr.ExportJob{StatusURL: serverCDR.URL + "/status/slow"})
+	assert.Equal(t, context.DeadlineExceeded, err)
+}
+
+func TestBulkExportDownloadValidation(t *testing.T) {
+	teardown := setup(t)
+	defer teardown()
+
+	muxCDR.HandleFunc("/files/patients.ndjson", func(w http.ResponseWriter, r *http.Request) {
+		if rng := r.Header.Get("Range"); rng != "" {
+			start, _ := strconv.Atoi(strings.TrimSuffix(strings.TrimPrefix(rng, "bytes="), "-"))
+			if start >= len(patientsNDJSON) {
+				w.Header().Set("Content-Range", fmt.Sprintf("bytes */%d", len(patientsNDJSON)))
+				w.WriteHeader(http.StatusRequestedRangeNotSatisfiable)
+				return
+			}
+		}
+		w.WriteHeader(http.StatusOK)
+		_, _ = io.WriteString(w, patientsNDJSON)
+	})
+
+	dir, err := ioutil.TempDir("", "export")
+	if !assert.Nil(t, err) {
+		return
+	}
+	defer os.RemoveAll(dir)
+
+	_, err = cdrClient.BulkExport.Download(context.Background(), &cdr.ExportManifest{
+		Output: []cdr.ExportFile{{Type: "../Patient", URL: serverCDR.URL + "/files/patients.ndjson"}},
+	}, dir, 1)
+	assert.True(t, errors.Is(err, cdr.ErrInvalidExportFile))
+
+	// A partial file larger than the file on the server is downloaded again
+	path := filepath.Join(dir, "Patient-0.ndjson")
+	_ = ioutil.WriteFile(path+".part", []byte(patientsNDJSON+"\n{}\n{}"), 0600)
+	files, err := cdrClient.BulkExport.Download(context.Background(), &cdr.ExportManifest{
+		Output: []cdr.ExportFile{{Type: "Patient", URL: serverCDR.URL + "/files/patients.ndjson"}},
+	}, dir, 1)
+	if !assert.Nil(t, err) || !assert.Len(t, files, 1) {
+		return
+	}
+	data, _ := ioutil.ReadFile(path)
+	assert.Equal(t, patientsNDJSON, string(data))
+
+	// A complete partial file is only renamed
+	_ = os.Remove(path)
+	_ = ioutil.WriteFile(path+".part", []byte(patientsNDJSON), 0600)
+	_, err = cdrClient.BulkExport.Download(context.Background(), &cdr.ExportManifest{
+		Output: []cdr.ExportFile{{Type: "Patient", URL: serverCDR.URL + "/files/patients.ndjson"}},
+	}, dir, 1)
+	assert.Nil(t, err)
+	data, _ = ioutil.ReadFile(path)
+	assert.Equal(t, patientsNDJSON, string(data))
+}
@@ -1,6 +1,7 @@
 package stu3
 
 import (
+	"strings"
 	"time"
 
 	"github.com/google/fhir/go/proto/google/fhir/proto/stu3/codes_go_proto"
@@ -57,6 +58,23 @@ func DeleteEndpointValue() StringValue {
 	}
 }
 
+// StatusValue returns the status of the subscription, e.g. "active" or "error"
+func StatusValue() StringValue {
+	return func(sub *stu3pb.Subscription) string {
+		if sub.Status == nil {
+			return ""
+		}
+		return strings.ToLower(strings.ReplaceAll(sub.Status.Value.String(), "_", "-"))
+	}
+}
+
+// ErrorValue returns the last error reported for the subscription, empty string otherwise
+func ErrorValue() StringValue {
+	return func(sub *stu3pb.Subscription) string {
+		return sub.Error.GetValue()
+	}
+}
+
 // WithDeleteEndpoint adds an endpoint which is called a Resource is deleted
 // This is an extension supported by CDR
 func WithDeleteEndpoint(endpoint string) WithFunc {
@@ -5,6 +5,8 @@ import (
 	"time"
 
 	"github.com/google/fhir/go/jsonformat"
+	"github.com/google/fhir/go/proto/google/fhir/proto/stu3/codes_go_proto"
+	stu3dt "github.com/google/fhir/go/proto/google/fhir/proto/stu3/datatypes_go_proto"
 
 	"github.com/stretchr/testify/assert"
 
@@ -45,3 +47,17 @@ func TestNewSubscription(t *testing.T) {
 	getDEV := stu3.DeleteEndpointValue()
 	assert.Equal(t, deleteEndpoint, getDEV(sub))
 }
+
+func TestSubscriptionStatus(t *testing.T) {
+	sub, err := stu3.NewSubscription(stu3.WithCriteria("Patient?given=Ron"))
+	if !assert.Nil(t, err) {
+		return
+	}
+	assert.Equal(t, "requested", stu3.StatusValue()(sub))
+	assert.Equal(t, "", stu3.ErrorValue()(sub))
+
+	sub.Status.Value = codes_go_proto.SubscriptionStatusCode_ERROR
+	sub.Error = &stu3dt.String{Value: "endpoint unreachable"}
+	assert.Equal(t, "error", stu3.StatusValue()(sub))
+	assert.Equal(t, "endpoint unreachable", stu3.ErrorValue()(sub))
+}
@@ -0,0 +1,117 @@
+package cdr
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	stu3pb "github.com/google/fhir/go/proto/google/fhir/proto/stu3/resources_go_proto"
+)
+
+// HistoryOptions limits the versions returned by a history request
+type HistoryOptions struct {
+	// Since only returns versions created at or after this time
+	Since *time.Time
+	// Count is the page size
+	Count *int
+}
+
+// Values returns the query parameters of the history request
+func (o *HistoryOptions) Values() url.Values {
+	values := url.Values{}
+	if o == nil {
+		return values
+	}
+	if o.Since != nil {
+		values.Set("_since", o.Since.UTC().Format(time.RFC3339))
+	}
+	if o.Count != nil {
+		values.Set("_count", strconv.Itoa(*o.Count))
+	}
+	return values
+}
+
+// VersionConflictError is returned when a versioned update or delete fails
+// because the resource was changed by someone else
+type VersionConflictError struct {
+	ResourceID string
+	// VersionID is the version the request expected
+	VersionID string
+	Response  *Response
+}
+
+func (e *VersionConflictError) Error() string {
+	return fmt.Sprintf("%s: version %s is not the current version: %v", e.ResourceID, e.VersionID, ErrVersionConflict)
+}
+
+// Unwrap returns ErrVersionConflict
+func (e *VersionConflictError) Unwrap() error {
+	return ErrVersionConflict
+}
+
+// WithIfMatch makes a request conditional on the current version of the resource
+func WithIfMatch(versionID string) OptionFunc {
+	return func(req *http.Request) error {
+		req.Header.Set("If-Match", `W/"`+versionID+`"`)
+		return nil
+	}
+}
+
+// withOption returns options followed by option without modifying the backing array of options
+func withOption(options []OptionFunc, option OptionFunc) []OptionFunc {
+	return append(append(make([]OptionFunc, 0, len(options)+1), options...), option)
+}
+
+// VRead returns a specific version of a FHIR resource
+func (o *OperationsSTU3Service) VRead(resourceID, versionID string, options ...OptionFunc) (*stu3pb.ContainedResource, *Response, error) {
+	return o.Get(resourceID+"/_history/"+versionID, options...)
+}
+
+// History returns the first page of versions. Use a path of "" for the history
+// of all resources, a resource type such as "Patient" for the history of all
+// resources of that type or "Patient/123" for the history of a single resource.
+// Use Next to read the following pages.
+func (o *OperationsSTU3Service) History(path string, opt *HistoryOptions, options ...OptionFunc) (*stu3pb.Bundle, *Response, error) {
+	historyPath := "_history"
+	if path != "" {
+		historyPath = strings.TrimSuffix(path, "/") + "/_history"
+	}
+	req, err := o.client.newCDRRequest(http.MethodGet, historyPath, nil, options)
+	if err != nil {
+		return nil, nil, err
+	}
+	req.URL.RawQuery = opt.Values().Encode()
+	return o.searchBundle(req)
+}
+
+// PutIfMatch updates a FHIR resource if versionID is its current version.
+// A *VersionConflictError is returned otherwise
+func (o *OperationsSTU3Service) PutIfMatch(resourceID, versionID string, jsonBody []byte, options ...OptionFunc) (*stu3pb.ContainedResource, *Response, error) {
+	contained, resp, err := o.Put(resourceID, jsonBody, withOption(options, WithIfMatch(versionID))...)
+	return contained, resp, versionConflict(resourceID, versionID, resp, err)
+}
+
+// PatchIfMatch patches a FHIR resource if versionID is its current version.
+// A *VersionConflictError is returned otherwise
+func (o *OperationsSTU3Service) PatchIfMatch(resourceID, versionID string, jsonPatch []byte, options ...OptionFunc) (*stu3pb.ContainedResource, *Response, error) {
+	contained, resp, err := o.Patch(resourceID, jsonPatch, withOption(options, WithIfMatch(versionID))...)
+	return contained, resp, versionConflict(resourceID, versionID, resp, err)
+}
+
+// DeleteIfMatch removes a FHIR resource if versionID is its current version.
+// A *VersionConflictError is returned otherwise
+func (o *OperationsSTU3Service) DeleteIfMatch(resourceID, versionID string, options ...OptionFunc) (bool, *Response, error) {
+	ok, resp, err := o.Delete(resourceID, withOption(options, WithIfMatch(versionID))...)
+	return ok, resp, versionConflict(resourceID, versionID, resp, err)
+}
+
+// versionConflict replaces err with a *VersionConflictError when the precondition failed
+func versionConflict(resourceID, versionID string, resp *Response, err error) error {
+	if err != nil && resp != nil && resp.StatusCode == http.StatusPreconditionFailed {
+		return &VersionConflictError{ResourceID: resourceID, VersionID: versionID, Response: resp}
+	}
+	return err
+}
@@ -0,0 +1,124 @@
+package cdr_test
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/philips-software/go-hsdp-api/cdr"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVersionedOperationsSTU3(t *testing.T) {
+	teardown := setup(t)
+	defer teardown()
+
+	orgID := "f5fe538f-c3b5-4454-8774-cd3789f59b9f"
+	lastModified := time.Date(2021, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	muxCDR.HandleFunc("/store/fhir/"+cdrOrgID+"/Organization/"+orgID, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/fhir+json")
+		if r.Method == "GET" {
+			w.Header().Set("ETag", `W/"2"`)
+			w.Header().Set("Last-Modified", lastModified.Format(http.TimeFormat))
+			w.WriteHeader(http.StatusOK)
+			_, _ = io.WriteString(w, r4Organization(orgID, "Hospital"))
+			return
+		}
+		if r.Header.Get("If-Match") != `W/"2"` {
+			w.WriteHeader(http.StatusPreconditionFailed)
+			_, _ = io.WriteString(w, `{"resourceType":"OperationOutcome"}`)
+			return
+		}
+		switch r.Method {
+		case "PUT":
+			w.Header().Set("ETag", `W/"3"`)
+			w.WriteHeader(http.StatusOK)
+			_, _ = io.WriteString(w, r4Organization(orgID, "Hospital2"))
+		case "DELETE":
+			w.WriteHeader(http.StatusNoContent)
+		}
+	})
+	muxCDR.HandleFunc("/store/fhir/"+cdrOrgID+"/Organization/"+orgID+"/_history/1", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/fhir+json")
+		w.Header().Set("ETag", `W/"1"`)
+		w.WriteHeader(http.StatusOK)
+		_, _ = io.WriteString(w, r4Organization(orgID, "Old Hospital"))
+	})
+
+	_, resp, err := cdrClient.OperationsSTU3.Get("Organization/" + orgID)
+	if !assert.Nil(t, err) {
+		return
+	}
+	assert.Equal(t, `W/"2"`, resp.ETag())
+	assert.Equal(t, "2", resp.VersionID())
+	assert.True(t, lastModified.Equal(resp.LastModified()))
+
+	old, resp, err := cdrClient.OperationsSTU3.VRead("Organization/"+orgID, "1")
+	if !assert.Nil(t, err) {
+		return
+	}
+	assert.Equal(t, "Old Hospital", old.GetOrganization().Name.Value)
+	assert.Equal(t, "1", resp.VersionID())
+
+	body := []byte(r4Organization(orgID, "Hospital2"))
+	_, _, err = cdrClient.OperationsSTU3.PutIfMatch("Organization/"+orgID, "1", body)
+	var conflict *cdr.VersionConflictError
+	if assert.True(t, errors.As(err, &conflict)) {
+		assert.Equal(t, "1", conflict.VersionID)
+		assert.Equal(t, http.StatusPreconditionFailed, conflict.Response.StatusCode)
+	}
+	assert.ErrorIs(t, err, cdr.ErrVersionConflict)
+
+	// The If-Match option is not written into spare capacity of the caller's options
+	options := make([]cdr.OptionFunc, 0, 1)
+	updated, resp, err := cdrClient.OperationsSTU3.PutIfMatch("Organization/"+orgID, "2", body, options...)
+	if !assert.Nil(t, err) {
+		return
+	}
+	assert.Nil(t, options[:1][0])
+	assert.Equal(t, "Hospital2", updated.GetOrganization().Name.Value)
+	assert.Equal(t, "3", resp.VersionID())
+
+	_, _, err = cdrClient.OperationsSTU3.DeleteIfMatch("Organization/"+orgID, "1")
+	assert.ErrorIs(t, err, cdr.ErrVersionConflict)
+	ok, _, err := cdrClient.OperationsSTU3.DeleteIfMatch("Organization/"+orgID, "2")
+	assert.Nil(t, err)
+	assert.True(t, ok)
+}
+
+func TestHistorySTU3(t *testing.T) {
+	teardown := setup(t)
+	defer teardown()
+
+	since := time.Date(2021, 6, 1, 0, 0, 0, 0, time.UTC)
+	base := "/store/fhir/" + cdrOrgID
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/fhir+json")
+		if r.URL.Query().Get("page") == "2" {
+			_, _ = io.WriteString(w, `{"resourceType":"Bundle","type":"history","entry":[`+patientEntry("p1", "match")+`]}`)
+			return
+		}
+		assert.Equal(t, "2021-06-01T00:00:00Z", r.URL.Query().Get("_since"))
+		_, _ = io.WriteString(w, `{"resourceType":"Bundle","type":"history",
+"link":[{"relation":"next","url":"`+r.URL.Path+`?page=2"}],"entry":[`+patientEntry("p1", "match")+`]}`)
+	}
+	muxCDR.HandleFunc(base+"/_history", handler)
+	muxCDR.HandleFunc(base+"/Patient/_history", handler)
+	muxCDR.HandleFunc(base+"/Patient/p1/_history", handler)
+
+	for _, path := range []string{"", "Patient", "Patient/p1"} {
+		bundle, _, err := cdrClient.OperationsSTU3.History(path, &cdr.HistoryOptions{Since: &since})
+		if !assert.Nil(t, err, path) {
+			return
+		}
+		assert.Len(t, bundle.Entry, 1)
+		next, _, err := cdrClient.OperationsSTU3.Next(bundle)
+		if !assert.Nil(t, err, path) {
+			return
+		}
+		assert.Equal(t, "", cdr.NextLinkSTU3(next))
+	}
+}
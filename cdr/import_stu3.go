@@ -0,0 +1,774 @@
+package cdr
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/google/fhir/go/proto/google/fhir/proto/stu3/codes_go_proto"
+	stu3dt "github.com/google/fhir/go/proto/google/fhir/proto/stu3/datatypes_go_proto"
+	stu3pb "github.com/google/fhir/go/proto/google/fhir/proto/stu3/resources_go_proto"
+	"github.com/google/uuid"
+)
+
+const (
+	// DefaultImportBundleSize is the default number of resources per bundle
+	DefaultImportBundleSize = 100
+	// DefaultImportWorkers is the default number of bundles submitted concurrently
+	DefaultImportWorkers = 4
+
+	importJournalExt = ".references"
+)
+
+// ImportConfig configures an ImporterSTU3. Zero values are replaced by their defaults
+type ImportConfig struct {
+	BundleSize int
+	Workers    int
+	// Transaction submits transaction bundles, which fail as a whole, instead of batch bundles
+	Transaction bool
+	// PreserveIDs updates resources with their IDs from the input instead of
+	// creating them with IDs assigned by the CDR. References are left as they are
+	PreserveIDs bool
+	// CheckpointFile records the progress of the import. The IDs assigned to imported
+	// resources are appended to CheckpointFile with a .references extension. An import
+	// using the same file continues where it stopped
+	CheckpointFile string
+	// Report receives a JSON line with the details of every resource which failed
+	Report io.Writer
+}
+
+// ImportStats holds the counters of an import
+type ImportStats struct {
+	Read     int64
+	Skipped  int64
+	Imported int64
+	Failed   int64
+}
+
+// ImportFailure is a line of the failure report
+type ImportFailure struct {
+	Source string `json:"source"`
+	// Index is the position of the resource in the source, starting at 0
+	Index        int             `json:"index"`
+	ResourceType string          `json:"resourceType,omitempty"`
+	ID           string          `json:"id,omitempty"`
+	Status       string          `json:"status,omitempty"`
+	Error        string          `json:"error,omitempty"`
+	Outcome      json.RawMessage `json:"outcome,omitempty"`
+}
+
+// importCheckpoint is the content of the checkpoint file
+type importCheckpoint struct {
+	// Sources maps a source to the number of its resources which were processed
+	Sources map[string]int `json:"sources"`
+}
+
+// importAssignment is a line of the reference journal. It maps a reference in
+// the input, e.g. "Patient/123", to the reference of the imported resource
+type importAssignment struct {
+	Ref      string `json:"ref"`
+	Assigned string `json:"assigned"`
+}
+
+// importTarget tracks a resource which is being imported so later resources can wait for it
+type importTarget struct {
+	done chan struct{}
+}
+
+type importItem struct {
+	index    int
+	resource map[string]interface{}
+	ref      string
+	// target is closed when the item was submitted
+	target *importTarget
+}
+
+type importJob struct {
+	seq   int
+	end   int
+	items []importItem
+	deps  []*importTarget
+}
+
+// ImporterSTU3 imports STU3 resources from NDJSON or Bundle files into the CDR.
+// Resources are grouped in bundles which are submitted concurrently. References
+// between resources of the import, also across sources, are rewritten to the
+// IDs assigned by the CDR. A resource is only resolved when it occurs before
+// the resources referring to it. Batch bundles are processed without order, so
+// a resource referring to a resource of the current bundle starts a new bundle
+// which is submitted once the earlier one completed. With a CheckpointFile,
+// resources whose type and ID were imported before are skipped, so resuming
+// never creates a resource twice.
+type ImporterSTU3 struct {
+	client *Client
+	config ImportConfig
+
+	mu         sync.Mutex
+	checkpoint importCheckpoint
+	// targets holds the resources which are being imported
+	targets map[string]*importTarget
+	// assigned maps the references of imported resources to their references in the CDR
+	assigned map[string]string
+	reportMu sync.Mutex
+	// checkpointMu serializes writes to the checkpoint and the reference journal
+	checkpointMu sync.Mutex
+}
+
+// NewImporterSTU3 returns an importer which imports through client. The progress
+// of an earlier import is read from the CheckpointFile, if it exists
+func NewImporterSTU3(client *Client, config ImportConfig) (*ImporterSTU3, error) {
+	if client == nil {
+		return nil, ErrMissingClient
+	}
+	if config.BundleSize <= 0 {
+		config.BundleSize = DefaultImportBundleSize
+	}
+	if config.Workers <= 0 {
+		config.Workers = DefaultImportWorkers
+	}
+	im := &ImporterSTU3{
+		client: client,
+		config: config,
+		checkpoint: importCheckpoint{
+			Sources: make(map[string]int),
+		},
+		targets:  make(map[string]*importTarget),
+		assigned: make(map[string]string),
+	}
+	if config.CheckpointFile != "" {
+		data, err := ioutil.ReadFile(config.CheckpointFile)
+		switch {
+		case err == nil:
+			if err := json.Unmarshal(data, &im.checkpoint); err != nil {
+				return nil, fmt.Errorf("checkpoint %s: %w", config.CheckpointFile, err)
+			}
+		case !os.IsNotExist(err):
+			return nil, err
+		}
+		if im.checkpoint.Sources == nil {
+			im.checkpoint.Sources = make(map[string]int)
+		}
+		if err := im.readJournal(); err != nil {
+			return nil, err
+		}
+	}
+	return im, nil
+}
+
+// readJournal loads the references assigned by earlier imports. A partial last
+// line, left by an interrupted write, is ignored
+func (im *ImporterSTU3) readJournal() error {
+	f, err := os.Open(im.config.CheckpointFile + importJournalExt)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	dec := json.NewDecoder(f)
+	for {
+		var assignment importAssignment
+		if err := dec.Decode(&assignment); err != nil {
+			if err == io.EOF || err == io.ErrUnexpectedEOF {
+				return nil
+			}
+			return fmt.Errorf("checkpoint %s%s: %w", im.config.CheckpointFile, importJournalExt, err)
+		}
+		im.assigned[assignment.Ref] = assignment.Assigned
+	}
+}
+
+// ImportFile imports a file. Files with an .ndjson extension are read as NDJSON,
+// others as a FHIR Bundle. The path identifies the source in the checkpoint
+func (im *ImporterSTU3) ImportFile(ctx context.Context, path string) (*ImportStats, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	if strings.EqualFold(filepath.Ext(path), ".ndjson") {
+		return im.ImportNDJSON(ctx, path, f)
+	}
+	return im.ImportBundle(ctx, path, f)
+}
+
+// ImportNDJSON imports the resources in r, one per line. source identifies r in the checkpoint
+func (im *ImporterSTU3) ImportNDJSON(ctx context.Context, source string, r io.Reader) (*ImportStats, error) {
+	lines := bufio.NewReader(r)
+	var lineNo int
+	var readErr error
+	return im.run(ctx, source, func() (map[string]interface{}, error) {
+		line, err := nextLine(lines, &lineNo, &readErr)
+		if err != nil {
+			return nil, err
+		}
+		resource, err := decodeObject(bytes.NewReader(line))
+		if err != nil {
+			return nil, fmt.Errorf("%s line %d: %w", source, lineNo, err)
+		}
+		return resource, nil
+	})
+}
+
+// ImportBundle imports the resources of the entries of the Bundle in r. The
+// entries are decoded one at a time. source identifies r in the checkpoint
+func (im *ImporterSTU3) ImportBundle(ctx context.Context, source string, r io.Reader) (*ImportStats, error) {
+	dec := json.NewDecoder(r)
+	dec.UseNumber()
+	if err := expectDelim(dec, '{'); err != nil {
+		return nil, fmt.Errorf("%s: %w", source, err)
+	}
+	inEntries := false
+	return im.run(ctx, source, func() (map[string]interface{}, error) {
+		for {
+			if inEntries {
+				if dec.More() {
+					var entry struct {
+						Resource map[string]interface{} `json:"resource"`
+					}
+					if err := dec.Decode(&entry); err != nil {
+						return nil, fmt.Errorf("%s: %w", source, err)
+					}
+					if entry.Resource == nil {
+						continue
+					}
+					return entry.Resource, nil
+				}
+				if err := expectDelim(dec, ']'); err != nil {
+					return nil, fmt.Errorf("%s: %w", source, err)
+				}
+				inEntries = false
+			}
+			if !dec.More() {
+				return nil, io.EOF
+			}
+			key, err := dec.Token()
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", source, err)
+			}
+			if key == "entry" {
+				if err := expectDelim(dec, '['); err != nil {
+					return nil, fmt.Errorf("%s: %w", source, err)
+				}
+				inEntries = true
+				continue
+			}
+			var skip json.RawMessage
+			if err := dec.Decode(&skip); err != nil {
+				return nil, fmt.Errorf("%s: %w", source, err)
+			}
+		}
+	})
+}
+
+// run groups the resources returned by next in bundles and submits them with
+// the configured concurrency
+func (im *ImporterSTU3) run(ctx context.Context, source string, next func() (map[string]interface{}, error)) (*ImportStats, error) {
+	stats := &ImportStats{}
+	im.mu.Lock()
+	processed := im.checkpoint.Sources[source]
+	im.mu.Unlock()
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	jobs := make(chan importJob, im.config.Workers)
+	var workers sync.WaitGroup
+	var errMu sync.Mutex
+	var firstErr error
+	setErr := func(err error) {
+		errMu.Lock()
+		if firstErr == nil {
+			firstErr = err
+			cancel()
+		}
+		errMu.Unlock()
+	}
+	progress := newImportProgress(processed)
+	for i := 0; i < im.config.Workers; i++ {
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			for job := range jobs {
+				if err := im.submit(ctx, source, job, stats); err != nil {
+					setErr(err)
+					continue
+				}
+				if end, ok := progress.complete(job.seq, job.end); ok {
+					if err := im.saveCheckpoint(source, end); err != nil {
+						setErr(err)
+					}
+				}
+			}
+		}()
+	}
+
+	job := importJob{}
+	index := 0
+	for ctx.Err() == nil {
+		resource, err := next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			setErr(err)
+			break
+		}
+		atomic.AddInt64(&stats.Read, 1)
+		if index < processed || im.imported(resource) {
+			atomic.AddInt64(&stats.Skipped, 1)
+			index++
+			continue
+		}
+		if im.refersToJob(resource, &job) {
+			job.end = index
+			jobs <- job
+			job = importJob{seq: job.seq + 1}
+		}
+		job.items = append(job.items, im.prepare(index, resource, &job))
+		index++
+		if len(job.items) == im.config.BundleSize {
+			job.end = index
+			jobs <- job
+			job = importJob{seq: job.seq + 1}
+		}
+	}
+	if len(job.items) > 0 && ctx.Err() == nil {
+		job.end = index
+		jobs <- job
+	} else {
+		im.release(job)
+	}
+	close(jobs)
+	workers.Wait()
+	if firstErr != nil {
+		return stats, firstErr
+	}
+	return stats, ctx.Err()
+}
+
+// prepare registers the resource as a target and records the earlier targets it refers to
+func (im *ImporterSTU3) prepare(index int, resource map[string]interface{}, job *importJob) importItem {
+	item := importItem{index: index, resource: resource}
+	resourceType, _ := resource["resourceType"].(string)
+	id, _ := resource["id"].(string)
+	if resourceType != "" && id != "" {
+		item.ref = resourceType + "/" + id
+	}
+	if im.config.PreserveIDs {
+		return item
+	}
+	im.mu.Lock()
+	defer im.mu.Unlock()
+	for _, ref := range collectReferences(resource, nil) {
+		if target, ok := im.targets[ref]; ok && !job.contains(ref) {
+			job.deps = append(job.deps, target)
+		}
+	}
+	if item.ref != "" {
+		item.target = &importTarget{done: make(chan struct{})}
+		im.targets[item.ref] = item.target
+	}
+	return item
+}
+
+// imported reports whether the reference journal records resource as imported.
+// Bundles which completed after the last checkpoint are skipped this way on resume
+func (im *ImporterSTU3) imported(resource map[string]interface{}) bool {
+	if im.config.PreserveIDs {
+		return false
+	}
+	resourceType, _ := resource["resourceType"].(string)
+	id, _ := resource["id"].(string)
+	if resourceType == "" || id == "" {
+		return false
+	}
+	im.mu.Lock()
+	defer im.mu.Unlock()
+	_, ok := im.assigned[resourceType+"/"+id]
+	return ok
+}
+
+// refersToJob reports whether resource refers to a resource of job in batch mode.
+// Batch entries are not resolved against each other, so the resource has to wait
+// for the bundle of job instead
+func (im *ImporterSTU3) refersToJob(resource map[string]interface{}, job *importJob) bool {
+	if im.config.Transaction || im.config.PreserveIDs || len(job.items) == 0 {
+		return false
+	}
+	for _, ref := range collectReferences(resource, nil) {
+		if job.contains(ref) {
+			return true
+		}
+	}
+	return false
+}
+
+// release unblocks the jobs waiting for the items of job. The targets are dropped
+// as later resources resolve them from the assigned references
+func (im *ImporterSTU3) release(job importJob) {
+	im.mu.Lock()
+	defer im.mu.Unlock()
+	for _, item := range job.items {
+		if item.target == nil {
+			continue
+		}
+		if im.targets[item.ref] == item.target {
+			delete(im.targets, item.ref)
+		}
+		close(item.target.done)
+	}
+}
+
+func (j *importJob) contains(ref string) bool {
+	for _, item := range j.items {
+		if item.ref == ref {
+			return true
+		}
+	}
+	return false
+}
+
+// submit waits for the bundles the job depends on, then submits the job as a bundle
+func (im *ImporterSTU3) submit(ctx context.Context, source string, job importJob, stats *ImportStats) error {
+	defer im.release(job)
+	for _, dep := range job.deps {
+		select {
+		case <-dep.done:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	// Only transaction entries can refer to each other by their fullUrl
+	urns := make(map[string]string)
+	if im.config.Transaction && !im.config.PreserveIDs {
+		for _, item := range job.items {
+			if item.ref != "" {
+				urns[item.ref] = "urn:uuid:" + uuid.New().String()
+			}
+		}
+	}
+	bundleType := codes_go_proto.BundleTypeCode_BATCH
+	if im.config.Transaction {
+		bundleType = codes_go_proto.BundleTypeCode_TRANSACTION
+	}
+	bundle := &stu3pb.Bundle{Type: &codes_go_proto.BundleTypeCode{Value: bundleType}}
+	var submitted []int
+	for i, item := range job.items {
+		entry, err := im.entry(item, urns)
+		if err != nil {
+			im.fail(source, item, stats, "", err.Error(), nil)
+			continue
+		}
+		bundle.Entry = append(bundle.Entry, entry)
+		submitted = append(submitted, i)
+	}
+	if len(bundle.Entry) == 0 {
+		return nil
+	}
+
+	var result *BundleResultSTU3
+	var resp *Response
+	var err error
+	if im.config.Transaction {
+		result, resp, err = im.client.OperationsSTU3.Transaction(bundle)
+	} else {
+		result, resp, err = im.client.OperationsSTU3.Batch(bundle)
+	}
+	if err != nil {
+		// Only a rejected bundle fails its resources. Other errors stop the
+		// import so it can be resumed from the checkpoint
+		if resp == nil || resp.StatusCode < 400 || resp.StatusCode >= 500 {
+			return err
+		}
+		var outcome *stu3pb.OperationOutcome
+		var outcomeErr *OutcomeErrorSTU3
+		if errors.As(err, &outcomeErr) {
+			outcome = outcomeErr.Outcome
+		}
+		for _, i := range submitted {
+			im.fail(source, job.items[i], stats, "", err.Error(), outcome)
+		}
+		return nil
+	}
+	var assignments []importAssignment
+	for n, i := range submitted {
+		entry := result.Entries[n]
+		if entry.StatusCode < 200 || entry.StatusCode > 299 {
+			im.fail(source, job.items[i], stats, entry.Status, "", entry.Outcome)
+			continue
+		}
+		atomic.AddInt64(&stats.Imported, 1)
+		if job.items[i].target == nil {
+			continue
+		}
+		if assigned := assignedReference(entry); assigned != "" {
+			assignments = append(assignments, importAssignment{Ref: job.items[i].ref, Assigned: assigned})
+		}
+	}
+	im.mu.Lock()
+	for _, a := range assignments {
+		im.assigned[a.Ref] = a.Assigned
+	}
+	im.mu.Unlock()
+	return im.appendJournal(assignments)
+}
+
+// entry returns the bundle entry of item with its references rewritten
+func (im *ImporterSTU3) entry(item importItem, urns map[string]string) (*stu3pb.Bundle_Entry, error) {
+	resource := item.resource
+	resourceType, _ := resource["resourceType"].(string)
+	if resourceType == "" {
+		return nil, ErrUnsupportedResource
+	}
+	request := &stu3pb.Bundle_Entry_Request{
+		Method: &codes_go_proto.HTTPVerbCode{Value: codes_go_proto.HTTPVerbCode_POST},
+		Url:    &stu3dt.Uri{Value: resourceType},
+	}
+	fullURL := "urn:uuid:" + uuid.New().String()
+	if im.config.PreserveIDs {
+		if item.ref == "" {
+			return nil, ErrMissingResourceID
+		}
+		request.Method.Value = codes_go_proto.HTTPVerbCode_PUT
+		request.Url.Value = item.ref
+		fullURL = strings.TrimSuffix(im.client.GetEndpointURL(), "/") + "/" + item.ref
+	} else {
+		if urn, ok := urns[item.ref]; ok {
+			fullURL = urn
+		}
+		delete(resource, "id")
+		im.mu.Lock()
+		rewriteReferences(resource, func(ref string) string {
+			if urn, ok := urns[ref]; ok {
+				return urn
+			}
+			if assigned, ok := im.assigned[ref]; ok {
+				return assigned
+			}
+			return ref
+		})
+		im.mu.Unlock()
+	}
+	data, err := json.Marshal(resource)
+	if err != nil {
+		return nil, err
+	}
+	unmarshalled, err := im.client.OperationsSTU3.um.Unmarshal(data)
+	if err != nil {
+		return nil, fmt.Errorf("FHIR unmarshal: %w", err)
+	}
+	return &stu3pb.Bundle_Entry{
+		FullUrl:  &stu3dt.Uri{Value: fullURL},
+		Resource: unmarshalled.(*stu3pb.ContainedResource),
+		Request:  request,
+	}, nil
+}
+
+// fail counts item as failed and adds it to the report
+func (im *ImporterSTU3) fail(source string, item importItem, stats *ImportStats, status, message string, outcome *stu3pb.OperationOutcome) {
+	atomic.AddInt64(&stats.Failed, 1)
+	if im.config.Report == nil {
+		return
+	}
+	failure := ImportFailure{Source: source, Index: item.index, Status: status, Error: message}
+	if item.ref != "" {
+		parts := strings.SplitN(item.ref, "/", 2)
+		failure.ResourceType, failure.ID = parts[0], parts[1]
+	} else {
+		failure.ResourceType, _ = item.resource["resourceType"].(string)
+	}
+	if outcome != nil {
+		if data, err := im.client.OperationsSTU3.ma.MarshalResource(outcome); err == nil {
+			failure.Outcome = data
+		}
+	}
+	line, err := json.Marshal(failure)
+	if err != nil {
+		return
+	}
+	im.reportMu.Lock()
+	defer im.reportMu.Unlock()
+	_, _ = im.config.Report.Write(append(line, '\n'))
+}
+
+// appendJournal records the references assigned to imported resources. They are
+// written before the checkpoint advances past the resources
+func (im *ImporterSTU3) appendJournal(assignments []importAssignment) error {
+	if im.config.CheckpointFile == "" || len(assignments) == 0 {
+		return nil
+	}
+	var lines bytes.Buffer
+	enc := json.NewEncoder(&lines)
+	for _, a := range assignments {
+		if err := enc.Encode(a); err != nil {
+			return err
+		}
+	}
+	im.checkpointMu.Lock()
+	defer im.checkpointMu.Unlock()
+	f, err := os.OpenFile(im.config.CheckpointFile+importJournalExt, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(lines.Bytes()); err != nil {
+		_ = f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// saveCheckpoint records that the first processed resources of source were imported
+func (im *ImporterSTU3) saveCheckpoint(source string, processed int) error {
+	if im.config.CheckpointFile == "" {
+		return nil
+	}
+	// Concurrent saves must not replace a newer checkpoint with an older one
+	im.checkpointMu.Lock()
+	defer im.checkpointMu.Unlock()
+	im.mu.Lock()
+	im.checkpoint.Sources[source] = processed
+	data, err := json.Marshal(im.checkpoint)
+	im.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	// Replace the checkpoint atomically so a crash never leaves a partial file
+	tmp, err := ioutil.TempFile(filepath.Dir(im.config.CheckpointFile), filepath.Base(im.config.CheckpointFile)+".tmp-")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		_ = tmp.Close()
+		_ = os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		_ = os.Remove(tmp.Name())
+		return err
+	}
+	if err := os.Rename(tmp.Name(), im.config.CheckpointFile); err != nil {
+		_ = os.Remove(tmp.Name())
+		return err
+	}
+	return nil
+}
+
+// importProgress tracks the contiguous prefix of completed bundles
+type importProgress struct {
+	mu        sync.Mutex
+	next      int
+	completed map[int]int
+	processed int
+}
+
+func newImportProgress(processed int) *importProgress {
+	return &importProgress{completed: make(map[int]int), processed: processed}
+}
+
+// complete marks bundle seq ending at resource end as done. It returns the
+// number of processed resources if it advanced
+func (p *importProgress) complete(seq, end int) (int, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.completed[seq] = end
+	advanced := false
+	for {
+		end, ok := p.completed[p.next]
+		if !ok {
+			break
+		}
+		delete(p.completed, p.next)
+		p.next++
+		p.processed = end
+		advanced = true
+	}
+	return p.processed, advanced
+}
+
+// assignedReference returns the reference of an imported resource, e.g. "Patient/456"
+func assignedReference(entry BundleEntryResultSTU3) string {
+	if location := entry.Location; location != "" {
+		location = strings.Split(location, "/_history/")[0]
+		parts := strings.Split(strings.TrimSuffix(location, "/"), "/")
+		if len(parts) >= 2 {
+			return parts[len(parts)-2] + "/" + parts[len(parts)-1]
+		}
+	}
+	if entry.Resource != nil {
+		if id := resourceIDOf(entry.Resource); id != "" {
+			return resourceTypeOf(entry.Resource) + "/" + id
+		}
+	}
+	return ""
+}
+
+// collectReferences returns the values of the reference elements in v
+func collectReferences(v interface{}, refs []string) []string {
+	switch value := v.(type) {
+	case map[string]interface{}:
+		for k, child := range value {
+			if ref, ok := child.(string); ok && k == "reference" {
+				refs = append(refs, ref)
+				continue
+			}
+			refs = collectReferences(child, refs)
+		}
+	case []interface{}:
+		for _, child := range value {
+			refs = collectReferences(child, refs)
+		}
+	}
+	return refs
+}
+
+// rewriteReferences replaces the values of the reference elements in v
+func rewriteReferences(v interface{}, rewrite func(string) string) {
+	switch value := v.(type) {
+	case map[string]interface{}:
+		for k, child := range value {
+			if ref, ok := child.(string); ok && k == "reference" {
+				value[k] = rewrite(ref)
+				continue
+			}
+			rewriteReferences(child, rewrite)
+		}
+	case []interface{}:
+		for _, child := range value {
+			rewriteReferences(child, rewrite)
+		}
+	}
+}
+
+func decodeObject(r io.Reader) (map[string]interface{}, error) {
+	dec := json.NewDecoder(r)
+	// Numbers are kept as they are so decimals keep their precision
+	dec.UseNumber()
+	var object map[string]interface{}
+	if err := dec.Decode(&object); err != nil {
+		return nil, err
+	}
+	return object, nil
+}
+
+func expectDelim(dec *json.Decoder, delim json.Delim) error {
+	token, err := dec.Token()
+	if err != nil {
+		return err
+	}
+	if token != delim {
+		return fmt.Errorf("expected %v, got %v", delim, token)
+	}
+	return nil
+}
@@ -0,0 +1,431 @@
+package cdr_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/philips-software/go-hsdp-api/cdr"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeStore is a FHIR store which handles batch and transaction bundles
+type fakeStore struct {
+	mu        sync.Mutex
+	next      int
+	resources map[string]map[string]interface{}
+	bundles   int
+	// failAfter makes the store unavailable after this number of bundles when positive
+	failAfter int
+	// rejectTransactions fails all transaction bundles
+	rejectTransactions bool
+}
+
+func newFakeStore() *fakeStore {
+	return &fakeStore{resources: make(map[string]map[string]interface{})}
+}
+
+func (s *fakeStore) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	w.Header().Set("Content-Type", "application/fhir+json")
+	s.bundles++
+	if s.failAfter > 0 && s.bundles > s.failAfter {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		return
+	}
+	var bundle struct {
+		Type  string `json:"type"`
+		Entry []struct {
+			FullURL  string                 `json:"fullUrl"`
+			Resource map[string]interface{} `json:"resource"`
+		} `json:"entry"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&bundle); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	if bundle.Type == "transaction" && s.rejectTransactions {
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = io.WriteString(w, `{"resourceType":"OperationOutcome","issue":[{"severity":"error","code":"invalid","diagnostics":"transaction rejected"}]}`)
+		return
+	}
+	urns := make(map[string]string)
+	for _, e := range bundle.Entry {
+		s.next++
+		urns[e.FullURL] = fmt.Sprintf("%s/srv-%d", e.Resource["resourceType"], s.next)
+	}
+	var entries []string
+	for _, e := range bundle.Entry {
+		if strings.Contains(fmt.Sprint(e.Resource["identifier"]), "reject") {
+			entries = append(entries, `{"response":{"status":"400 Bad Request","outcome":{"resourceType":"OperationOutcome",`+
+				`"issue":[{"severity":"error","code":"invalid","diagnostics":"rejected"}]}}}`)
+			continue
+		}
+		// Only transaction entries can refer to each other
+		if subject, ok := e.Resource["subject"].(map[string]interface{}); ok && bundle.Type == "transaction" {
+			if ref, ok := urns[subject["reference"].(string)]; ok {
+				subject["reference"] = ref
+			}
+		}
+		s.resources[urns[e.FullURL]] = e.Resource
+		entries = append(entries, `{"response":{"status":"201 Created","location":"`+urns[e.FullURL]+`/_history/1"}}`)
+	}
+	w.WriteHeader(http.StatusOK)
+	_, _ = io.WriteString(w, `{"resourceType":"Bundle","type":"batch-response","entry":[`+strings.Join(entries, ",")+`]}`)
+}
+
+// subjectIdentifier returns the identifier of the patient the observation refers to
+func (s *fakeStore) subjectIdentifier(observation map[string]interface{}) string {
+	ref := observation["subject"].(map[string]interface{})["reference"].(string)
+	patient, ok := s.resources[ref]
+	if !ok {
+		return "unresolved " + ref
+	}
+	return patient["identifier"].([]interface{})[0].(map[string]interface{})["value"].(string)
+}
+
+func (s *fakeStore) observations() []map[string]interface{} {
+	var observations []map[string]interface{}
+	for _, r := range s.resources {
+		if r["resourceType"] == "Observation" {
+			observations = append(observations, r)
+		}
+	}
+	return observations
+}
+
+func patientLine(id, identifier string) string {
+	return `{"resourceType":"Patient","id":"` + id + `","identifier":[{"system":"urn:legacy","value":"` + identifier + `"}]}`
+}
+
+func observationLine(id, patientID string) string {
+	return `{"resourceType":"Observation","id":"` + id + `","status":"final","code":{"text":"` + patientID + `"},` +
+		`"subject":{"reference":"Patient/` + patientID + `"},"valueQuantity":{"value":72.50,"unit":"kg"}}`
+}
+
+func TestImporterSTU3(t *testing.T) {
+	teardown := setup(t)
+	defer teardown()
+
+	store := newFakeStore()
+	muxCDR.Handle("/store/fhir/"+cdrOrgID, store)
+
+	var lines []string
+	for i := 0; i < 6; i++ {
+		lines = append(lines, patientLine(fmt.Sprintf("p%d", i), fmt.Sprintf("legacy-%d", i)))
+		lines = append(lines, observationLine(fmt.Sprintf("o%d", i), fmt.Sprintf("p%d", i)))
+	}
+	lines = append(lines, patientLine("p6", "reject"))
+	lines = append(lines, `{"resourceType":"Patient","id":"p7","gender":"invalid"}`)
+
+	var report bytes.Buffer
+	importer, err := cdr.NewImporterSTU3(cdrClient, cdr.ImportConfig{BundleSize: 3, Workers: 3, Report: &report})
+	if !assert.Nil(t, err) {
+		return
+	}
+	stats, err := importer.ImportNDJSON(context.Background(), "legacy.ndjson", strings.NewReader(strings.Join(lines, "\n")))
+	if !assert.Nil(t, err) || !assert.NotNil(t, stats) {
+		return
+	}
+	assert.Equal(t, int64(14), stats.Read)
+	assert.Equal(t, int64(12), stats.Imported)
+	assert.Equal(t, int64(2), stats.Failed)
+
+	observations := store.observations()
+	if assert.Len(t, observations, 6) {
+		for _, o := range observations {
+			patientID := o["code"].(map[string]interface{})["text"].(string)
+			assert.Equal(t, "legacy-"+strings.TrimPrefix(patientID, "p"), store.subjectIdentifier(o))
+			assert.Equal(t, 72.5, o["valueQuantity"].(map[string]interface{})["value"])
+		}
+	}
+
+	var failures []cdr.ImportFailure
+	dec := json.NewDecoder(&report)
+	for dec.More() {
+		var f cdr.ImportFailure
+		if !assert.Nil(t, dec.Decode(&f)) {
+			return
+		}
+		failures = append(failures, f)
+	}
+	if assert.Len(t, failures, 2) {
+		byID := map[string]cdr.ImportFailure{failures[0].ID: failures[0], failures[1].ID: failures[1]}
+		assert.Equal(t, 12, byID["p6"].Index)
+		assert.Equal(t, "400 Bad Request", byID["p6"].Status)
+		assert.Contains(t, string(byID["p6"].Outcome), "rejected")
+		assert.Equal(t, 13, byID["p7"].Index)
+		assert.Contains(t, byID["p7"].Error, "FHIR unmarshal")
+	}
+
+	_, err = cdr.NewImporterSTU3(nil, cdr.ImportConfig{})
+	assert.Equal(t, cdr.ErrMissingClient, err)
+}
+
+func TestImporterSTU3Resume(t *testing.T) {
+	teardown := setup(t)
+	defer teardown()
+
+	store := newFakeStore()
+	store.failAfter = 2
+	muxCDR.Handle("/store/fhir/"+cdrOrgID, store)
+
+	dir, err := ioutil.TempDir("", "import")
+	if !assert.Nil(t, err) {
+		return
+	}
+	defer os.RemoveAll(dir)
+	checkpoint := filepath.Join(dir, "checkpoint.json")
+	patients := filepath.Join(dir, "patients.ndjson")
+	observations := filepath.Join(dir, "observations.ndjson")
+	var patientLines, observationLines []string
+	for i := 0; i < 6; i++ {
+		patientLines = append(patientLines, patientLine(fmt.Sprintf("p%d", i), fmt.Sprintf("legacy-%d", i)))
+		observationLines = append(observationLines, observationLine(fmt.Sprintf("o%d", i), fmt.Sprintf("p%d", i)))
+	}
+	_ = ioutil.WriteFile(patients, []byte(strings.Join(patientLines, "\n")), 0600)
+	_ = ioutil.WriteFile(observations, []byte(strings.Join(observationLines, "\n")), 0600)
+
+	config := cdr.ImportConfig{BundleSize: 2, Workers: 1, CheckpointFile: checkpoint}
+	importer, err := cdr.NewImporterSTU3(cdrClient, config)
+	if !assert.Nil(t, err) {
+		return
+	}
+	_, err = importer.ImportFile(context.Background(), patients)
+	assert.True(t, errors.Is(err, cdr.ErrNonHttp20xResponse))
+	assert.Len(t, store.resources, 4)
+
+	// A new importer continues after the imported resources and still resolves references to them
+	store.failAfter = 0
+	importer, err = cdr.NewImporterSTU3(cdrClient, config)
+	if !assert.Nil(t, err) {
+		return
+	}
+	stats, err := importer.ImportFile(context.Background(), patients)
+	if !assert.Nil(t, err) {
+		return
+	}
+	assert.Equal(t, int64(4), stats.Skipped)
+	assert.Equal(t, int64(2), stats.Imported)
+	stats, err = importer.ImportFile(context.Background(), observations)
+	if !assert.Nil(t, err) {
+		return
+	}
+	assert.Equal(t, int64(6), stats.Imported)
+	for _, o := range store.observations() {
+		patientID := o["code"].(map[string]interface{})["text"].(string)
+		assert.Equal(t, "legacy-"+strings.TrimPrefix(patientID, "p"), store.subjectIdentifier(o))
+	}
+	// Assigned references are journaled, the checkpoint only holds the progress
+	journal, _ := ioutil.ReadFile(checkpoint + ".references")
+	assert.Len(t, strings.Split(strings.TrimSpace(string(journal)), "\n"), 12)
+	data, _ := ioutil.ReadFile(checkpoint)
+	assert.NotContains(t, string(data), "srv-")
+
+	// Everything was imported so nothing is submitted again
+	importer, err = cdr.NewImporterSTU3(cdrClient, config)
+	if !assert.Nil(t, err) {
+		return
+	}
+	stats, err = importer.ImportFile(context.Background(), observations)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(6), stats.Skipped)
+	assert.Equal(t, int64(0), stats.Imported)
+}
+
+func TestImporterSTU3Bundle(t *testing.T) {
+	teardown := setup(t)
+	defer teardown()
+
+	store := newFakeStore()
+	store.rejectTransactions = true
+	muxCDR.Handle("/store/fhir/"+cdrOrgID, store)
+
+	dir, err := ioutil.TempDir("", "import")
+	if !assert.Nil(t, err) {
+		return
+	}
+	defer os.RemoveAll(dir)
+	bundleFile := filepath.Join(dir, "bundle.json")
+	_ = ioutil.WriteFile(bundleFile, []byte(`{"resourceType":"Bundle","type":"collection","entry":[`+
+		`{"fullUrl":"Patient/p1","resource":`+patientLine("p1", "legacy-1")+`},`+
+		`{"fullUrl":"Observation/o1","resource":`+observationLine("o1", "p1")+`}],"total":2}`), 0600)
+
+	var report bytes.Buffer
+	importer, err := cdr.NewImporterSTU3(cdrClient, cdr.ImportConfig{Transaction: true, Report: &report})
+	if !assert.Nil(t, err) {
+		return
+	}
+	stats, err := importer.ImportFile(context.Background(), bundleFile)
+	if !assert.Nil(t, err) {
+		return
+	}
+	assert.Equal(t, int64(2), stats.Read)
+	assert.Equal(t, int64(2), stats.Failed)
+	reportLines := strings.Split(strings.TrimSpace(report.String()), "\n")
+	if assert.Len(t, reportLines, 2) {
+		assert.Contains(t, reportLines[0], "transaction rejected")
+		assert.Contains(t, reportLines[0], `"source":"`+bundleFile+`"`)
+	}
+}
+
+func TestImporterSTU3Transaction(t *testing.T) {
+	teardown := setup(t)
+	defer teardown()
+
+	store := newFakeStore()
+	muxCDR.Handle("/store/fhir/"+cdrOrgID, store)
+
+	lines := []string{
+		patientLine("p1", "legacy-1"), observationLine("o1", "p1"),
+		patientLine("p2", "legacy-2"), observationLine("o2", "p2"),
+	}
+	importer, err := cdr.NewImporterSTU3(cdrClient, cdr.ImportConfig{BundleSize: 4, Transaction: true})
+	if !assert.Nil(t, err) {
+		return
+	}
+	stats, err := importer.ImportNDJSON(context.Background(), "legacy.ndjson", strings.NewReader(strings.Join(lines, "\n")))
+	if !assert.Nil(t, err) {
+		return
+	}
+	assert.Equal(t, int64(4), stats.Imported)
+	// Transaction entries refer to each other, so everything fits in one bundle
+	assert.Equal(t, 1, store.bundles)
+	for _, o := range store.observations() {
+		patientID := o["code"].(map[string]interface{})["text"].(string)
+		assert.Equal(t, "legacy-"+strings.TrimPrefix(patientID, "p"), store.subjectIdentifier(o))
+	}
+}
+
+func TestImporterSTU3BatchDependencies(t *testing.T) {
+	teardown := setup(t)
+	defer teardown()
+
+	store := newFakeStore()
+	muxCDR.Handle("/store/fhir/"+cdrOrgID, store)
+
+	lines := []string{
+		patientLine("p1", "legacy-1"), patientLine("p2", "legacy-2"),
+		observationLine("o1", "p1"), observationLine("o2", "p2"),
+	}
+	importer, err := cdr.NewImporterSTU3(cdrClient, cdr.ImportConfig{BundleSize: 4, Workers: 2})
+	if !assert.Nil(t, err) {
+		return
+	}
+	stats, err := importer.ImportNDJSON(context.Background(), "legacy.ndjson", strings.NewReader(strings.Join(lines, "\n")))
+	if !assert.Nil(t, err) {
+		return
+	}
+	assert.Equal(t, int64(4), stats.Imported)
+	// The observations wait for the bundle with their patients
+	assert.Equal(t, 2, store.bundles)
+	for _, o := range store.observations() {
+		patientID := o["code"].(map[string]interface{})["text"].(string)
+		assert.Equal(t, "legacy-"+strings.TrimPrefix(patientID, "p"), store.subjectIdentifier(o))
+	}
+}
+
+func TestImporterSTU3ResumeAfterCrash(t *testing.T) {
+	teardown := setup(t)
+	defer teardown()
+
+	store := newFakeStore()
+	failed := false
+	secondDone := make(chan struct{})
+	muxCDR.HandleFunc("/store/fhir/"+cdrOrgID, func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		r.Body = ioutil.NopCloser(bytes.NewReader(body))
+		if strings.Contains(string(body), "legacy-0") && !failed {
+			// The first bundle fails after the second one was imported
+			failed = true
+			<-secondDone
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		store.ServeHTTP(w, r)
+		if strings.Contains(string(body), "legacy-2") {
+			close(secondDone)
+		}
+	})
+
+	dir, err := ioutil.TempDir("", "import")
+	if !assert.Nil(t, err) {
+		return
+	}
+	defer os.RemoveAll(dir)
+	var lines []string
+	for i := 0; i < 4; i++ {
+		lines = append(lines, patientLine(fmt.Sprintf("p%d", i), fmt.Sprintf("legacy-%d", i)))
+	}
+	input := strings.Join(lines, "\n")
+	config := cdr.ImportConfig{BundleSize: 2, Workers: 2, CheckpointFile: filepath.Join(dir, "checkpoint.json")}
+
+	importer, err := cdr.NewImporterSTU3(cdrClient, config)
+	if !assert.Nil(t, err) {
+		return
+	}
+	_, err = importer.ImportNDJSON(context.Background(), "patients.ndjson", strings.NewReader(input))
+	assert.True(t, errors.Is(err, cdr.ErrNonHttp20xResponse))
+
+	// The checkpoint did not advance past the failed bundle, the journal skips the completed one
+	importer, err = cdr.NewImporterSTU3(cdrClient, config)
+	if !assert.Nil(t, err) {
+		return
+	}
+	stats, err := importer.ImportNDJSON(context.Background(), "patients.ndjson", strings.NewReader(input))
+	if !assert.Nil(t, err) {
+		return
+	}
+	assert.Equal(t, int64(2), stats.Skipped)
+	assert.Equal(t, int64(2), stats.Imported)
+	identifiers := make(map[string]int)
+	for _, r := range store.resources {
+		identifiers[r["identifier"].([]interface{})[0].(map[string]interface{})["value"].(string)]++
+	}
+	assert.Equal(t, map[string]int{"legacy-0": 1, "legacy-1": 1, "legacy-2": 1, "legacy-3": 1}, identifiers)
+}
+
+func TestImporterSTU3PreserveIDs(t *testing.T) {
+	teardown := setup(t)
+	defer teardown()
+
+	var fullURLs []string
+	muxCDR.HandleFunc("/store/fhir/"+cdrOrgID, func(w http.ResponseWriter, r *http.Request) {
+		var bundle struct {
+			Entry []struct {
+				FullURL string `json:"fullUrl"`
+			} `json:"entry"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&bundle)
+		for _, e := range bundle.Entry {
+			fullURLs = append(fullURLs, e.FullURL)
+		}
+		w.Header().Set("Content-Type", "application/fhir+json")
+		w.WriteHeader(http.StatusOK)
+		_, _ = io.WriteString(w, `{"resourceType":"Bundle","type":"batch-response","entry":[`+
+			`{"response":{"status":"200 OK","location":"Patient/p1/_history/2"}}]}`)
+	})
+
+	importer, err := cdr.NewImporterSTU3(cdrClient, cdr.ImportConfig{PreserveIDs: true})
+	if !assert.Nil(t, err) {
+		return
+	}
+	stats, err := importer.ImportNDJSON(context.Background(), "patients.ndjson", strings.NewReader(patientLine("p1", "legacy-1")))
+	if !assert.Nil(t, err) {
+		return
+	}
+	assert.Equal(t, int64(1), stats.Imported)
+	// The fullUrl of an entry must be absolute
+	assert.Equal(t, []string{cdrClient.GetEndpointURL() + "/Patient/p1"}, fullURLs)
+}
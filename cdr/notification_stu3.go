@@ -0,0 +1,180 @@
+package cdr
+
+import (
+	"crypto/subtle"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/google/fhir/go/jsonformat"
+	stu3pb "github.com/google/fhir/go/proto/google/fhir/proto/stu3/resources_go_proto"
+)
+
+// DefaultNotificationMaxBodySize is the default maximum size of a notification body
+const DefaultNotificationMaxBodySize = 10 * 1024 * 1024
+
+// NotificationSTU3 is a REST-hook notification sent by the CDR for a subscription
+type NotificationSTU3 struct {
+	// ResourceType and ID identify the resource the notification is about. They
+	// are empty for notifications without payload
+	ResourceType string
+	ID           string
+	// Resource is the changed resource. It is nil for deletions and
+	// notifications without payload
+	Resource *stu3pb.ContainedResource
+	// Request is the incoming notification request
+	Request *http.Request
+}
+
+// NotificationHandlerSTU3 receives REST-hook notifications of STU3 subscriptions.
+// The CDR sends a resource to [endpoint]/[type]/[id] when it is created or updated,
+// an empty request to [endpoint] when the subscription has no payload and, when
+// the subscription has a delete endpoint (see stu3.WithDeleteEndpoint), a request
+// to [delete endpoint]/[type]/[id] when a resource is deleted. A handler created
+// as a struct literal reads resources in UTC
+type NotificationHandlerSTU3 struct {
+	// Headers must all be present with these values. Configure them on the
+	// subscription channel with stu3.WithHeaders, e.g. "Authorization: Bearer secret".
+	// Notifications are rejected while no headers are configured, as anyone who can
+	// reach the endpoint could send them otherwise
+	Headers map[string]string
+	// MaxBodySize limits the size of a notification body. Larger notifications are
+	// answered with a 413. Defaults to DefaultNotificationMaxBodySize
+	MaxBodySize int64
+	// DeletePath is the path of the delete endpoint. Requests below it are
+	// dispatched to OnDelete. DELETE requests are always dispatched to OnDelete
+	DeletePath string
+
+	// OnResource is called when a resource was created or updated
+	OnResource func(n NotificationSTU3) error
+	// OnDelete is called when a resource was deleted
+	OnDelete func(n NotificationSTU3) error
+	// OnPing is called for notifications without payload
+	OnPing func(n NotificationSTU3) error
+
+	um     *jsonformat.Unmarshaller
+	umOnce sync.Once
+	umErr  error
+}
+
+// NewNotificationHandlerSTU3 returns a handler for notifications. Set the callbacks
+// of the events to receive. The CDR is answered with a 500 when a callback fails so it retries
+func NewNotificationHandlerSTU3(timeZone string) (*NotificationHandlerSTU3, error) {
+	um, err := jsonformat.NewUnmarshaller(timeZone, jsonformat.STU3)
+	if err != nil {
+		return nil, fmt.Errorf("cdr.NewNotificationHandlerSTU3 create FHIR STU3 unmarshaller (timezone=[%s]): %w", timeZone, err)
+	}
+	return &NotificationHandlerSTU3{
+		Headers:     make(map[string]string),
+		MaxBodySize: DefaultNotificationMaxBodySize,
+		um:          um,
+	}, nil
+}
+
+// ServeHTTP verifies and dispatches a notification
+func (h *NotificationHandlerSTU3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !h.verify(r) {
+		http.Error(w, ErrInvalidNotification.Error(), http.StatusUnauthorized)
+		return
+	}
+	n := NotificationSTU3{Request: r}
+	n.ResourceType, n.ID = notificationTarget(r.URL.Path)
+
+	var callback func(n NotificationSTU3) error
+	switch {
+	case r.Method == http.MethodDelete || (h.DeletePath != "" && strings.HasPrefix(r.URL.Path, h.DeletePath)):
+		if n.ResourceType == "" || n.ID == "" {
+			http.Error(w, ErrInvalidNotification.Error(), http.StatusBadRequest)
+			return
+		}
+		callback = h.OnDelete
+	case r.Method == http.MethodPost || r.Method == http.MethodPut:
+		maxBodySize := h.MaxBodySize
+		if maxBodySize <= 0 {
+			maxBodySize = DefaultNotificationMaxBodySize
+		}
+		body, err := ioutil.ReadAll(http.MaxBytesReader(w, r.Body, maxBodySize))
+		if err != nil {
+			status := http.StatusBadRequest
+			if strings.Contains(err.Error(), "request body too large") {
+				status = http.StatusRequestEntityTooLarge
+			}
+			http.Error(w, err.Error(), status)
+			return
+		}
+		if len(body) == 0 {
+			callback = h.OnPing
+			break
+		}
+		um, err := h.unmarshaller()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		unmarshalled, err := um.Unmarshal(body)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("FHIR unmarshal: %v", err), http.StatusBadRequest)
+			return
+		}
+		n.Resource = unmarshalled.(*stu3pb.ContainedResource)
+		if resourceType := resourceTypeOf(n.Resource); resourceType != "" {
+			n.ResourceType = resourceType
+		}
+		if id := resourceIDOf(n.Resource); id != "" {
+			n.ID = id
+		}
+		callback = h.OnResource
+	default:
+		w.Header().Set("Allow", "POST, PUT, DELETE")
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+	if callback != nil {
+		if err := callback(n); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+// unmarshaller returns the unmarshaller of the handler. Handlers not created
+// with NewNotificationHandlerSTU3 get a UTC one on first use
+func (h *NotificationHandlerSTU3) unmarshaller() (*jsonformat.Unmarshaller, error) {
+	h.umOnce.Do(func() {
+		if h.um != nil {
+			return
+		}
+		h.um, h.umErr = jsonformat.NewUnmarshaller("UTC", jsonformat.STU3)
+	})
+	return h.um, h.umErr
+}
+
+// verify checks the configured headers in constant time
+func (h *NotificationHandlerSTU3) verify(r *http.Request) bool {
+	if len(h.Headers) == 0 {
+		return false
+	}
+	valid := true
+	for name, value := range h.Headers {
+		if subtle.ConstantTimeCompare([]byte(r.Header.Get(name)), []byte(value)) != 1 {
+			valid = false
+		}
+	}
+	return valid
+}
+
+// notificationTarget returns the resource type and ID at the end of path, if any
+func notificationTarget(path string) (string, string) {
+	segments := strings.Split(strings.Trim(path, "/"), "/")
+	if len(segments) < 2 {
+		return "", ""
+	}
+	resourceType, id := segments[len(segments)-2], segments[len(segments)-1]
+	if resourceType == "" || id == "" || resourceType[0] < 'A' || resourceType[0] > 'Z' {
+		return "", ""
+	}
+	return resourceType, id
+}
@@ -0,0 +1,99 @@
+package cdr_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/philips-software/go-hsdp-api/cdr"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNotificationHandlerSTU3(t *testing.T) {
+	handler, err := cdr.NewNotificationHandlerSTU3("Europe/Amsterdam")
+	if !assert.Nil(t, err) {
+		return
+	}
+	handler.Headers["Authorization"] = "Bearer secret"
+	handler.DeletePath = "/deleted"
+
+	var resources, deletes, pings []cdr.NotificationSTU3
+	handler.OnResource = func(n cdr.NotificationSTU3) error {
+		if n.ID == "fail" {
+			return errors.New("processing failed")
+		}
+		resources = append(resources, n)
+		return nil
+	}
+	handler.OnDelete = func(n cdr.NotificationSTU3) error {
+		deletes = append(deletes, n)
+		return nil
+	}
+	handler.OnPing = func(n cdr.NotificationSTU3) error {
+		pings = append(pings, n)
+		return nil
+	}
+
+	notify := func(method, path, body, token string) int {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	assert.Equal(t, http.StatusOK, notify("PUT", "/notify/Patient/123", `{"resourceType":"Patient","id":"123"}`, "secret"))
+	if assert.Len(t, resources, 1) {
+		assert.Equal(t, "Patient", resources[0].ResourceType)
+		assert.Equal(t, "123", resources[0].ID)
+		assert.Equal(t, "123", resources[0].Resource.GetPatient().GetId().GetValue())
+	}
+	assert.Equal(t, http.StatusOK, notify("POST", "/notify", "", "secret"))
+	assert.Len(t, pings, 1)
+	assert.Equal(t, http.StatusOK, notify("POST", "/deleted/Patient/123", "", "secret"))
+	assert.Equal(t, http.StatusOK, notify("DELETE", "/notify/Patient/456", "", "secret"))
+	if assert.Len(t, deletes, 2) {
+		assert.Equal(t, "123", deletes[0].ID)
+		assert.Equal(t, "456", deletes[1].ID)
+		assert.Nil(t, deletes[1].Resource)
+	}
+
+	assert.Equal(t, http.StatusUnauthorized, notify("PUT", "/notify/Patient/123", `{"resourceType":"Patient","id":"123"}`, "wrong"))
+	assert.Equal(t, http.StatusUnauthorized, notify("PUT", "/notify/Patient/123", `{"resourceType":"Patient","id":"123"}`, ""))
+	assert.Equal(t, http.StatusBadRequest, notify("PUT", "/notify/Patient/123", `{"resourceType":"Bogus"}`, "secret"))
+	assert.Equal(t, http.StatusBadRequest, notify("DELETE", "/notify", "", "secret"))
+	assert.Equal(t, http.StatusMethodNotAllowed, notify("GET", "/notify", "", "secret"))
+	assert.Equal(t, http.StatusInternalServerError, notify("PUT", "/notify/Patient/fail", `{"resourceType":"Patient","id":"fail"}`, "secret"))
+	assert.Len(t, resources, 1)
+
+	handler.MaxBodySize = 16
+	assert.Equal(t, http.StatusRequestEntityTooLarge, notify("PUT", "/notify/Patient/123", `{"resourceType":"Patient","id":"123"}`, "secret"))
+
+	// Without headers every notification is rejected
+	handler.Headers = nil
+	assert.Equal(t, http.StatusUnauthorized, notify("POST", "/notify", "", ""))
+	assert.Len(t, pings, 1)
+}
+
+func TestNotificationHandlerSTU3Literal(t *testing.T) {
+	var received []cdr.NotificationSTU3
+	handler := &cdr.NotificationHandlerSTU3{
+		Headers: map[string]string{"Authorization": "Bearer secret"},
+		OnResource: func(n cdr.NotificationSTU3) error {
+			received = append(received, n)
+			return nil
+		},
+	}
+	req := httptest.NewRequest("POST", "/notify/Patient/123", strings.NewReader(`{"resourceType":"Patient","id":"123"}`))
+	req.Header.Set("Authorization", "Bearer secret")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	if assert.Len(t, received, 1) {
+		assert.Equal(t, "123", received[0].Resource.GetPatient().GetId().GetValue())
+	}
+}
@@ -0,0 +1,114 @@
+package cdr_test
+
+import (
+	"io"
+	"io/ioutil"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const fhirJSONR4 = "application/fhir+json;fhirVersion=4.0"
+
+func r4Organization(orgID, name string) string {
+	return `{
+  "resourceType": "Organization",
+  "id": "` + orgID + `",
+  "meta": {
+    "versionId": "6dfa7cc8-2000-11ea-91df-bb500f85c5e2",
+    "lastUpdated": "2019-12-16T12:34:40.544022+00:00"
+  },
+  "identifier": [
+    {
+      "use": "usual",
+      "system": "https://identity.philips-healthsuite.com/organization",
+      "value": "` + orgID + `"
+    }
+  ],
+  "active": true,
+  "name": "` + name + `"
+}
+`
+}
+
+func TestR4Operations(t *testing.T) {
+	teardown := setup(t)
+	defer teardown()
+
+	orgID := "f5fe538f-c3b5-4454-8774-cd3789f59b9f"
+
+	muxCDR.HandleFunc("/store/fhir/"+cdrOrgID+"/Organization/"+orgID, func(w http.ResponseWriter, r *http.Request) {
+		if !assert.Equal(t, fhirJSONR4, r.Header.Get("Accept")) {
+			w.WriteHeader(http.StatusNotAcceptable)
+			return
+		}
+		w.Header().Set("Content-Type", fhirJSONR4)
+		switch r.Method {
+		case "GET":
+			w.WriteHeader(http.StatusOK)
+			_, _ = io.WriteString(w, r4Organization(orgID, "Hospital"))
+		case "POST", "PUT":
+			if !assert.Equal(t, fhirJSONR4, r.Header.Get("Content-Type")) {
+				w.WriteHeader(http.StatusUnsupportedMediaType)
+				return
+			}
+			body, _ := ioutil.ReadAll(r.Body)
+			w.WriteHeader(http.StatusCreated)
+			_, _ = w.Write(body)
+		case "PATCH":
+			if !assert.Equal(t, "application/json-patch+json", r.Header.Get("Content-Type")) {
+				w.WriteHeader(http.StatusUnsupportedMediaType)
+				return
+			}
+			w.WriteHeader(http.StatusOK)
+			_, _ = io.WriteString(w, r4Organization(orgID, "Hospital2"))
+		case "DELETE":
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		}
+	})
+
+	retrieved, resp, err := cdrClient.OperationsR4.Get("Organization/" + orgID)
+	if !assert.Nil(t, err) || !assert.NotNil(t, resp) {
+		return
+	}
+	assert.Equal(t, "Hospital", retrieved.GetOrganization().Name.Value)
+
+	created, resp, err := cdrClient.OperationsR4.Put("Organization/"+orgID, []byte(r4Organization(orgID, "Hospital")))
+	if !assert.Nil(t, err) || !assert.NotNil(t, resp) {
+		return
+	}
+	assert.Equal(t, http.StatusCreated, resp.StatusCode)
+	assert.Equal(t, orgID, created.GetOrganization().Id.Value)
+
+	patched, _, err := cdrClient.OperationsR4.Patch("Organization/"+orgID, []byte(`[{"op": "replace","path": "/name","value": "Hospital2"}]`))
+	if !assert.Nil(t, err) {
+		return
+	}
+	assert.Equal(t, "Hospital2", patched.GetOrganization().Name.Value)
+
+	ok, _, err := cdrClient.OperationsR4.Delete("Organization/" + orgID)
+	assert.Nil(t, err)
+	assert.True(t, ok)
+}
+
+func TestSTU3Accept(t *testing.T) {
+	teardown := setup(t)
+	defer teardown()
+
+	orgID := "f5fe538f-c3b5-4454-8774-cd3789f59b9f"
+
+	muxCDR.HandleFunc("/store/fhir/"+cdrOrgID+"/Organization/"+orgID, func(w http.ResponseWriter, r *http.Request) {
+		if !assert.Equal(t, "application/fhir+json;fhirVersion=3.0", r.Header.Get("Accept")) {
+			w.WriteHeader(http.StatusNotAcceptable)
+			return
+		}
+		w.Header().Set("Content-Type", "application/fhir+json")
+		w.WriteHeader(http.StatusOK)
+		_, _ = io.WriteString(w, r4Organization(orgID, "Hospital"))
+	})
+	_, _, err := cdrClient.OperationsSTU3.Get("Organization/" + orgID)
+	assert.Nil(t, err)
+}
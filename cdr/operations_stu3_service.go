@@ -24,6 +24,7 @@ func (o *OperationsSTU3Service) Patch(resourceID string, jsonPatch []byte, optio
 		return nil, nil, err
 	}
 	req.Header.Set("Content-Type", "application/json-patch+json")
+	req.Header.Set("Accept", fhirJSONSTU3)
 	var patchResponse bytes.Buffer
 	resp, err := o.client.do(req, &patchResponse)
 	if (err != nil && err != io.EOF) || resp == nil {
@@ -57,6 +58,7 @@ func (o *OperationsSTU3Service) Get(resourceID string, options ...OptionFunc) (*
 		return nil, nil, err
 	}
 	req.Header.Set("Content-Type", "application/fhir+json")
+	req.Header.Set("Accept", fhirJSONSTU3)
 	var operationResponse bytes.Buffer
 	resp, err := o.client.do(req, &operationResponse)
 	if (err != nil && err != io.EOF) || resp == nil {
@@ -80,6 +82,7 @@ func (o *OperationsSTU3Service) Delete(resourceID string, options ...OptionFunc)
 		return false, nil, err
 	}
 	req.Header.Set("Content-Type", "application/fhir+json")
+	req.Header.Set("Accept", fhirJSONSTU3)
 	var operationResponse bytes.Buffer
 	resp, err := o.client.do(req, &operationResponse)
 	if (err != nil && err != io.EOF) || resp == nil {
@@ -97,6 +100,7 @@ func (o *OperationsSTU3Service) postOrPut(method, resourceID string, jsonBody []
 		return nil, nil, err
 	}
 	req.Header.Set("Content-Type", "application/fhir+json")
+	req.Header.Set("Accept", fhirJSONSTU3)
 	var operationResponse bytes.Buffer
 	resp, err := o.client.do(req, &operationResponse)
 	if (err != nil && err != io.EOF) || resp == nil {
@@ -14,6 +14,37 @@ import (
 	"github.com/stretchr/testify/assert"
 )
 
+func stu3Organization(orgID, name string) string {
+	return `{
+  "resourceType": "Organization",
+  "id": "` + orgID + `",
+  "meta": {
+    "versionId": "6dfa7cc8-2000-11ea-91df-bb500f85c5e2",
+    "lastUpdated": "2019-12-16T12:34:40.544022+00:00"
+  },
+  "identifier": [
+    {
+      "use": "usual",
+      "system": "https://identity.philips-healthsuite.com/organization",
+      "value": "` + orgID + `"
+    }
+  ],
+  "type": [
+    {
+      "coding": [
+        {
+          "system": "http://hl7.org/fhir/organization-type",
+          "code": "prov"
+        }
+      ]
+    }
+  ],
+  "active": true,
+  "name": "` + name + `"
+}
+`
+}
+
 func TestPatchOperation(t *testing.T) {
 	teardown := setup(t)
 	defer teardown()
@@ -0,0 +1,118 @@
+package cdr
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// JSON Patch operations
+const (
+	PatchOpAdd     = "add"
+	PatchOpRemove  = "remove"
+	PatchOpReplace = "replace"
+	PatchOpTest    = "test"
+)
+
+// PatchOperation is a single JSON Patch (RFC 6902) operation
+type PatchOperation struct {
+	Op    string
+	Path  string
+	Value json.RawMessage
+}
+
+// MarshalJSON encodes the operation. The value is left out of remove operations only
+func (p PatchOperation) MarshalJSON() ([]byte, error) {
+	if p.Op == PatchOpRemove {
+		return json.Marshal(struct {
+			Op   string `json:"op"`
+			Path string `json:"path"`
+		}{p.Op, p.Path})
+	}
+	value := p.Value
+	if value == nil {
+		value = json.RawMessage("null")
+	}
+	return json.Marshal(struct {
+		Op    string          `json:"op"`
+		Path  string          `json:"path"`
+		Value json.RawMessage `json:"value"`
+	}{p.Op, p.Path, value})
+}
+
+// JSONPatch builds a JSON Patch document. Use JSONPointer to build paths
+type JSONPatch struct {
+	operations []PatchOperation
+	err        error
+}
+
+// NewJSONPatch returns an empty patch
+func NewJSONPatch() *JSONPatch {
+	return &JSONPatch{}
+}
+
+// JSONPointer returns the JSON pointer of the reference tokens, escaping
+// "~" and "/". For example JSONPointer("name", "0", "given") returns "/name/0/given"
+func JSONPointer(tokens ...string) string {
+	escaper := strings.NewReplacer("~", "~0", "/", "~1")
+	var pointer strings.Builder
+	for _, t := range tokens {
+		pointer.WriteString("/")
+		pointer.WriteString(escaper.Replace(t))
+	}
+	return pointer.String()
+}
+
+// Add adds value at path. Use "-" as the last token to append to an array
+func (p *JSONPatch) Add(path string, value interface{}) *JSONPatch {
+	return p.withValue(PatchOpAdd, path, value)
+}
+
+// Remove removes the value at path
+func (p *JSONPatch) Remove(path string) *JSONPatch {
+	p.operations = append(p.operations, PatchOperation{Op: PatchOpRemove, Path: path})
+	return p
+}
+
+// Replace replaces the value at path
+func (p *JSONPatch) Replace(path string, value interface{}) *JSONPatch {
+	return p.withValue(PatchOpReplace, path, value)
+}
+
+// Test makes the patch fail unless the value at path equals value
+func (p *JSONPatch) Test(path string, value interface{}) *JSONPatch {
+	return p.withValue(PatchOpTest, path, value)
+}
+
+// Operations returns the operations of the patch
+func (p *JSONPatch) Operations() []PatchOperation {
+	return p.operations
+}
+
+// Len returns the number of operations
+func (p *JSONPatch) Len() int {
+	return len(p.operations)
+}
+
+// MarshalJSON returns the patch document or the first error encountered while encoding values
+func (p *JSONPatch) MarshalJSON() ([]byte, error) {
+	if p.err != nil {
+		return nil, p.err
+	}
+	operations := p.operations
+	if operations == nil {
+		operations = []PatchOperation{}
+	}
+	return json.Marshal(operations)
+}
+
+func (p *JSONPatch) withValue(op, path string, value interface{}) *JSONPatch {
+	raw, ok := value.(json.RawMessage)
+	if !ok {
+		var err error
+		if raw, err = json.Marshal(value); err != nil && p.err == nil {
+			p.err = err
+		}
+	}
+	p.operations = append(p.operations, PatchOperation{Op: op, Path: path, Value: raw})
+	return p
+}
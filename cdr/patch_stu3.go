@@ -0,0 +1,152 @@
+package cdr
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+
+	jsonpatch "github.com/evanphx/json-patch/v5"
+	stu3pb "github.com/google/fhir/go/proto/google/fhir/proto/stu3/resources_go_proto"
+)
+
+// DiffOptions controls which elements Diff compares
+type DiffOptions struct {
+	// IncludeMeta also compares the meta element, which the CDR maintains itself
+	IncludeMeta bool
+}
+
+// Diff returns a patch which turns from into to. Both must be of the same resource
+// type. The meta element is skipped, as it is maintained by the CDR and differs
+// between any two versions of a resource
+func (o *OperationsSTU3Service) Diff(from, to *stu3pb.ContainedResource) (*JSONPatch, error) {
+	return o.DiffWithOptions(from, to, nil)
+}
+
+// DiffWithOptions returns a patch which turns from into to, comparing the elements selected by opt
+func (o *OperationsSTU3Service) DiffWithOptions(from, to *stu3pb.ContainedResource, opt *DiffOptions) (*JSONPatch, error) {
+	if resourceTypeOf(from) != resourceTypeOf(to) {
+		return nil, fmt.Errorf("%w: %s and %s", ErrUnexpectedResourceType, resourceTypeOf(from), resourceTypeOf(to))
+	}
+	var fromDoc, toDoc interface{}
+	if err := o.decodeResource(from, &fromDoc); err != nil {
+		return nil, err
+	}
+	if err := o.decodeResource(to, &toDoc); err != nil {
+		return nil, err
+	}
+	if opt == nil || !opt.IncludeMeta {
+		for _, doc := range []interface{}{fromDoc, toDoc} {
+			if m, ok := doc.(map[string]interface{}); ok {
+				delete(m, "meta")
+			}
+		}
+	}
+	patch := NewJSONPatch()
+	diffValues(patch, nil, fromDoc, toDoc)
+	return patch, nil
+}
+
+// ApplyPatch applies patch to resource locally. ErrInvalidPatchResult is
+// returned when the result is not a valid FHIR resource
+func (o *OperationsSTU3Service) ApplyPatch(resource *stu3pb.ContainedResource, patch *JSONPatch) (*stu3pb.ContainedResource, error) {
+	doc, err := o.ma.Marshal(resource)
+	if err != nil {
+		return nil, err
+	}
+	patchJSON, err := json.Marshal(patch)
+	if err != nil {
+		return nil, err
+	}
+	decoded, err := jsonpatch.DecodePatch(patchJSON)
+	if err != nil {
+		return nil, err
+	}
+	patched, err := decoded.Apply(doc)
+	if err != nil {
+		return nil, fmt.Errorf("apply patch: %w", err)
+	}
+	unmarshalled, err := o.um.Unmarshal(patched)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidPatchResult, err)
+	}
+	result := unmarshalled.(*stu3pb.ContainedResource)
+	if resourceTypeOf(result) != resourceTypeOf(resource) || resourceIDOf(result) != resourceIDOf(resource) {
+		return nil, fmt.Errorf("%w: resource type or id changed", ErrInvalidPatchResult)
+	}
+	return result, nil
+}
+
+// PatchResource applies patch to current locally and, if the result is a valid
+// FHIR resource, sends the patch to the CDR. current must have an ID
+func (o *OperationsSTU3Service) PatchResource(current *stu3pb.ContainedResource, patch *JSONPatch, options ...OptionFunc) (*stu3pb.ContainedResource, *Response, error) {
+	id := resourceIDOf(current)
+	if id == "" {
+		return nil, nil, ErrMissingResourceID
+	}
+	if _, err := o.ApplyPatch(current, patch); err != nil {
+		return nil, nil, err
+	}
+	patchJSON, err := json.Marshal(patch)
+	if err != nil {
+		return nil, nil, err
+	}
+	return o.Patch(resourceTypeOf(current)+"/"+id, patchJSON, options...)
+}
+
+func (o *OperationsSTU3Service) decodeResource(resource *stu3pb.ContainedResource, v interface{}) error {
+	doc, err := o.ma.Marshal(resource)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(doc, v)
+}
+
+// diffValues adds the operations turning from into to at path. Arrays of
+// different length are replaced as a whole
+func diffValues(patch *JSONPatch, path []string, from, to interface{}) {
+	switch f := from.(type) {
+	case map[string]interface{}:
+		t, ok := to.(map[string]interface{})
+		if !ok {
+			break
+		}
+		keys := make([]string, 0, len(f)+len(t))
+		for k := range f {
+			keys = append(keys, k)
+		}
+		for k := range t {
+			if _, exists := f[k]; !exists {
+				keys = append(keys, k)
+			}
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			fv, inFrom := f[k]
+			tv, inTo := t[k]
+			childPath := append(append([]string{}, path...), k)
+			switch {
+			case !inTo:
+				patch.Remove(JSONPointer(childPath...))
+			case !inFrom:
+				patch.Add(JSONPointer(childPath...), tv)
+			default:
+				diffValues(patch, childPath, fv, tv)
+			}
+		}
+		return
+	case []interface{}:
+		t, ok := to.([]interface{})
+		if !ok || len(f) != len(t) {
+			break
+		}
+		for i := range f {
+			diffValues(patch, append(append([]string{}, path...), strconv.Itoa(i)), f[i], t[i])
+		}
+		return
+	}
+	if !reflect.DeepEqual(from, to) {
+		patch.Replace(JSONPointer(path...), to)
+	}
+}
@@ -0,0 +1,153 @@
+package cdr_test
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"testing"
+
+	"github.com/google/fhir/go/jsonformat"
+	stu3pb "github.com/google/fhir/go/proto/google/fhir/proto/stu3/resources_go_proto"
+	"github.com/philips-software/go-hsdp-api/cdr"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestJSONPointer(t *testing.T) {
+	assert.Equal(t, "/name/0/given", cdr.JSONPointer("name", "0", "given"))
+	assert.Equal(t, "/a~1b/m~0n", cdr.JSONPointer("a/b", "m~n"))
+	assert.Equal(t, "", cdr.JSONPointer())
+}
+
+func TestJSONPatch(t *testing.T) {
+	patch := cdr.NewJSONPatch().
+		Test(cdr.JSONPointer("active"), true).
+		Replace(cdr.JSONPointer("name"), "New name").
+		Add(cdr.JSONPointer("alias", "-"), "Alias").
+		Add(cdr.JSONPointer("extension"), nil).
+		Remove(cdr.JSONPointer("telecom", "0"))
+	assert.Equal(t, 5, patch.Len())
+	data, err := json.Marshal(patch)
+	if !assert.Nil(t, err) {
+		return
+	}
+	assert.JSONEq(t, `[
+		{"op":"test","path":"/active","value":true},
+		{"op":"replace","path":"/name","value":"New name"},
+		{"op":"add","path":"/alias/-","value":"Alias"},
+		{"op":"add","path":"/extension","value":null},
+		{"op":"remove","path":"/telecom/0"}
+	]`, string(data))
+
+	data, err = json.Marshal(cdr.NewJSONPatch())
+	assert.Nil(t, err)
+	assert.Equal(t, `[]`, string(data))
+
+	_, err = json.Marshal(cdr.NewJSONPatch().Add("/foo", func() {}))
+	assert.NotNil(t, err)
+}
+
+func unmarshalSTU3(t *testing.T, resource string) *stu3pb.ContainedResource {
+	um, err := jsonformat.NewUnmarshaller("UTC", jsonformat.STU3)
+	if !assert.Nil(t, err) {
+		t.FailNow()
+	}
+	unmarshalled, err := um.Unmarshal([]byte(resource))
+	if !assert.Nil(t, err) {
+		t.FailNow()
+	}
+	return unmarshalled.(*stu3pb.ContainedResource)
+}
+
+func TestDiffAndApplyPatchSTU3(t *testing.T) {
+	teardown := setup(t)
+	defer teardown()
+
+	from := unmarshalSTU3(t, `{"resourceType":"Patient","id":"123","active":true,"gender":"male",`+
+		`"name":[{"family":"Swanson","given":["Ron"]}],"telecom":[{"system":"phone","value":"555"}]}`)
+	to := unmarshalSTU3(t, `{"resourceType":"Patient","id":"123","active":false,"birthDate":"1970-01-01",`+
+		`"name":[{"family":"Swanson","given":["Ron","Ulysses"]}],"telecom":[{"system":"phone","value":"556"}]}`)
+
+	patch, err := cdrClient.OperationsSTU3.Diff(from, to)
+	if !assert.Nil(t, err) {
+		return
+	}
+	data, _ := json.Marshal(patch)
+	assert.JSONEq(t, `[
+		{"op":"replace","path":"/active","value":false},
+		{"op":"add","path":"/birthDate","value":"1970-01-01"},
+		{"op":"remove","path":"/gender"},
+		{"op":"replace","path":"/name/0/given","value":["Ron","Ulysses"]},
+		{"op":"replace","path":"/telecom/0/value","value":"556"}
+	]`, string(data))
+
+	patched, err := cdrClient.OperationsSTU3.ApplyPatch(from, patch)
+	if !assert.Nil(t, err) {
+		return
+	}
+	same, err := cdrClient.OperationsSTU3.Diff(patched, to)
+	if !assert.Nil(t, err) {
+		return
+	}
+	assert.Equal(t, 0, same.Len())
+
+	// Meta is only compared on request
+	newer := unmarshalSTU3(t, `{"resourceType":"Patient","id":"123","active":false,"birthDate":"1970-01-01",`+
+		`"meta":{"versionId":"2","lastUpdated":"2021-06-01T12:00:00Z"},`+
+		`"name":[{"family":"Swanson","given":["Ron","Ulysses"]}],"telecom":[{"system":"phone","value":"556"}]}`)
+	same, err = cdrClient.OperationsSTU3.Diff(to, newer)
+	if !assert.Nil(t, err) {
+		return
+	}
+	assert.Equal(t, 0, same.Len())
+	withMeta, err := cdrClient.OperationsSTU3.DiffWithOptions(to, newer, &cdr.DiffOptions{IncludeMeta: true})
+	if !assert.Nil(t, err) {
+		return
+	}
+	data, _ = json.Marshal(withMeta)
+	assert.JSONEq(t, `[{"op":"add","path":"/meta","value":{"versionId":"2","lastUpdated":"2021-06-01T12:00:00Z"}}]`, string(data))
+
+	_, err = cdrClient.OperationsSTU3.ApplyPatch(from, cdr.NewJSONPatch().Replace("/gender", "unknown-gender"))
+	assert.True(t, errors.Is(err, cdr.ErrInvalidPatchResult))
+	_, err = cdrClient.OperationsSTU3.ApplyPatch(from, cdr.NewJSONPatch().Replace("/id", "456"))
+	assert.True(t, errors.Is(err, cdr.ErrInvalidPatchResult))
+	_, err = cdrClient.OperationsSTU3.ApplyPatch(from, cdr.NewJSONPatch().Test("/active", false))
+	assert.NotNil(t, err)
+
+	_, err = cdrClient.OperationsSTU3.Diff(from, unmarshalSTU3(t, r4Organization("123", "Hospital")))
+	assert.True(t, errors.Is(err, cdr.ErrUnexpectedResourceType))
+}
+
+func TestPatchResourceSTU3(t *testing.T) {
+	teardown := setup(t)
+	defer teardown()
+
+	var calls int
+	muxCDR.HandleFunc("/store/fhir/"+cdrOrgID+"/Patient/123", func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if !assert.Equal(t, "PATCH", r.Method) {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		body, _ := ioutil.ReadAll(r.Body)
+		assert.JSONEq(t, `[{"op":"replace","path":"/active","value":false}]`, string(body))
+		w.Header().Set("Content-Type", "application/fhir+json")
+		w.WriteHeader(http.StatusOK)
+		_, _ = io.WriteString(w, `{"resourceType":"Patient","id":"123","active":false}`)
+	})
+
+	current := unmarshalSTU3(t, `{"resourceType":"Patient","id":"123","active":true}`)
+	patched, resp, err := cdrClient.OperationsSTU3.PatchResource(current, cdr.NewJSONPatch().Replace("/active", false))
+	if !assert.Nil(t, err) || !assert.NotNil(t, resp) || !assert.NotNil(t, patched) {
+		return
+	}
+	assert.False(t, patched.GetPatient().GetActive().GetValue())
+
+	_, _, err = cdrClient.OperationsSTU3.PatchResource(current, cdr.NewJSONPatch().Replace("/active", "maybe"))
+	assert.True(t, errors.Is(err, cdr.ErrInvalidPatchResult))
+	assert.Equal(t, 1, calls)
+
+	_, _, err = cdrClient.OperationsSTU3.PatchResource(unmarshalSTU3(t, `{"resourceType":"Patient"}`), cdr.NewJSONPatch())
+	assert.Equal(t, cdr.ErrMissingResourceID, err)
+}
@@ -0,0 +1,111 @@
+package cdr
+
+import (
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// Search prefixes for ordered parameter values such as dates and numbers
+const (
+	PrefixEq = "eq"
+	PrefixNe = "ne"
+	PrefixGt = "gt"
+	PrefixLt = "lt"
+	PrefixGe = "ge"
+	PrefixLe = "le"
+	PrefixSa = "sa"
+	PrefixEb = "eb"
+	PrefixAp = "ap"
+)
+
+// SearchParam is a FHIR search parameter. It is encoded as
+// name[:modifier][.chain...]=[prefix]value
+type SearchParam struct {
+	Name string
+	// Modifier is e.g. "exact", "missing" or, for chained parameters, the target resource type
+	Modifier string
+	// Chain are the parameter names following Name in a chained search
+	Chain  []string
+	Prefix string
+	// Values are combined with OR
+	Values []string
+}
+
+// Param returns a search parameter matching any of values
+func Param(name string, values ...string) SearchParam {
+	return SearchParam{Name: name, Values: values}
+}
+
+// ParamWithPrefix returns a search parameter comparing with value, e.g. ParamWithPrefix("birthdate", PrefixGe, "2000-01-01")
+func ParamWithPrefix(name, prefix, value string) SearchParam {
+	return SearchParam{Name: name, Prefix: prefix, Values: []string{value}}
+}
+
+// ChainedParam returns a chained search parameter. For example
+// ChainedParam("subject", "Patient", []string{"name"}, "peter") searches subject:Patient.name=peter
+func ChainedParam(name, resourceType string, chain []string, values ...string) SearchParam {
+	return SearchParam{Name: name, Modifier: resourceType, Chain: chain, Values: values}
+}
+
+func (p SearchParam) key() string {
+	key := p.Name
+	if p.Modifier != "" {
+		key += ":" + p.Modifier
+	}
+	for _, c := range p.Chain {
+		key += "." + c
+	}
+	return key
+}
+
+// SearchOptions describes a FHIR search
+type SearchOptions struct {
+	Params []SearchParam
+	// Include and RevInclude add referenced resources, e.g. "Observation:subject"
+	Include    []string
+	RevInclude []string
+	// Sort lists the fields to sort on. Prefix a field with "-" to sort descending
+	Sort []string
+	// Count is the page size
+	Count *int
+	// Elements limits the returned elements of each resource
+	Elements []string
+}
+
+// Values returns the query parameters of the search
+func (o *SearchOptions) Values() url.Values {
+	values := url.Values{}
+	if o == nil {
+		return values
+	}
+	for _, p := range o.Params {
+		encoded := make([]string, len(p.Values))
+		for i, v := range p.Values {
+			encoded[i] = p.Prefix + escapeSearchValue(v)
+		}
+		values.Add(p.key(), strings.Join(encoded, ","))
+	}
+	for _, i := range o.Include {
+		values.Add("_include", i)
+	}
+	for _, i := range o.RevInclude {
+		values.Add("_revinclude", i)
+	}
+	if len(o.Sort) > 0 {
+		values.Set("_sort", strings.Join(o.Sort, ","))
+	}
+	if o.Count != nil {
+		values.Set("_count", strconv.Itoa(*o.Count))
+	}
+	if len(o.Elements) > 0 {
+		values.Set("_elements", strings.Join(o.Elements, ","))
+	}
+	return values
+}
+
+// escapeSearchValue escapes commas, which separate values. Token separators
+// such as "|" are left as they are so "system|code" keeps its meaning
+func escapeSearchValue(v string) string {
+	return strings.NewReplacer(`\`, `\\`, `,`, `\,`).Replace(v)
+}
@@ -0,0 +1,180 @@
+package cdr
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/google/fhir/go/proto/google/fhir/proto/stu3/codes_go_proto"
+	stu3pb "github.com/google/fhir/go/proto/google/fhir/proto/stu3/resources_go_proto"
+)
+
+// Search returns the first page of resources of resourceType matching opt
+func (o *OperationsSTU3Service) Search(resourceType string, opt *SearchOptions, options ...OptionFunc) (*stu3pb.Bundle, *Response, error) {
+	req, err := o.client.newCDRRequest(http.MethodGet, resourceType, nil, options)
+	if err != nil {
+		return nil, nil, err
+	}
+	req.URL.RawQuery = opt.Values().Encode()
+	return o.searchBundle(req)
+}
+
+// Next returns the page following bundle. ErrNoMorePages is returned on the last page
+func (o *OperationsSTU3Service) Next(bundle *stu3pb.Bundle, options ...OptionFunc) (*stu3pb.Bundle, *Response, error) {
+	link := NextLinkSTU3(bundle)
+	if link == "" {
+		return nil, nil, ErrNoMorePages
+	}
+	next, err := url.Parse(link)
+	if err != nil {
+		return nil, nil, err
+	}
+	req, err := o.client.newCDRRequest(http.MethodGet, "", nil, options)
+	if err != nil {
+		return nil, nil, err
+	}
+	// Only the path and query of the link are used so the token is never sent elsewhere
+	if !strings.HasPrefix(next.EscapedPath(), o.client.fhirStoreURL.Path) {
+		return nil, nil, fmt.Errorf("%w: %s", ErrInvalidNextLink, link)
+	}
+	req.URL.Opaque = next.EscapedPath()
+	req.URL.RawQuery = next.RawQuery
+	return o.searchBundle(req)
+}
+
+// Iterate returns an iterator over the resources of resourceType matching opt.
+// It follows the next links until all pages are read or max resources are
+// returned. A max of zero means no limit. Included resources are returned
+// as well but do not count towards max.
+func (o *OperationsSTU3Service) Iterate(resourceType string, opt *SearchOptions, max int, options ...OptionFunc) *SearchIteratorSTU3 {
+	return &SearchIteratorSTU3{service: o, resourceType: resourceType, opt: opt, max: max, options: options}
+}
+
+// SearchIteratorSTU3 iterates over the entries of search result bundles
+type SearchIteratorSTU3 struct {
+	service      *OperationsSTU3Service
+	resourceType string
+	opt          *SearchOptions
+	max          int
+	options      []OptionFunc
+
+	bundle  *stu3pb.Bundle
+	index   int
+	matches int
+	entry   *stu3pb.Bundle_Entry
+	err     error
+	done    bool
+}
+
+// Next advances to the next entry. It returns false when there are no more
+// entries or an error occurred, which is returned by Err
+func (it *SearchIteratorSTU3) Next() bool {
+	for !it.done {
+		if it.bundle == nil || it.index >= len(it.bundle.Entry) {
+			if !it.fetch() {
+				return false
+			}
+			continue
+		}
+		entry := it.bundle.Entry[it.index]
+		it.index++
+		if !isIncludedSTU3(entry) {
+			if it.max > 0 && it.matches >= it.max {
+				it.done = true
+				return false
+			}
+			it.matches++
+		}
+		it.entry = entry
+		return true
+	}
+	return false
+}
+
+func (it *SearchIteratorSTU3) fetch() bool {
+	var bundle *stu3pb.Bundle
+	var err error
+	if it.bundle == nil {
+		bundle, _, err = it.service.Search(it.resourceType, it.opt, it.options...)
+	} else {
+		if NextLinkSTU3(it.bundle) == "" {
+			it.done = true
+			return false
+		}
+		bundle, _, err = it.service.Next(it.bundle, it.options...)
+	}
+	if err != nil {
+		it.err = err
+		it.done = true
+		return false
+	}
+	it.bundle = bundle
+	it.index = 0
+	return true
+}
+
+// Entry returns the current bundle entry
+func (it *SearchIteratorSTU3) Entry() *stu3pb.Bundle_Entry {
+	return it.entry
+}
+
+// Resource returns the resource of the current entry
+func (it *SearchIteratorSTU3) Resource() *stu3pb.ContainedResource {
+	if it.entry == nil {
+		return nil
+	}
+	return it.entry.Resource
+}
+
+// Bundle returns the current page
+func (it *SearchIteratorSTU3) Bundle() *stu3pb.Bundle {
+	return it.bundle
+}
+
+// Err returns the error which stopped the iteration, if any
+func (it *SearchIteratorSTU3) Err() error {
+	return it.err
+}
+
+// NextLinkSTU3 returns the URL of the next page of bundle, empty if there is none
+func NextLinkSTU3(bundle *stu3pb.Bundle) string {
+	if bundle == nil {
+		return ""
+	}
+	for _, link := range bundle.Link {
+		if link.Relation.GetValue() == "next" && link.Url != nil {
+			return link.Url.Value
+		}
+	}
+	return ""
+}
+
+func isIncludedSTU3(entry *stu3pb.Bundle_Entry) bool {
+	mode := entry.Search.GetMode().GetValue()
+	return mode == codes_go_proto.SearchEntryModeCode_INCLUDE || mode == codes_go_proto.SearchEntryModeCode_OUTCOME
+}
+
+func (o *OperationsSTU3Service) searchBundle(req *http.Request) (*stu3pb.Bundle, *Response, error) {
+	req.Header.Set("Content-Type", "application/fhir+json")
+	req.Header.Set("Accept", fhirJSONSTU3)
+	var searchResponse bytes.Buffer
+	resp, err := o.client.do(req, &searchResponse)
+	if (err != nil && err != io.EOF) || resp == nil {
+		if resp == nil && err != nil {
+			err = fmt.Errorf("OperationsSTU3Service.Search: %w", ErrEmptyResult)
+		}
+		return nil, resp, err
+	}
+	unmarshalled, err := o.um.Unmarshal(searchResponse.Bytes())
+	if err != nil {
+		return nil, resp, fmt.Errorf("FHIR unmarshal: %w", err)
+	}
+	bundle := unmarshalled.(*stu3pb.ContainedResource).GetBundle()
+	if bundle == nil {
+		return nil, resp, ErrUnexpectedResourceType
+	}
+	return bundle, resp, nil
+}
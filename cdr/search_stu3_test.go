@@ -0,0 +1,129 @@
+package cdr_test
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/philips-software/go-hsdp-api/cdr"
+	"github.com/stretchr/testify/assert"
+)
+
+func patientEntry(id, mode string) string {
+	return searchEntry("Patient", id, mode)
+}
+
+func searchEntry(resourceType, id, mode string) string {
+	return `{"fullUrl":"` + resourceType + `/` + id + `","resource":{"resourceType":"` + resourceType + `","id":"` + id + `"},"search":{"mode":"` + mode + `"}}`
+}
+
+func TestSearchOptions(t *testing.T) {
+	count := 10
+	opt := &cdr.SearchOptions{
+		Params: []cdr.SearchParam{
+			cdr.Param("name", "peter", "a,b"),
+			cdr.ParamWithPrefix("birthdate", cdr.PrefixGe, "2000-01-01"),
+			cdr.ChainedParam("subject", "Patient", []string{"identifier"}, "http://example.com|123"),
+			{Name: "gender", Modifier: "missing", Values: []string{"true"}},
+		},
+		Include:    []string{"Observation:subject"},
+		RevInclude: []string{"Provenance:target"},
+		Sort:       []string{"-date", "name"},
+		Count:      &count,
+		Elements:   []string{"id", "name"},
+	}
+	values := opt.Values()
+	assert.Equal(t, `peter,a\,b`, values.Get("name"))
+	assert.Equal(t, "ge2000-01-01", values.Get("birthdate"))
+	assert.Equal(t, "http://example.com|123", values.Get("subject:Patient.identifier"))
+	assert.Equal(t, "true", values.Get("gender:missing"))
+	assert.Equal(t, "Observation:subject", values.Get("_include"))
+	assert.Equal(t, "Provenance:target", values.Get("_revinclude"))
+	assert.Equal(t, "-date,name", values.Get("_sort"))
+	assert.Equal(t, "10", values.Get("_count"))
+	assert.Equal(t, "id,name", values.Get("_elements"))
+	assert.Equal(t, []string{"peter", "a,b"}, opt.Params[0].Values)
+}
+
+func TestSearchSTU3(t *testing.T) {
+	teardown := setup(t)
+	defer teardown()
+
+	base := "/store/fhir/" + cdrOrgID + "/Patient"
+	muxCDR.HandleFunc(base, func(w http.ResponseWriter, r *http.Request) {
+		if !assert.Equal(t, "GET", r.Method) {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		w.Header().Set("Content-Type", "application/fhir+json")
+		page := r.URL.Query().Get("page")
+		switch page {
+		case "":
+			assert.Equal(t, "peter", r.URL.Query().Get("name"))
+			_, _ = io.WriteString(w, fmt.Sprintf(`{"resourceType":"Bundle","type":"searchset","total":5,
+"link":[{"relation":"self","url":"%[1]s"},{"relation":"next","url":"%[1]s?page=2"}],
+"entry":[%s,%s,%s]}`, "https://other.example.com"+base, patientEntry("p1", "match"), patientEntry("p2", "match"), searchEntry("Organization", "o1", "include")))
+		case "2":
+			_, _ = io.WriteString(w, fmt.Sprintf(`{"resourceType":"Bundle","type":"searchset","total":5,
+"link":[{"relation":"next","url":"%[1]s?page=3"}],"entry":[%s,%s]}`, base, patientEntry("p3", "match"), patientEntry("p4", "match")))
+		case "3":
+			_, _ = io.WriteString(w, fmt.Sprintf(`{"resourceType":"Bundle","type":"searchset","total":5,"entry":[%s]}`, patientEntry("p5", "match")))
+		}
+	})
+	opt := &cdr.SearchOptions{Params: []cdr.SearchParam{cdr.Param("name", "peter")}}
+
+	bundle, resp, err := cdrClient.OperationsSTU3.Search("Patient", opt)
+	if !assert.Nil(t, err) || !assert.NotNil(t, resp) {
+		return
+	}
+	assert.Equal(t, uint32(5), bundle.Total.Value)
+	assert.Len(t, bundle.Entry, 3)
+	assert.True(t, strings.HasSuffix(cdr.NextLinkSTU3(bundle), "page=2"))
+
+	var ids []string
+	it := cdrClient.OperationsSTU3.Iterate("Patient", opt, 0)
+	included := 0
+	for it.Next() {
+		if p := it.Resource().GetPatient(); p != nil {
+			ids = append(ids, p.Id.Value)
+		} else {
+			included++
+		}
+	}
+	assert.Equal(t, 1, included)
+	assert.Nil(t, it.Err())
+	assert.Equal(t, []string{"p1", "p2", "p3", "p4", "p5"}, ids)
+
+	ids = nil
+	it = cdrClient.OperationsSTU3.Iterate("Patient", opt, 3)
+	for it.Next() {
+		if p := it.Resource().GetPatient(); p != nil {
+			ids = append(ids, p.Id.Value)
+		}
+	}
+	assert.Nil(t, it.Err())
+	assert.Equal(t, []string{"p1", "p2", "p3"}, ids)
+
+	last, _, err := cdrClient.OperationsSTU3.Search("Patient", opt)
+	if assert.Nil(t, err) {
+		last.Link = nil
+		_, _, err = cdrClient.OperationsSTU3.Next(last)
+		assert.Equal(t, cdr.ErrNoMorePages, err)
+	}
+}
+
+func TestSearchSTU3Error(t *testing.T) {
+	teardown := setup(t)
+	defer teardown()
+
+	muxCDR.HandleFunc("/store/fhir/"+cdrOrgID+"/Patient", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/fhir+json")
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = io.WriteString(w, `{"resourceType":"OperationOutcome"}`)
+	})
+	it := cdrClient.OperationsSTU3.Iterate("Patient", nil, 0)
+	assert.False(t, it.Next())
+	assert.ErrorIs(t, it.Err(), cdr.ErrNonHttp20xResponse)
+}
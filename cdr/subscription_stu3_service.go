@@ -0,0 +1,123 @@
+package cdr
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/google/fhir/go/jsonformat"
+	stu3pb "github.com/google/fhir/go/proto/google/fhir/proto/stu3/resources_go_proto"
+	"github.com/philips-software/go-hsdp-api/cdr/helper/fhir/stu3"
+)
+
+// SubscriptionSTU3Service manages FHIR STU3 Subscriptions. Use the helpers in
+// cdr/helper/fhir/stu3 to build them
+type SubscriptionSTU3Service struct {
+	client   *Client
+	timeZone string
+	ma       *jsonformat.Marshaller
+	um       *jsonformat.Unmarshaller
+}
+
+// SubscriptionStatus is the state of a subscription as reported by the CDR
+type SubscriptionStatus struct {
+	// Status is one of "requested", "active", "error" or "off"
+	Status string
+	// Error is the last error the CDR encountered delivering notifications
+	Error string
+}
+
+// Create creates a subscription. The CDR activates it after validating the channel
+func (s *SubscriptionSTU3Service) Create(sub *stu3pb.Subscription, options ...OptionFunc) (*stu3pb.Subscription, *Response, error) {
+	subJSON, err := s.ma.MarshalResource(sub)
+	if err != nil {
+		return nil, nil, err
+	}
+	req, err := s.client.newCDRRequest(http.MethodPost, "Subscription", subJSON, options)
+	if err != nil {
+		return nil, nil, err
+	}
+	return s.subscriptionRequest("Create", req)
+}
+
+// Get returns the subscription with the given ID
+func (s *SubscriptionSTU3Service) Get(id string, options ...OptionFunc) (*stu3pb.Subscription, *Response, error) {
+	req, err := s.client.newCDRRequest(http.MethodGet, "Subscription/"+id, nil, options)
+	if err != nil {
+		return nil, nil, err
+	}
+	return s.subscriptionRequest("Get", req)
+}
+
+// Update replaces the subscription, which must have an ID
+func (s *SubscriptionSTU3Service) Update(sub *stu3pb.Subscription, options ...OptionFunc) (*stu3pb.Subscription, *Response, error) {
+	id := sub.GetId().GetValue()
+	if id == "" {
+		return nil, nil, ErrMissingResourceID
+	}
+	subJSON, err := s.ma.MarshalResource(sub)
+	if err != nil {
+		return nil, nil, err
+	}
+	req, err := s.client.newCDRRequest(http.MethodPut, "Subscription/"+id, subJSON, options)
+	if err != nil {
+		return nil, nil, err
+	}
+	return s.subscriptionRequest("Update", req)
+}
+
+// Delete removes the subscription with the given ID
+func (s *SubscriptionSTU3Service) Delete(id string, options ...OptionFunc) (bool, *Response, error) {
+	return s.client.OperationsSTU3.Delete("Subscription/"+id, options...)
+}
+
+// List returns all subscriptions matching opt, following the next links of the
+// search. The returned Response is nil as more than one request may be made
+func (s *SubscriptionSTU3Service) List(opt *SearchOptions, options ...OptionFunc) ([]*stu3pb.Subscription, *Response, error) {
+	var subs []*stu3pb.Subscription
+	it := s.client.OperationsSTU3.Iterate("Subscription", opt, 0, options...)
+	for it.Next() {
+		if sub := it.Resource().GetSubscription(); sub != nil {
+			subs = append(subs, sub)
+		}
+	}
+	if err := it.Err(); err != nil {
+		return nil, nil, err
+	}
+	return subs, nil, nil
+}
+
+// Status returns the status and last error of the subscription with the given ID
+func (s *SubscriptionSTU3Service) Status(id string, options ...OptionFunc) (*SubscriptionStatus, *Response, error) {
+	sub, resp, err := s.Get(id, options...)
+	if err != nil {
+		return nil, resp, err
+	}
+	return &SubscriptionStatus{
+		Status: stu3.StatusValue()(sub),
+		Error:  stu3.ErrorValue()(sub),
+	}, resp, nil
+}
+
+func (s *SubscriptionSTU3Service) subscriptionRequest(operation string, req *http.Request) (*stu3pb.Subscription, *Response, error) {
+	req.Header.Set("Content-Type", "application/fhir+json")
+	req.Header.Set("Accept", fhirJSONSTU3)
+	var subResponse bytes.Buffer
+	resp, err := s.client.do(req, &subResponse)
+	if (err != nil && err != io.EOF) || resp == nil {
+		if resp == nil && err != nil {
+			err = fmt.Errorf("SubscriptionSTU3Service.%s: %w", operation, ErrEmptyResult)
+		}
+		return nil, resp, err
+	}
+	unmarshalled, err := s.um.Unmarshal(subResponse.Bytes())
+	if err != nil {
+		return nil, resp, fmt.Errorf("FHIR unmarshal: %w", err)
+	}
+	sub := unmarshalled.(*stu3pb.ContainedResource).GetSubscription()
+	if sub == nil {
+		return nil, resp, ErrUnexpectedResourceType
+	}
+	return sub, resp, nil
+}
@@ -0,0 +1,113 @@
+package cdr_test
+
+import (
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/google/fhir/go/proto/google/fhir/proto/stu3/codes_go_proto"
+	"github.com/philips-software/go-hsdp-api/cdr"
+	"github.com/philips-software/go-hsdp-api/cdr/helper/fhir/stu3"
+	"github.com/stretchr/testify/assert"
+)
+
+func subscriptionJSON(id, status, errorText string) string {
+	sub := `{"resourceType":"Subscription","id":"` + id + `","status":"` + status + `",` +
+		`"criteria":"Patient?given=Ron","reason":"some reason",` +
+		`"channel":{"type":"rest-hook","endpoint":"https://foo/notification","payload":"application/fhir+json"}`
+	if errorText != "" {
+		sub += `,"error":"` + errorText + `"`
+	}
+	return sub + `}`
+}
+
+func TestSubscriptionSTU3(t *testing.T) {
+	teardown := setup(t)
+	defer teardown()
+
+	subID := "0a6b6b4c-d7b7-4e5f-9b1c-cd27ba4a6df3"
+	status := "requested"
+
+	muxCDR.HandleFunc("/store/fhir/"+cdrOrgID+"/Subscription", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/fhir+json")
+		switch r.Method {
+		case "POST":
+			body, _ := ioutil.ReadAll(r.Body)
+			if !assert.True(t, strings.Contains(string(body), `"criteria":"Patient?given=Ron"`)) {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			w.WriteHeader(http.StatusCreated)
+			_, _ = io.WriteString(w, subscriptionJSON(subID, status, ""))
+		case "GET":
+			w.WriteHeader(http.StatusOK)
+			_, _ = io.WriteString(w, `{"resourceType":"Bundle","type":"searchset","total":1,"entry":[`+
+				`{"fullUrl":"Subscription/`+subID+`","resource":`+subscriptionJSON(subID, status, "")+`,"search":{"mode":"match"}}]}`)
+		}
+	})
+	muxCDR.HandleFunc("/store/fhir/"+cdrOrgID+"/Subscription/"+subID, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/fhir+json")
+		switch r.Method {
+		case "GET":
+			w.WriteHeader(http.StatusOK)
+			_, _ = io.WriteString(w, subscriptionJSON(subID, "error", "endpoint unreachable"))
+		case "PUT":
+			body, _ := ioutil.ReadAll(r.Body)
+			assert.True(t, strings.Contains(string(body), `"status":"off"`))
+			w.WriteHeader(http.StatusOK)
+			_, _ = io.WriteString(w, subscriptionJSON(subID, "off", ""))
+		case "DELETE":
+			w.WriteHeader(http.StatusNoContent)
+		}
+	})
+
+	sub, err := stu3.NewSubscription(
+		stu3.WithCriteria("Patient?given=Ron"),
+		stu3.WithReason("some reason"),
+		stu3.WithEndpoint("https://foo/notification"))
+	if !assert.Nil(t, err) {
+		return
+	}
+	created, resp, err := cdrClient.SubscriptionSTU3.Create(sub)
+	if !assert.Nil(t, err) || !assert.NotNil(t, resp) || !assert.NotNil(t, created) {
+		return
+	}
+	assert.Equal(t, http.StatusCreated, resp.StatusCode)
+	assert.Equal(t, subID, created.Id.GetValue())
+
+	subs, _, err := cdrClient.SubscriptionSTU3.List(&cdr.SearchOptions{
+		Params: []cdr.SearchParam{cdr.Param("status", "requested")},
+	})
+	if !assert.Nil(t, err) || !assert.Len(t, subs, 1) {
+		return
+	}
+	assert.Equal(t, subID, subs[0].Id.GetValue())
+
+	subStatus, _, err := cdrClient.SubscriptionSTU3.Status(subID)
+	if !assert.Nil(t, err) || !assert.NotNil(t, subStatus) {
+		return
+	}
+	assert.Equal(t, "error", subStatus.Status)
+	assert.Equal(t, "endpoint unreachable", subStatus.Error)
+
+	got, _, err := cdrClient.SubscriptionSTU3.Get(subID)
+	if !assert.Nil(t, err) || !assert.NotNil(t, got) {
+		return
+	}
+	got.Status.Value = codes_go_proto.SubscriptionStatusCode_OFF
+	updated, _, err := cdrClient.SubscriptionSTU3.Update(got)
+	if !assert.Nil(t, err) || !assert.NotNil(t, updated) {
+		return
+	}
+	assert.Equal(t, "off", stu3.StatusValue()(updated))
+
+	ok, _, err := cdrClient.SubscriptionSTU3.Delete(subID)
+	assert.Nil(t, err)
+	assert.True(t, ok)
+
+	_, _, err = cdrClient.SubscriptionSTU3.Update(sub)
+	assert.True(t, errors.Is(err, cdr.ErrMissingResourceID))
+}
@@ -31,6 +31,7 @@ func (t *TenantSTU3Service) Onboard(organization *stu3pb.Organization, options .
 		return nil, nil, err
 	}
 	req.Header.Set("Content-Type", "application/fhir+json")
+	req.Header.Set("Accept", fhirJSONSTU3)
 
 	var onboardResponse bytes.Buffer
 	resp, err := t.client.do(req, &onboardResponse)
@@ -55,6 +56,7 @@ func (t *TenantSTU3Service) GetOrganizationByID(orgID string) (*stu3pb.Organizat
 		return nil, nil, err
 	}
 	req.Header.Set("Content-Type", "application/fhir+json")
+	req.Header.Set("Accept", fhirJSONSTU3)
 
 	var getResponse bytes.Buffer
 	resp, err := t.client.do(req, &getResponse)
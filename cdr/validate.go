@@ -0,0 +1,240 @@
+package cdr
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/google/fhir/go/jsonformat/fhirvalidate"
+	apb "github.com/google/fhir/go/proto/google/fhir/proto/annotations_go_proto"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// Issue severities
+const (
+	SeverityFatal       = "fatal"
+	SeverityError       = "error"
+	SeverityWarning     = "warning"
+	SeverityInformation = "information"
+)
+
+// ValidationIssue is a problem found in a resource
+type ValidationIssue struct {
+	Severity string
+	// Code is the FHIR issue type, e.g. "required", "code-invalid" or "value"
+	Code        string
+	Diagnostics string
+	// Location is the FHIRPath of the element, e.g. "Patient.name[0].given[1]"
+	Location string
+}
+
+func (i ValidationIssue) String() string {
+	return fmt.Sprintf("%s: %s: %s (%s)", i.Severity, i.Location, i.Diagnostics, i.Code)
+}
+
+// ValidationIssues is the outcome of a validation
+type ValidationIssues []ValidationIssue
+
+// HasErrors returns true if any of the issues is an error or fatal
+func (v ValidationIssues) HasErrors() bool {
+	for _, i := range v {
+		if i.Severity == SeverityError || i.Severity == SeverityFatal {
+			return true
+		}
+	}
+	return false
+}
+
+// ValidateResource validates a STU3 or R4 resource, e.g. a *stu3pb.Patient or a
+// ContainedResource, locally. It checks required elements, the format of primitive
+// values and that codes bound to a core value set are valid
+func ValidateResource(resource proto.Message) ValidationIssues {
+	if resource == nil {
+		return ValidationIssues{{Severity: SeverityFatal, Code: "structure", Diagnostics: "no resource"}}
+	}
+	m := unwrapContained(resource.ProtoReflect())
+	if m == nil {
+		return ValidationIssues{{Severity: SeverityFatal, Code: "structure", Diagnostics: "empty contained resource"}}
+	}
+	var issues ValidationIssues
+	validateMessage(m, string(m.Descriptor().Name()), &issues)
+	return issues
+}
+
+// unwrapContained returns the resource inside a ContainedResource or m itself
+func unwrapContained(m protoreflect.Message) protoreflect.Message {
+	d := m.Descriptor()
+	if d.Name() != "ContainedResource" || d.Oneofs().Len() == 0 {
+		return m
+	}
+	fd := m.WhichOneof(d.Oneofs().Get(0))
+	if fd == nil {
+		return nil
+	}
+	return m.Get(fd).Message()
+}
+
+func validateMessage(m protoreflect.Message, path string, issues *ValidationIssues) {
+	if fd := m.Descriptor().Fields().ByName("value"); fd != nil && fd.Enum() != nil {
+		validateCode(m, fd, path, issues)
+		return
+	}
+	if isKind(m.Descriptor(), apb.StructureDefinitionKindValue_KIND_PRIMITIVE_TYPE) {
+		validatePrimitive(m, path, issues)
+		return
+	}
+	// Choices such as Patient.deceased[x] are named after the choice element only
+	choice := proto.GetExtension(m.Descriptor().Options(), apb.E_IsChoiceType).(bool)
+	fields := m.Descriptor().Fields()
+	for i := 0; i < fields.Len(); i++ {
+		fd := fields.Get(i)
+		fieldPath := path
+		if !choice {
+			fieldPath = path + "." + fd.JSONName()
+		}
+		if fd.Message() == nil {
+			continue
+		}
+		required := proto.GetExtension(fd.Options(), apb.E_ValidationRequirement).(apb.Requirement) == apb.Requirement_REQUIRED_BY_FHIR
+		if fd.IsList() {
+			list := m.Get(fd).List()
+			if required && list.Len() == 0 {
+				*issues = append(*issues, missingElement(fieldPath))
+			}
+			for j := 0; j < list.Len(); j++ {
+				validateElement(list.Get(j).Message(), fmt.Sprintf("%s[%d]", fieldPath, j), issues)
+			}
+			continue
+		}
+		if !m.Has(fd) {
+			if required {
+				*issues = append(*issues, missingElement(fieldPath))
+			}
+			continue
+		}
+		validateElement(m.Get(fd).Message(), fieldPath, issues)
+	}
+}
+
+// validateElement validates a child element, unwrapping contained resources
+func validateElement(m protoreflect.Message, path string, issues *ValidationIssues) {
+	if inner := unwrapContained(m); inner != nil {
+		validateMessage(inner, path, issues)
+	}
+}
+
+func validatePrimitive(m protoreflect.Message, path string, issues *ValidationIssues) {
+	if err := fhirvalidate.ValidatePrimitives(m.Interface()); err != nil {
+		*issues = append(*issues, ValidationIssue{
+			Severity:    SeverityError,
+			Code:        "value",
+			Diagnostics: err.Error(),
+			Location:    path,
+		})
+	}
+}
+
+// validateCode reports codes which are not part of the bound value set. These
+// are unmarshalled as the uninitialized enum value
+func validateCode(m protoreflect.Message, fd protoreflect.FieldDescriptor, path string, issues *ValidationIssues) {
+	if m.Get(fd).Enum() != 0 {
+		return
+	}
+	// A code may be absent if an extension, e.g. data-absent-reason, takes its place
+	if ext := m.Descriptor().Fields().ByName("extension"); ext != nil && m.Get(ext).List().Len() > 0 {
+		return
+	}
+	diagnostics := "invalid code"
+	if valueSet := proto.GetExtension(m.Descriptor().Options(), apb.E_FhirValuesetUrl).(string); valueSet != "" {
+		diagnostics = fmt.Sprintf("code is not in value set %s", valueSet)
+	}
+	*issues = append(*issues, ValidationIssue{
+		Severity:    SeverityError,
+		Code:        "code-invalid",
+		Diagnostics: diagnostics,
+		Location:    path,
+	})
+}
+
+func missingElement(path string) ValidationIssue {
+	return ValidationIssue{
+		Severity:    SeverityError,
+		Code:        "required",
+		Diagnostics: fmt.Sprintf("missing required element %s", path[strings.LastIndex(path, ".")+1:]),
+		Location:    path,
+	}
+}
+
+func isKind(d protoreflect.MessageDescriptor, kind apb.StructureDefinitionKindValue) bool {
+	return proto.GetExtension(d.Options(), apb.E_StructureDefinitionKind).(apb.StructureDefinitionKindValue) == kind
+}
+
+// issuesFromOutcome converts the issues of a STU3 or R4 OperationOutcome
+func issuesFromOutcome(outcome protoreflect.Message) ValidationIssues {
+	issues := ValidationIssues{}
+	issueField := outcome.Descriptor().Fields().ByName("issue")
+	if issueField == nil {
+		return issues
+	}
+	list := outcome.Get(issueField).List()
+	for i := 0; i < list.Len(); i++ {
+		issue := list.Get(i).Message()
+		v := ValidationIssue{
+			Severity:    codeOf(messageField(issue, "severity")),
+			Code:        codeOf(messageField(issue, "code")),
+			Diagnostics: stringOf(messageField(issue, "diagnostics")),
+		}
+		// R4 reports FHIRPath in expression, STU3 in location
+		for _, name := range []protoreflect.Name{"expression", "location"} {
+			if fd := issue.Descriptor().Fields().ByName(name); fd != nil && issue.Get(fd).List().Len() > 0 {
+				v.Location = stringOf(issue.Get(fd).List().Get(0).Message())
+				break
+			}
+		}
+		if v.Diagnostics == "" {
+			v.Diagnostics = stringOf(messageField(messageField(issue, "details"), "text"))
+		}
+		issues = append(issues, v)
+	}
+	return issues
+}
+
+func messageField(m protoreflect.Message, name protoreflect.Name) protoreflect.Message {
+	if m == nil {
+		return nil
+	}
+	fd := m.Descriptor().Fields().ByName(name)
+	if fd == nil || fd.Message() == nil || !m.Has(fd) {
+		return nil
+	}
+	return m.Get(fd).Message()
+}
+
+func stringOf(m protoreflect.Message) string {
+	if m == nil {
+		return ""
+	}
+	if fd := m.Descriptor().Fields().ByName("value"); fd != nil && fd.Kind() == protoreflect.StringKind {
+		return m.Get(fd).String()
+	}
+	return ""
+}
+
+// codeOf returns the FHIR code of an enum based code, e.g. "code-invalid"
+func codeOf(m protoreflect.Message) string {
+	if m == nil {
+		return ""
+	}
+	fd := m.Descriptor().Fields().ByName("value")
+	if fd == nil || fd.Enum() == nil {
+		return ""
+	}
+	value := fd.Enum().Values().ByNumber(m.Get(fd).Enum())
+	if value == nil || value.Number() == 0 {
+		return ""
+	}
+	if original := proto.GetExtension(value.Options(), apb.E_FhirOriginalCode).(string); original != "" {
+		return original
+	}
+	return strings.ToLower(strings.ReplaceAll(string(value.Name()), "_", "-"))
+}
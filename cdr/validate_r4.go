@@ -0,0 +1,61 @@
+package cdr
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+
+	r4pb "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/resources/bundle_and_contained_resource_go_proto"
+)
+
+// Validate calls the $validate operation of the CDR for resource. The issues
+// reported by the CDR are returned, also when it rejects the resource with a
+// 422. Other non 20x responses also return their error
+func (o *OperationsR4Service) Validate(resource *r4pb.ContainedResource, options ...OptionFunc) (ValidationIssues, *Response, error) {
+	inner := unwrapContained(resource.ProtoReflect())
+	if inner == nil {
+		return nil, nil, ErrUnsupportedResource
+	}
+	resourceType := string(inner.Descriptor().Name())
+	resourceJSON, err := o.ma.Marshal(resource)
+	if err != nil {
+		return nil, nil, err
+	}
+	req, err := o.client.newCDRRequest(http.MethodPost, resourceType+"/$validate", resourceJSON, options)
+	if err != nil {
+		return nil, nil, err
+	}
+	req.Header.Set("Content-Type", fhirJSONR4)
+	req.Header.Set("Accept", fhirJSONR4)
+	var validateResponse bytes.Buffer
+	resp, doErr := o.client.do(req, &validateResponse)
+	if resp == nil {
+		if doErr == nil || doErr == io.EOF {
+			doErr = fmt.Errorf("OperationsR4Service.Validate: %w", ErrEmptyResult)
+		}
+		return nil, nil, doErr
+	}
+	if doErr != nil && doErr != io.EOF {
+		// The body of non 20x responses is not read by do
+		defer resp.Body.Close()
+		_, _ = io.Copy(&validateResponse, resp.Body)
+	}
+	unmarshalled, err := o.um.Unmarshal(validateResponse.Bytes())
+	if err != nil {
+		if doErr != nil && doErr != io.EOF {
+			return nil, resp, doErr
+		}
+		return nil, resp, fmt.Errorf("FHIR unmarshal: %w", err)
+	}
+	outcome := unmarshalled.(*r4pb.ContainedResource).GetOperationOutcome()
+	if outcome == nil {
+		return nil, resp, ErrUnexpectedResourceType
+	}
+	issues := issuesFromOutcome(outcome.ProtoReflect())
+	// A 422 reports a resource which failed validation, other errors are returned with the issues
+	if doErr != nil && doErr != io.EOF && resp.StatusCode != http.StatusUnprocessableEntity {
+		return issues, resp, doErr
+	}
+	return issues, resp, nil
+}
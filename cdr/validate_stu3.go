@@ -0,0 +1,60 @@
+package cdr
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+
+	stu3pb "github.com/google/fhir/go/proto/google/fhir/proto/stu3/resources_go_proto"
+)
+
+// Validate calls the $validate operation of the CDR for resource. The issues
+// reported by the CDR are returned, also when it rejects the resource with a
+// 422. Other non 20x responses also return their error
+func (o *OperationsSTU3Service) Validate(resource *stu3pb.ContainedResource, options ...OptionFunc) (ValidationIssues, *Response, error) {
+	resourceType := resourceTypeOf(resource)
+	if resourceType == "" {
+		return nil, nil, ErrUnsupportedResource
+	}
+	resourceJSON, err := o.ma.Marshal(resource)
+	if err != nil {
+		return nil, nil, err
+	}
+	req, err := o.client.newCDRRequest(http.MethodPost, resourceType+"/$validate", resourceJSON, options)
+	if err != nil {
+		return nil, nil, err
+	}
+	req.Header.Set("Content-Type", "application/fhir+json")
+	req.Header.Set("Accept", fhirJSONSTU3)
+	var validateResponse bytes.Buffer
+	resp, doErr := o.client.do(req, &validateResponse)
+	if resp == nil {
+		if doErr == nil || doErr == io.EOF {
+			doErr = fmt.Errorf("OperationsSTU3Service.Validate: %w", ErrEmptyResult)
+		}
+		return nil, nil, doErr
+	}
+	if doErr != nil && doErr != io.EOF {
+		// The body of non 20x responses is not read by do
+		defer resp.Body.Close()
+		_, _ = io.Copy(&validateResponse, resp.Body)
+	}
+	unmarshalled, err := o.um.Unmarshal(validateResponse.Bytes())
+	if err != nil {
+		if doErr != nil && doErr != io.EOF {
+			return nil, resp, doErr
+		}
+		return nil, resp, fmt.Errorf("FHIR unmarshal: %w", err)
+	}
+	outcome := unmarshalled.(*stu3pb.ContainedResource).GetOperationOutcome()
+	if outcome == nil {
+		return nil, resp, ErrUnexpectedResourceType
+	}
+	issues := issuesFromOutcome(outcome.ProtoReflect())
+	// A 422 reports a resource which failed validation, other errors are returned with the issues
+	if doErr != nil && doErr != io.EOF && resp.StatusCode != http.StatusUnprocessableEntity {
+		return issues, resp, doErr
+	}
+	return issues, resp, nil
+}
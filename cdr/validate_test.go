@@ -0,0 +1,197 @@
+package cdr_test
+
+import (
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/google/fhir/go/jsonformat"
+	r4dt "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/datatypes_go_proto"
+	r4pb "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/resources/bundle_and_contained_resource_go_proto"
+	r4patient "github.com/google/fhir/go/proto/google/fhir/proto/r4/core/resources/patient_go_proto"
+	"github.com/google/fhir/go/proto/google/fhir/proto/stu3/codes_go_proto"
+	stu3dt "github.com/google/fhir/go/proto/google/fhir/proto/stu3/datatypes_go_proto"
+	stu3pb "github.com/google/fhir/go/proto/google/fhir/proto/stu3/resources_go_proto"
+	"github.com/philips-software/go-hsdp-api/cdr"
+	"github.com/stretchr/testify/assert"
+)
+
+func locations(issues cdr.ValidationIssues) map[string]string {
+	found := make(map[string]string)
+	for _, i := range issues {
+		found[i.Location] = i.Code
+	}
+	return found
+}
+
+func TestValidateResourceSTU3(t *testing.T) {
+	patient := &stu3pb.Patient{
+		Id:     &stu3dt.Id{Value: "123"},
+		Active: &stu3dt.Boolean{Value: true},
+		Name: []*stu3dt.HumanName{
+			{Family: &stu3dt.String{Value: "Swanson"}, Given: []*stu3dt.String{{Value: "Ron"}}},
+		},
+		Gender: &codes_go_proto.AdministrativeGenderCode{Value: codes_go_proto.AdministrativeGenderCode_MALE},
+	}
+	issues := cdr.ValidateResource(patient)
+	assert.Len(t, issues, 0)
+	assert.False(t, issues.HasErrors())
+
+	patient.Id = &stu3dt.Id{Value: "not a valid id!"}
+	patient.Gender = &codes_go_proto.AdministrativeGenderCode{}
+	patient.Extension = []*stu3dt.Extension{{}}
+	issues = cdr.ValidateResource(&stu3pb.ContainedResource{
+		OneofResource: &stu3pb.ContainedResource_Patient{Patient: patient},
+	})
+	assert.True(t, issues.HasErrors())
+	found := locations(issues)
+	assert.Equal(t, "value", found["Patient.id"])
+	assert.Equal(t, "code-invalid", found["Patient.gender"])
+	assert.Equal(t, "required", found["Patient.extension[0].url"])
+	assert.Len(t, issues, 3)
+
+	// A data-absent-reason style extension may replace the code
+	patient.Id = nil
+	patient.Extension = nil
+	patient.Gender = &codes_go_proto.AdministrativeGenderCode{Extension: []*stu3dt.Extension{{
+		Url: &stu3dt.Uri{Value: "http://hl7.org/fhir/StructureDefinition/data-absent-reason"},
+		Value: &stu3dt.Extension_ValueX{Choice: &stu3dt.Extension_ValueX_Code{
+			Code: &stu3dt.Code{Value: "unknown"},
+		}},
+	}}}
+	assert.Len(t, cdr.ValidateResource(patient), 0)
+
+	issues = cdr.ValidateResource(&stu3pb.Observation{})
+	found = locations(issues)
+	assert.Equal(t, "required", found["Observation.status"])
+	assert.Equal(t, "required", found["Observation.code"])
+
+	bundle := &stu3pb.Bundle{
+		Type: &codes_go_proto.BundleTypeCode{Value: codes_go_proto.BundleTypeCode_COLLECTION},
+		Entry: []*stu3pb.Bundle_Entry{{
+			Resource: &stu3pb.ContainedResource{
+				OneofResource: &stu3pb.ContainedResource_Observation{Observation: &stu3pb.Observation{}},
+			},
+		}},
+	}
+	found = locations(cdr.ValidateResource(bundle))
+	assert.Equal(t, "required", found["Bundle.entry[0].resource.status"])
+
+	assert.True(t, cdr.ValidateResource(nil).HasErrors())
+	assert.True(t, cdr.ValidateResource(&stu3pb.ContainedResource{}).HasErrors())
+}
+
+func TestValidateResourceR4(t *testing.T) {
+	patient := &r4patient.Patient{
+		Id:        &r4dt.Id{Value: "123"},
+		BirthDate: &r4dt.Date{ValueUs: 0, Precision: r4dt.Date_DAY},
+		Deceased: &r4patient.Patient_DeceasedX{Choice: &r4patient.Patient_DeceasedX_Boolean{
+			Boolean: &r4dt.Boolean{Value: false},
+		}},
+		Gender: &r4patient.Patient_GenderCode{},
+	}
+	issues := cdr.ValidateResource(patient)
+	found := locations(issues)
+	assert.Equal(t, "code-invalid", found["Patient.gender"])
+	assert.Len(t, issues, 1)
+}
+
+func TestValidateOperation(t *testing.T) {
+	teardown := setup(t)
+	defer teardown()
+
+	muxCDR.HandleFunc("/store/fhir/"+cdrOrgID+"/Patient/$validate", func(w http.ResponseWriter, r *http.Request) {
+		if !assert.Equal(t, "POST", r.Method) {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		body, _ := ioutil.ReadAll(r.Body)
+		w.Header().Set("Content-Type", "application/fhir+json")
+		if r.Header.Get("X-Fail") != "" {
+			w.WriteHeader(http.StatusForbidden)
+			_, _ = io.WriteString(w, `{"resourceType":"OperationOutcome","issue":[
+				{"severity":"error","code":"forbidden","diagnostics":"Not allowed"}]}`)
+			return
+		}
+		if strings.Contains(r.Header.Get("Accept"), "fhirVersion=4.0") {
+			w.WriteHeader(http.StatusOK)
+			_, _ = io.WriteString(w, `{"resourceType":"OperationOutcome","issue":[
+				{"severity":"information","code":"informational","details":{"text":"All OK"}}]}`)
+			return
+		}
+		if strings.Contains(string(body), `"active"`) {
+			w.WriteHeader(http.StatusOK)
+			_, _ = io.WriteString(w, `{"resourceType":"OperationOutcome","issue":[
+				{"severity":"information","code":"informational","diagnostics":"All OK"}]}`)
+			return
+		}
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		_, _ = io.WriteString(w, `{"resourceType":"OperationOutcome","issue":[
+			{"severity":"error","code":"code-invalid","diagnostics":"Unknown gender","location":["Patient.gender"]},
+			{"severity":"warning","code":"business-rule","diagnostics":"No name","location":["Patient.name"]}]}`)
+	})
+
+	valid := &stu3pb.ContainedResource{OneofResource: &stu3pb.ContainedResource_Patient{Patient: &stu3pb.Patient{
+		Active: &stu3dt.Boolean{Value: true},
+	}}}
+	issues, resp, err := cdrClient.OperationsSTU3.Validate(valid)
+	if !assert.Nil(t, err) || !assert.NotNil(t, resp) || !assert.Len(t, issues, 1) {
+		return
+	}
+	assert.False(t, issues.HasErrors())
+	assert.Equal(t, "All OK", issues[0].Diagnostics)
+
+	invalid := &stu3pb.ContainedResource{OneofResource: &stu3pb.ContainedResource_Patient{Patient: &stu3pb.Patient{}}}
+	issues, resp, err = cdrClient.OperationsSTU3.Validate(invalid)
+	if !assert.Nil(t, err) || !assert.NotNil(t, resp) || !assert.Len(t, issues, 2) {
+		return
+	}
+	assert.Equal(t, http.StatusUnprocessableEntity, resp.StatusCode)
+	assert.True(t, issues.HasErrors())
+	assert.Equal(t, cdr.ValidationIssue{
+		Severity:    cdr.SeverityError,
+		Code:        "code-invalid",
+		Diagnostics: "Unknown gender",
+		Location:    "Patient.gender",
+	}, issues[0])
+	assert.Equal(t, cdr.SeverityWarning, issues[1].Severity)
+
+	_, _, err = cdrClient.OperationsSTU3.Validate(&stu3pb.ContainedResource{})
+	assert.Equal(t, cdr.ErrUnsupportedResource, err)
+
+	r4 := unmarshalR4(t, `{"resourceType":"Patient","active":true}`)
+	issues, _, err = cdrClient.OperationsR4.Validate(r4)
+	if !assert.Nil(t, err) || !assert.Len(t, issues, 1) {
+		return
+	}
+	assert.Equal(t, "All OK", issues[0].Diagnostics)
+	assert.Equal(t, cdr.SeverityInformation, issues[0].Severity)
+
+	// Errors other than a failed validation are not reported as validation issues only
+	fail := func(req *http.Request) error {
+		req.Header.Set("X-Fail", "true")
+		return nil
+	}
+	issues, resp, err = cdrClient.OperationsSTU3.Validate(valid, fail)
+	assert.ErrorIs(t, err, cdr.ErrNonHttp20xResponse)
+	if assert.NotNil(t, resp) && assert.Len(t, issues, 1) {
+		assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+		assert.Equal(t, "Not allowed", issues[0].Diagnostics)
+	}
+	_, _, err = cdrClient.OperationsR4.Validate(r4, fail)
+	assert.ErrorIs(t, err, cdr.ErrNonHttp20xResponse)
+}
+
+func unmarshalR4(t *testing.T, resource string) *r4pb.ContainedResource {
+	um, err := jsonformat.NewUnmarshaller("UTC", jsonformat.R4)
+	if !assert.Nil(t, err) {
+		t.FailNow()
+	}
+	unmarshalled, err := um.Unmarshal([]byte(resource))
+	if !assert.Nil(t, err) {
+		t.FailNow()
+	}
+	return unmarshalled.(*r4pb.ContainedResource)
+}
@@ -10,7 +10,8 @@ const (
 	applicationAPIVersion = "1"
 )
 
-// ApplicationsService implements actions on IAM Application entities
+// ApplicationsService implements actions on IAM Application entities.
+// IAM has no API to update or delete an Application
 type ApplicationsService struct {
 	client *Client
 }
@@ -19,6 +20,8 @@ type ApplicationsService struct {
 // can be used to look for entities
 type GetApplicationsOptions struct {
 	ID                *string `url:"_id,omitempty"`
+	Count             *int    `url:"_count,omitempty"`
+	Page              *int    `url:"_page,omitempty"`
 	PropositionID     *string `url:"propositionId,omitempty"`
 	GlobalReferenceID *string `url:"globalReferenceId,omitempty"`
 	Name              *string `url:"name,omitempty"`
@@ -44,9 +47,21 @@ func (a *ApplicationsService) GetApplicationByName(name string) (*Application, *
 
 // GetApplications search for an Applications entity based on the GetApplicationsOptions values
 func (a *ApplicationsService) GetApplications(opt *GetApplicationsOptions, options ...OptionFunc) ([]*Application, *Response, error) {
+	apps, total, resp, err := a.getApplicationsPage(opt, options...)
+	if err != nil {
+		return nil, resp, err
+	}
+	if total == 0 {
+		return nil, resp, ErrEmptyResults
+	}
+	return apps, resp, nil
+}
+
+// getApplicationsPage returns a page of applications and the total number of matching applications
+func (a *ApplicationsService) getApplicationsPage(opt *GetApplicationsOptions, options ...OptionFunc) ([]*Application, int, *Response, error) {
 	req, err := a.client.newRequest(IDM, "GET", "authorize/identity/Application", opt, options)
 	if err != nil {
-		return nil, nil, err
+		return nil, 0, nil, err
 	}
 	req.Header.Set("api-version", applicationAPIVersion)
 	req.Header.Set("Content-Type", "application/json")
@@ -58,13 +73,39 @@ func (a *ApplicationsService) GetApplications(opt *GetApplicationsOptions, optio
 
 	resp, err := a.client.do(req, &bundleResponse)
 	if err != nil {
-		return nil, resp, err
-	}
-	if bundleResponse.Total == 0 {
-		return nil, resp, ErrEmptyResults
+		return nil, 0, resp, err
 	}
+	return bundleResponse.Entry, bundleResponse.Total, resp, nil
+}
 
-	return bundleResponse.Entry, resp, nil
+// GetAllApplications retrieves all applications matching GetApplicationsOptions, following pages
+// until the total reported by IAM has been retrieved. No matches is not an error
+func (a *ApplicationsService) GetAllApplications(opt *GetApplicationsOptions, options ...OptionFunc) ([]*Application, *Response, error) {
+	var apps []*Application
+	var query GetApplicationsOptions
+	if opt != nil {
+		query = *opt
+	}
+	page := 1
+	if query.Page != nil {
+		page = *query.Page
+	}
+	for {
+		query.Page = &page
+		list, total, resp, err := a.getApplicationsPage(&query, options...)
+		if err != nil {
+			return apps, resp, err
+		}
+		// IAM may return fewer applications per page than requested
+		if len(list) == 0 {
+			return apps, resp, nil
+		}
+		apps = append(apps, list...)
+		if len(apps) >= total {
+			return apps, resp, nil
+		}
+		page++
+	}
 }
 
 // CreateApplication creates a Application
@@ -205,6 +205,41 @@ func (c *Client) WithLogin(username, password string) (*Client, error) {
 	return client, err
 }
 
+// WithDeviceLogin returns a cloned client with new device login
+func (c *Client) WithDeviceLogin(loginID, password string) (*Client, error) {
+	client, err := NewClient(c.client, c.config)
+	if err != nil {
+		return nil, err
+	}
+	err = client.DeviceLogin(loginID, password)
+	return client, err
+}
+
+// DeviceCredentials holds the login credentials of a device
+type DeviceCredentials struct {
+	LoginID  string
+	Password string
+}
+
+// DeviceLoginResult holds the outcome of a device login
+type DeviceLoginResult struct {
+	LoginID string
+	Client  *Client
+	Err     error
+}
+
+// DeviceLogins logs in each of the given devices using a cloned client per device.
+// Results are returned in the same order as the credentials. At most concurrency
+// logins are performed in parallel.
+func (c *Client) DeviceLogins(credentials []DeviceCredentials, concurrency int) []DeviceLoginResult {
+	results := make([]DeviceLoginResult, len(credentials))
+	forEachDevice(len(credentials), concurrency, func(i int) {
+		results[i].LoginID = credentials[i].LoginID
+		results[i].Client, results[i].Err = c.WithDeviceLogin(credentials[i].LoginID, credentials[i].Password)
+	})
+	return results
+}
+
 func (c *Client) accessTokenEndpoint() string {
 	return c.baseIAMURL.String() + "oauth2/access_token"
 }
@@ -398,6 +398,75 @@ func TestWithLogin(t *testing.T) {
 	assert.NotEqual(t, client, newClient)
 }
 
+func TestDeviceLogin(t *testing.T) {
+	muxIAM = http.NewServeMux()
+	serverIAM = httptest.NewServer(muxIAM)
+	muxIDM = http.NewServeMux()
+	serverIDM = httptest.NewServer(muxIDM)
+
+	defer serverIAM.Close()
+	defer serverIDM.Close()
+
+	deviceClient, err := NewClient(nil, &Config{
+		OAuth2ClientID: "TestClient",
+		OAuth2Secret:   "Secret",
+		IAMURL:         serverIAM.URL,
+		IDMURL:         serverIDM.URL,
+	})
+	if !assert.Nil(t, err) {
+		return
+	}
+	deviceToken := "66d20214-7879-4e35-923d-f9d4e01c9746"
+
+	muxIAM.HandleFunc("/authorize/oauth2/token", func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseForm(); !assert.Nil(t, err) {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		if !assert.Equal(t, "device", r.Form.Get("grant_type")) ||
+			!assert.Equal(t, "device-login", r.Form.Get("username")) ||
+			!assert.Equal(t, "device-password", r.Form.Get("password")) {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		_, _ = io.WriteString(w, `{
+			"access_token": "`+deviceToken+`",
+			"expires_in": 1799,
+			"token_type": "Bearer"
+		}`)
+	})
+
+	err = deviceClient.DeviceLogin("device-login", "device-password")
+	if assert.Nil(t, err) {
+		assert.Equal(t, deviceToken, deviceClient.Token())
+	}
+}
+
+func TestDeviceLogins(t *testing.T) {
+	teardown := setup(t)
+	defer teardown()
+
+	results := client.DeviceLogins([]DeviceCredentials{
+		{LoginID: "username", Password: "password"},
+		{LoginID: "username2", Password: "password"},
+	}, 2)
+	if !assert.Len(t, results, 2) {
+		return
+	}
+	for _, r := range results {
+		if !assert.Nil(t, r.Err) {
+			return
+		}
+		assert.NotNil(t, r.Client)
+	}
+	assert.Equal(t, "username", results[0].LoginID)
+	assert.Equal(t, token, results[0].Client.Token())
+	assert.Equal(t, "username2", results[1].LoginID)
+	assert.NotEqual(t, token, results[1].Client.Token())
+}
+
 func TestDebug(t *testing.T) {
 	teardown := setup(t)
 	defer teardown()
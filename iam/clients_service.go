@@ -50,6 +50,8 @@ type ClientsService struct {
 // GetClientsOptions describes search criteria for looking up roles
 type GetClientsOptions struct {
 	ID                *string `url:"_id,omitempty"`
+	Count             *int    `url:"_count,omitempty"`
+	Page              *int    `url:"_page,omitempty"`
 	Name              *string `url:"name,omitempty"`
 	GlobalReferenceID *string `url:"globalReferenceId,omitempty"`
 	ApplicationID     *string `url:"applicationId,omitempty"`
@@ -130,9 +132,15 @@ func (c *ClientsService) GetClientByID(id string) (*ApplicationClient, *Response
 
 // GetClients looks up clients based on GetClientsOptions
 func (c *ClientsService) GetClients(opt *GetClientsOptions, options ...OptionFunc) (*[]ApplicationClient, *Response, error) {
+	list, _, resp, err := c.getClientsPage(opt, options...)
+	return list, resp, err
+}
+
+// getClientsPage returns a page of clients and the total number of matching clients
+func (c *ClientsService) getClientsPage(opt *GetClientsOptions, options ...OptionFunc) (*[]ApplicationClient, int, *Response, error) {
 	req, err := c.client.newRequest(IDM, "GET", "authorize/identity/Client", opt, options)
 	if err != nil {
-		return nil, nil, err
+		return nil, 0, nil, err
 	}
 	req.Header.Set("api-version", clientAPIVersion)
 	req.Header.Set("Content-Type", "application/json")
@@ -144,9 +152,39 @@ func (c *ClientsService) GetClients(opt *GetClientsOptions, options ...OptionFun
 
 	resp, err := c.client.do(req, &bundleResponse)
 	if err != nil {
-		return nil, resp, err
+		return nil, 0, resp, err
+	}
+	return &bundleResponse.Entry, bundleResponse.Total, resp, err
+}
+
+// GetAllClients retrieves all clients matching GetClientsOptions, following pages
+// until the total reported by IAM has been retrieved
+func (c *ClientsService) GetAllClients(opt *GetClientsOptions, options ...OptionFunc) ([]ApplicationClient, *Response, error) {
+	var clients []ApplicationClient
+	var query GetClientsOptions
+	if opt != nil {
+		query = *opt
+	}
+	page := 1
+	if query.Page != nil {
+		page = *query.Page
+	}
+	for {
+		query.Page = &page
+		list, total, resp, err := c.getClientsPage(&query, options...)
+		if err != nil {
+			return clients, resp, err
+		}
+		// IAM may return fewer clients per page than requested
+		if list == nil || len(*list) == 0 {
+			return clients, resp, nil
+		}
+		clients = append(clients, *list...)
+		if len(clients) >= total {
+			return clients, resp, nil
+		}
+		page++
 	}
-	return &bundleResponse.Entry, resp, err
 }
 
 // UpdateScope updates a clients scope
@@ -195,3 +233,83 @@ func (c *ClientsService) UpdateClient(ac ApplicationClient) (*ApplicationClient,
 	}
 	return &updatedClient, resp, nil
 }
+
+// DiffScopes returns the changes needed to bring the scopes of the client to the desired state
+func (c *ClientsService) DiffScopes(ac ApplicationClient, scopes []string, defaultScopes []string) (*ScopeDiff, *Response, error) {
+	current, resp, err := c.GetClientByID(ac.ID)
+	if err != nil {
+		return nil, resp, err
+	}
+	diff := DiffScopes(current.Scopes, current.DefaultScopes, scopes, defaultScopes)
+	return &diff, resp, nil
+}
+
+// SetScopes brings the scopes of the client to the desired state.
+// No update is done when the scopes already match. The applied diff is returned.
+func (c *ClientsService) SetScopes(ac ApplicationClient, scopes []string, defaultScopes []string) (*ScopeDiff, *Response, error) {
+	diff, resp, err := c.DiffScopes(ac, scopes, defaultScopes)
+	if err != nil {
+		return nil, resp, err
+	}
+	if diff.Empty() {
+		return diff, resp, nil
+	}
+	_, resp, err = c.UpdateScopes(ac, scopes, defaultScopes)
+	if err != nil {
+		return nil, resp, err
+	}
+	return diff, resp, nil
+}
+
+// ClientReport describes a client of an application in a proposition
+type ClientReport struct {
+	ApplicationID   string
+	ApplicationName string
+	ID              string
+	ClientID        string
+	Name            string
+	Type            string
+	// ResponseTypes are the OAuth2 and OpenID Connect response types the client
+	// may request at the authorize endpoint, e.g. "code id_token"
+	ResponseTypes   []string
+	RedirectionURIs []string
+	Scopes          []string
+	DefaultScopes   []string
+	Disabled        bool
+}
+
+// GetClientsReport lists all clients of all applications in the given proposition.
+// All pages of applications and clients are retrieved
+func (c *ClientsService) GetClientsReport(propositionID string, options ...OptionFunc) ([]ClientReport, *Response, error) {
+	apps, resp, err := c.client.Applications.GetAllApplications(&GetApplicationsOptions{
+		PropositionID: &propositionID,
+	}, options...)
+	if err != nil {
+		return nil, resp, err
+	}
+	report := []ClientReport{}
+	for _, app := range apps {
+		clients, resp, err := c.GetAllClients(&GetClientsOptions{
+			ApplicationID: &app.ID,
+		}, options...)
+		if err != nil {
+			return nil, resp, fmt.Errorf("GetClientsReport: GetAllClients: %w", err)
+		}
+		for _, ac := range clients {
+			report = append(report, ClientReport{
+				ApplicationID:   app.ID,
+				ApplicationName: app.Name,
+				ID:              ac.ID,
+				ClientID:        ac.ClientID,
+				Name:            ac.Name,
+				Type:            ac.Type,
+				ResponseTypes:   ac.ResponseTypes,
+				RedirectionURIs: ac.RedirectionURIs,
+				Scopes:          ac.Scopes,
+				DefaultScopes:   ac.DefaultScopes,
+				Disabled:        ac.Disabled,
+			})
+		}
+	}
+	return report, resp, nil
+}
@@ -1,6 +1,7 @@
 package iam
 
 import (
+	"encoding/json"
 	"io"
 	"net/http"
 	"testing"
@@ -171,3 +172,144 @@ func TestPasswordValidation(t *testing.T) {
 	err = validate.Struct(c)
 	assert.Nil(t, err)
 }
+
+func clientsHandler(t *testing.T, applicationID, clientID string) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !assert.Equal(t, "GET", r.Method) {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		_, _ = io.WriteString(w, `{
+			"total": 1,
+			"entry": [
+				{
+					"clientId": "TestClient",
+					"type": "Public",
+					"name": "TestClient",
+					"redirectionURIs": [
+						"https://something/OAuth2/something"
+					],
+					"applicationId": "`+applicationID+`",
+					"responseTypes": [
+						"code id_token"
+					],
+					"globalReferenceId": "c3fe79e6-13c2-48c1-adfa-826a01d4b31c",
+					"defaultScopes": [
+						"cn"
+					],
+					"scopes": [
+						"mail",
+						"sn",
+						"cn"
+					],
+					"id": "`+clientID+`"
+				}
+			]
+		}`)
+	}
+}
+
+func TestClientSecretAndScopes(t *testing.T) {
+	teardown := setup(t)
+	defer teardown()
+
+	applicationID := "f5fe538f-c3b5-4454-8774-cd3789f59b9f"
+	clientID := "4b7ad5b5-bbd2-4cd8-9a7c-30e0e9b8c1b4"
+	var putScopes []string
+	muxIDM.HandleFunc("/authorize/identity/Client", clientsHandler(t, applicationID, clientID))
+	muxIDM.HandleFunc("/authorize/identity/Client/"+clientID+"/$scopes", func(w http.ResponseWriter, r *http.Request) {
+		if !assert.Equal(t, "PUT", r.Method) {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		var body struct {
+			Scopes []string `json:"scopes"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		putScopes = body.Scopes
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	ac := ApplicationClient{ID: clientID}
+
+	diff, _, err := client.Clients.SetScopes(ac, []string{"cn", "mail", "sn"}, []string{"cn"})
+	if !assert.Nil(t, err) {
+		return
+	}
+	assert.True(t, diff.Empty())
+	assert.Nil(t, putScopes)
+
+	diff, _, err = client.Clients.SetScopes(ac, []string{"cn", "mail"}, []string{"cn"})
+	if !assert.Nil(t, err) {
+		return
+	}
+	assert.Equal(t, []string{"sn"}, diff.RemoveScopes)
+	assert.Equal(t, []string{"cn", "mail"}, putScopes)
+}
+
+func TestGetClientsReport(t *testing.T) {
+	teardown := setup(t)
+	defer teardown()
+
+	propositionID := "dae89cf0-888d-4a26-8c1d-578e97365efc"
+	applicationID := "f5fe538f-c3b5-4454-8774-cd3789f59b9f"
+	clientID := "4b7ad5b5-bbd2-4cd8-9a7c-30e0e9b8c1b4"
+	muxIDM.HandleFunc("/authorize/identity/Application", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, propositionID, r.URL.Query().Get("propositionId"))
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		// The second page holds an application without clients
+		if r.URL.Query().Get("_page") == "2" {
+			_, _ = io.WriteString(w, `{
+				"total": 2,
+				"entry": [
+					{
+						"id": "empty-app",
+						"name": "EmptyApp",
+						"propositionId": "`+propositionID+`",
+						"globalReferenceId": "empty-ref"
+					}
+				]
+			}`)
+			return
+		}
+		_, _ = io.WriteString(w, `{
+			"total": 2,
+			"entry": [
+				{
+					"id": "`+applicationID+`",
+					"name": "TestApp",
+					"propositionId": "`+propositionID+`",
+					"globalReferenceId": "app-ref"
+				}
+			]
+		}`)
+	})
+	var applications []string
+	muxIDM.HandleFunc("/authorize/identity/Client", func(w http.ResponseWriter, r *http.Request) {
+		applications = append(applications, r.URL.Query().Get("applicationId"))
+		if r.URL.Query().Get("applicationId") != applicationID {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusOK)
+			_, _ = io.WriteString(w, `{"total": 0, "entry": []}`)
+			return
+		}
+		clientsHandler(t, applicationID, clientID)(w, r)
+	})
+
+	report, resp, err := client.Clients.GetClientsReport(propositionID)
+	if !assert.Nil(t, err) {
+		return
+	}
+	assert.NotNil(t, resp)
+	assert.Equal(t, []string{applicationID, "empty-app"}, applications)
+	if !assert.Len(t, report, 1) {
+		return
+	}
+	assert.Equal(t, "TestApp", report[0].ApplicationName)
+	assert.Equal(t, clientID, report[0].ID)
+	assert.Equal(t, []string{"code id_token"}, report[0].ResponseTypes)
+	assert.Equal(t, []string{"https://something/OAuth2/something"}, report[0].RedirectionURIs)
+}
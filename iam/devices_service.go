@@ -2,8 +2,11 @@ package iam
 
 import (
 	"bytes"
+	"crypto/rand"
 	"fmt"
+	"math/big"
 	"net/http"
+	"sync"
 	"time"
 
 	"github.com/go-playground/validator/v10"
@@ -75,9 +78,15 @@ type DevicesService struct {
 // GetDevices looks up Devices based on GetDevicesOptions
 // A user with DEVICE.READ permission can read device information under the user organization.
 func (p *DevicesService) GetDevices(opt *GetDevicesOptions, options ...OptionFunc) (*[]Device, *Response, error) {
+	devices, _, resp, err := p.getDevicesPage(opt, options...)
+	return devices, resp, err
+}
+
+// getDevicesPage retrieves a page of devices and the total number of matching devices
+func (p *DevicesService) getDevicesPage(opt *GetDevicesOptions, options ...OptionFunc) (*[]Device, int, *Response, error) {
 	req, err := p.client.newRequest(IDM, "GET", "authorize/identity/Device", opt, options)
 	if err != nil {
-		return nil, nil, err
+		return nil, 0, nil, err
 	}
 	req.Header.Set("api-version", servicesAPIVersion)
 	req.Header.Set("Content-Type", "application/json")
@@ -89,9 +98,9 @@ func (p *DevicesService) GetDevices(opt *GetDevicesOptions, options ...OptionFun
 
 	resp, err := p.client.do(req, &bundleResponse)
 	if err != nil {
-		return nil, resp, err
+		return nil, 0, resp, err
 	}
-	return &bundleResponse.Entry, resp, err
+	return &bundleResponse.Entry, bundleResponse.Total, resp, err
 }
 
 // GetDeviceByID retrieves a device by ID
@@ -137,7 +146,12 @@ func (p *DevicesService) CreateDevice(device Device) (*Device, *Response, error)
 // The entire resource data must be passed as request body to update a device.
 // If read-only attributes (such as id, loginId, password, meta, organizationId) are passed, that will be ignored.
 func (p *DevicesService) UpdateDevice(device Device) (*Device, *Response, error) {
-	req, err := p.client.newRequest(IDM, "PUT", "authorize/identity/Device/"+device.ID, &device, nil)
+	return p.updateDevice(device.ID, &device)
+}
+
+// updateDevice replaces the device with the given ID by body
+func (p *DevicesService) updateDevice(deviceID string, body interface{}) (*Device, *Response, error) {
+	req, err := p.client.newRequest(IDM, "PUT", "authorize/identity/Device/"+deviceID, body, nil)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -204,3 +218,225 @@ func (p *DevicesService) deviceActionV(deviceID string, body interface{}, action
 	}
 	return true, resp, nil
 }
+
+const (
+	devicePasswordLength     = 24
+	devicePasswordSpecials   = "!@#$%*-_=+"
+	devicePasswordAlphabet   = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789" + devicePasswordSpecials
+	defaultDevicePageSize    = 100
+	defaultDeviceConcurrency = 10
+)
+
+// DeviceResult holds the outcome of a bulk operation for a single device
+type DeviceResult struct {
+	Device Device
+	// Password is only set by operations which generate a new password.
+	// It is not stored anywhere so this is the only opportunity to capture it.
+	Password string
+	Err      error
+}
+
+// GenerateDevicePassword returns a random password containing lower case,
+// upper case, numeric and special characters
+func GenerateDevicePassword() (string, error) {
+	return generatePassword(devicePasswordLength)
+}
+
+func generatePassword(length int) (string, error) {
+	classes := []string{
+		"abcdefghijklmnopqrstuvwxyz",
+		"ABCDEFGHIJKLMNOPQRSTUVWXYZ",
+		"0123456789",
+		devicePasswordSpecials,
+	}
+	password := make([]byte, length)
+	for i := range password {
+		alphabet := devicePasswordAlphabet
+		if i < len(classes) { // Guarantee one character of each class
+			alphabet = classes[i]
+		}
+		c, err := randomChar(alphabet)
+		if err != nil {
+			return "", err
+		}
+		password[i] = c
+	}
+	// Shuffle so the guaranteed characters are not always in front
+	for i := len(password) - 1; i > 0; i-- {
+		j, err := rand.Int(rand.Reader, big.NewInt(int64(i+1)))
+		if err != nil {
+			return "", err
+		}
+		password[i], password[j.Int64()] = password[j.Int64()], password[i]
+	}
+	return string(password), nil
+}
+
+func randomChar(alphabet string) (byte, error) {
+	n, err := rand.Int(rand.Reader, big.NewInt(int64(len(alphabet))))
+	if err != nil {
+		return 0, err
+	}
+	return alphabet[n.Int64()], nil
+}
+
+// forEachDevice calls fn for the indices 0..n-1 using at most concurrency goroutines
+func forEachDevice(n, concurrency int, fn func(i int)) {
+	if concurrency <= 0 {
+		concurrency = defaultDeviceConcurrency
+	}
+	var wg sync.WaitGroup
+	work := make(chan int)
+	for w := 0; w < concurrency && w < n; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range work {
+				fn(i)
+			}
+		}()
+	}
+	for i := 0; i < n; i++ {
+		work <- i
+	}
+	close(work)
+	wg.Wait()
+}
+
+// GetAllDevices retrieves all devices matching GetDevicesOptions, following pages
+// until the total reported by IAM has been retrieved
+func (p *DevicesService) GetAllDevices(opt *GetDevicesOptions, options ...OptionFunc) ([]Device, *Response, error) {
+	var devices []Device
+	var query GetDevicesOptions
+	if opt != nil {
+		query = *opt
+	}
+	count := defaultDevicePageSize
+	if query.Count != nil {
+		count = *query.Count
+	}
+	page := 1
+	if query.Page != nil {
+		page = *query.Page
+	}
+	query.Count = &count
+	for {
+		query.Page = &page
+		list, total, resp, err := p.getDevicesPage(&query, options...)
+		if err != nil {
+			return devices, resp, err
+		}
+		// IAM may return fewer devices per page than requested
+		if list == nil || len(*list) == 0 {
+			return devices, resp, nil
+		}
+		devices = append(devices, *list...)
+		if len(devices) >= total {
+			return devices, resp, nil
+		}
+		page++
+	}
+}
+
+// RegisterDevices creates the given devices. Devices without a password are
+// assigned a generated one, which is returned in the DeviceResult.
+// Results are returned in the same order as the input. At most concurrency
+// devices are registered in parallel.
+func (p *DevicesService) RegisterDevices(devices []Device, concurrency int) []DeviceResult {
+	results := make([]DeviceResult, len(devices))
+	forEachDevice(len(devices), concurrency, func(i int) {
+		device := devices[i]
+		result := &results[i]
+		result.Device = device
+		if device.Password == "" {
+			password, err := GenerateDevicePassword()
+			if err != nil {
+				result.Err = err
+				return
+			}
+			device.Password = password
+			result.Password = password
+		}
+		created, _, err := p.CreateDevice(device)
+		if err != nil {
+			result.Err = err
+			return
+		}
+		if created == nil {
+			result.Err = ErrCouldNoReadResourceAfterCreate
+			return
+		}
+		result.Device = *created
+	})
+	return results
+}
+
+// RotatePasswords changes the password of all devices matching GetDevicesOptions
+// to a newly generated one. The current password of each device is retrieved through
+// the currentPassword callback. The new passwords are returned in the DeviceResult list.
+func (p *DevicesService) RotatePasswords(opt *GetDevicesOptions, currentPassword func(device Device) (string, error), concurrency int, options ...OptionFunc) ([]DeviceResult, *Response, error) {
+	devices, resp, err := p.GetAllDevices(opt, options...)
+	if err != nil {
+		return nil, resp, err
+	}
+	results := make([]DeviceResult, len(devices))
+	forEachDevice(len(devices), concurrency, func(i int) {
+		result := &results[i]
+		result.Device = devices[i]
+		oldPassword, err := currentPassword(devices[i])
+		if err != nil {
+			result.Err = err
+			return
+		}
+		newPassword, err := GenerateDevicePassword()
+		if err != nil {
+			result.Err = err
+			return
+		}
+		ok, _, err := p.ChangePassword(devices[i].ID, oldPassword, newPassword)
+		if err == nil && !ok {
+			err = ErrOperationFailed
+		}
+		if err != nil {
+			result.Err = err
+			return
+		}
+		result.Password = newPassword
+	})
+	return results, resp, nil
+}
+
+// RotateGroupPasswords changes the password of all devices in the given group. See RotatePasswords.
+func (p *DevicesService) RotateGroupPasswords(groupID string, currentPassword func(device Device) (string, error), concurrency int, options ...OptionFunc) ([]DeviceResult, *Response, error) {
+	return p.RotatePasswords(&GetDevicesOptions{GroupID: &groupID}, currentPassword, concurrency, options...)
+}
+
+// SetActiveDevices activates or deactivates all devices matching GetDevicesOptions
+func (p *DevicesService) SetActiveDevices(opt *GetDevicesOptions, active bool, concurrency int, options ...OptionFunc) ([]DeviceResult, *Response, error) {
+	devices, resp, err := p.GetAllDevices(opt, options...)
+	if err != nil {
+		return nil, resp, err
+	}
+	results := make([]DeviceResult, len(devices))
+	forEachDevice(len(devices), concurrency, func(i int) {
+		result := &results[i]
+		result.Device = devices[i]
+		if devices[i].IsActive == active {
+			return
+		}
+		// isActive is omitted from the Device JSON when false so shadow it
+		updated, _, err := p.updateDevice(devices[i].ID, struct {
+			Device
+			IsActive bool `json:"isActive"`
+		}{
+			Device:   devices[i],
+			IsActive: active,
+		})
+		if err != nil {
+			result.Err = err
+			return
+		}
+		result.Device = *updated
+	})
+	return results, resp, nil
+}
@@ -1,8 +1,12 @@
 package iam
 
 import (
+	"encoding/json"
 	"io"
 	"net/http"
+	"strconv"
+	"strings"
+	"sync"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -189,3 +193,177 @@ func TestValidationDevices(t *testing.T) {
 		return
 	}
 }
+
+func TestGenerateDevicePassword(t *testing.T) {
+	password, err := GenerateDevicePassword()
+	if !assert.Nil(t, err) {
+		return
+	}
+	assert.Len(t, password, devicePasswordLength)
+	assert.Regexp(t, "[a-z]", password)
+	assert.Regexp(t, "[A-Z]", password)
+	assert.Regexp(t, "[0-9]", password)
+	assert.Regexp(t, "[!@#$%*\\-_=+]", password)
+}
+
+func TestDevicesBulk(t *testing.T) {
+	teardown := setup(t)
+	defer teardown()
+
+	managingOrgID := "f5fe538f-c3b5-4454-8774-cd3789f59b9a"
+	groupID := "1eec7b01-1417-4546-9c5e-088dea0a9e8b"
+	deviceID := "dbf1d779-ab9f-4c27-b4aa-ea75f9efbbc1"
+	deviceJSON := `{
+      "loginId": "andydevice",
+      "organizationId": "` + managingOrgID + `",
+      "applicationId": "711171ab-d28c-4616-a314-f95584e280c3",
+      "deviceExtId": {
+        "type": {
+          "code": "ID"
+        },
+        "system": "http://www.philips.co.id/c-m-ho/fake/fakedevice",
+        "value": "0001"
+      },
+      "type": "Device",
+      "isActive": true,
+      "globalReferenceId": "c157bd2e-e992-4b5e-88ab-911766b7b8f4",
+      "id": "` + deviceID + `"
+    }`
+	var passwords []string
+	var activeUpdates []bool
+	var mu sync.Mutex
+
+	muxIDM.HandleFunc("/authorize/identity/Device", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.Method {
+		case "POST":
+			var d Device
+			_ = json.NewDecoder(r.Body).Decode(&d)
+			mu.Lock()
+			passwords = append(passwords, d.Password)
+			mu.Unlock()
+			w.Header().Set("Location", "/authorize/identity/Device/"+deviceID)
+			w.WriteHeader(http.StatusCreated)
+		case "GET":
+			if r.URL.Query().Get("groupId") != "" {
+				assert.Equal(t, "1", r.URL.Query().Get("_page"))
+				assert.Equal(t, "100", r.URL.Query().Get("_count"))
+			}
+			w.WriteHeader(http.StatusOK)
+			_, _ = io.WriteString(w, `{ "total": 1, "entry": [`+deviceJSON+`] }`)
+		}
+	})
+	muxIDM.HandleFunc("/authorize/identity/Device/"+deviceID, func(w http.ResponseWriter, r *http.Request) {
+		if !assert.Equal(t, "PUT", r.Method) {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		var body map[string]interface{}
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		active, _ := body["isActive"].(bool)
+		mu.Lock()
+		activeUpdates = append(activeUpdates, active)
+		mu.Unlock()
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		_, _ = io.WriteString(w, deviceJSON)
+	})
+	muxIDM.HandleFunc("/authorize/identity/Device/"+deviceID+"/$change-password", func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			OldPassword string `json:"oldPassword"`
+			NewPassword string `json:"newPassword"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		if body.OldPassword != "OldPassw0rd!" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	d := Device{
+		LoginID:           "andydevice",
+		GlobalReferenceID: "c157bd2e-e992-4b5e-88ab-911766b7b8f4",
+		ApplicationID:     "711171ab-d28c-4616-a314-f95584e280c3",
+		OrganizationID:    managingOrgID,
+		Type:              "Device",
+		DeviceExtID: DeviceIdentifier{
+			Type: CodeableConcept{
+				Code: "ID",
+			},
+			System: "http://www.philips.co.id/c-m-ho/fake/fakedevice",
+			Value:  "0001",
+		},
+	}
+	withPassword := d
+	withPassword.Password = "SecretPasw0rd!"
+
+	results := client.Devices.RegisterDevices([]Device{d, withPassword}, 2)
+	if !assert.Len(t, results, 2) {
+		return
+	}
+	assert.Nil(t, results[0].Err)
+	assert.Nil(t, results[1].Err)
+	assert.Equal(t, deviceID, results[0].Device.ID)
+	assert.Len(t, results[0].Password, devicePasswordLength)
+	assert.Equal(t, "", results[1].Password)
+	assert.Contains(t, passwords, results[0].Password)
+	assert.Contains(t, passwords, "SecretPasw0rd!")
+
+	results, resp, err := client.Devices.RotateGroupPasswords(groupID, func(device Device) (string, error) {
+		return "OldPassw0rd!", nil
+	}, 1)
+	if !assert.Nil(t, err) {
+		return
+	}
+	assert.NotNil(t, resp)
+	if assert.Len(t, results, 1) {
+		assert.Nil(t, results[0].Err)
+		assert.Len(t, results[0].Password, devicePasswordLength)
+	}
+
+	results, _, err = client.Devices.RotatePasswords(&GetDevicesOptions{GroupID: &groupID}, func(device Device) (string, error) {
+		return "WrongPassw0rd!", nil
+	}, 1)
+	if !assert.Nil(t, err) {
+		return
+	}
+	if assert.Len(t, results, 1) {
+		assert.NotNil(t, results[0].Err)
+		assert.Equal(t, "", results[0].Password)
+	}
+
+	results, _, err = client.Devices.SetActiveDevices(&GetDevicesOptions{GroupID: &groupID}, false, 1)
+	if !assert.Nil(t, err) {
+		return
+	}
+	if assert.Len(t, results, 1) {
+		assert.Nil(t, results[0].Err)
+	}
+	assert.Equal(t, []bool{false}, activeUpdates)
+}
+
+func TestGetAllDevicesPaging(t *testing.T) {
+	teardown := setup(t)
+	defer teardown()
+
+	// The server caps pages at two devices regardless of _count
+	muxIDM.HandleFunc("/authorize/identity/Device", func(w http.ResponseWriter, r *http.Request) {
+		page, _ := strconv.Atoi(r.URL.Query().Get("_page"))
+		var entries []string
+		for i := (page - 1) * 2; i < page*2 && i < 5; i++ {
+			entries = append(entries, `{"id": "device-`+strconv.Itoa(i)+`"}`)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		_, _ = io.WriteString(w, `{ "total": 5, "entry": [`+strings.Join(entries, ",")+`] }`)
+	})
+
+	devices, _, err := client.Devices.GetAllDevices(nil)
+	if !assert.Nil(t, err) {
+		return
+	}
+	if assert.Len(t, devices, 5) {
+		assert.Equal(t, "device-4", devices[4].ID)
+	}
+}
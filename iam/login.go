@@ -61,6 +61,16 @@ func (c *Client) ServiceLogin(service Service) error {
 
 // Login logs in a user with `username` and `password`
 func (c *Client) Login(username, password string) error {
+	return c.passwordLogin("password", username, password)
+}
+
+// DeviceLogin logs in a device with `loginID` and `password` using the device grant
+func (c *Client) DeviceLogin(loginID, password string) error {
+	return c.passwordLogin("device", loginID, password)
+}
+
+// passwordLogin requests a token for a username and password using grantType
+func (c *Client) passwordLogin(grantType, username, password string) error {
 	req, err := c.newRequest(IAM, "POST", "authorize/oauth2/token", nil, nil)
 	if err != nil {
 		return err
@@ -68,7 +78,7 @@ func (c *Client) Login(username, password string) error {
 	form := url.Values{}
 	form.Add("username", username)
 	form.Add("password", password)
-	form.Add("grant_type", "password")
+	form.Add("grant_type", grantType)
 	if len(c.config.Scopes) > 0 {
 		scopes := strings.Join(c.config.Scopes, " ")
 		form.Add("scope", scopes)
@@ -2,7 +2,9 @@ package iam
 
 import (
 	"bytes"
+	"fmt"
 	"net/http"
+	"strings"
 
 	validator "github.com/go-playground/validator/v10"
 )
@@ -100,3 +102,166 @@ func (p *MFAPoliciesService) DeleteMFAPolicy(policy MFAPolicy) (bool, *Response,
 	}
 	return true, resp, err
 }
+
+// GetMFAPolicyOptions describes the criteria for looking up MFA policies
+type GetMFAPolicyOptions struct {
+	Filter       *string `url:"filter,omitempty"`
+	ResourceType *string `url:"-"`
+	ResourceID   *string `url:"-"`
+	StartIndex   *int    `url:"startIndex,omitempty"`
+	Count        *int    `url:"count,omitempty"`
+}
+
+// MFAPolicyList holds a paginated list of MFA policies
+type MFAPolicyList struct {
+	Policies     []MFAPolicy
+	TotalResults int
+	StartIndex   int
+	ItemsPerPage int
+	HasNextPage  bool
+}
+
+// filter combines the explicit filter with the resource type and ID criteria
+func (o *GetMFAPolicyOptions) filter() *string {
+	var clauses []string
+	if o.Filter != nil && *o.Filter != "" {
+		clauses = append(clauses, *o.Filter)
+	}
+	if o.ResourceType != nil {
+		clauses = append(clauses, "resource.type eq "+scimString(*o.ResourceType))
+	}
+	if o.ResourceID != nil {
+		clauses = append(clauses, "resource.value eq "+scimString(*o.ResourceID))
+	}
+	if len(clauses) == 0 {
+		return nil
+	}
+	query := strings.Join(clauses, " and ")
+	return &query
+}
+
+// scimString quotes value for use in a SCIM filter, escaping backslashes and quotes
+func scimString(value string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(value) + `"`
+}
+
+// GetMFAPolicies searches MFA policies based on GetMFAPolicyOptions
+func (p *MFAPoliciesService) GetMFAPolicies(opt *GetMFAPolicyOptions, options ...OptionFunc) (*MFAPolicyList, *Response, error) {
+	var query *GetMFAPolicyOptions
+	if opt != nil {
+		query = &GetMFAPolicyOptions{
+			Filter:     opt.filter(),
+			StartIndex: opt.StartIndex,
+			Count:      opt.Count,
+		}
+	}
+	req, err := p.client.newRequest(IDM, "GET", scimBasePath+"MFAPolicies", query, options)
+	if err != nil {
+		return nil, nil, err
+	}
+	req.Header.Set("api-version", mfaPoliciesAPIVersion)
+	req.Header.Set("Content-Type", "application/scim+json")
+
+	var listResponse struct {
+		Schemas      []string    `json:"schemas"`
+		TotalResults int         `json:"totalResults"`
+		StartIndex   int         `json:"startIndex"`
+		ItemsPerPage int         `json:"itemsPerPage"`
+		Resources    []MFAPolicy `json:"Resources"`
+	}
+
+	resp, err := p.client.do(req, &listResponse)
+	if err != nil {
+		return nil, resp, err
+	}
+	list := MFAPolicyList{
+		Policies:     listResponse.Resources,
+		TotalResults: listResponse.TotalResults,
+		StartIndex:   listResponse.StartIndex,
+		ItemsPerPage: listResponse.ItemsPerPage,
+	}
+	if list.StartIndex == 0 {
+		list.StartIndex = 1
+	}
+	list.HasNextPage = len(list.Policies) > 0 && list.StartIndex-1+len(list.Policies) < list.TotalResults
+	return &list, resp, nil
+}
+
+// GetAllMFAPolicies retrieves all MFA policies matching GetMFAPolicyOptions, following pages
+func (p *MFAPoliciesService) GetAllMFAPolicies(opt *GetMFAPolicyOptions, options ...OptionFunc) ([]MFAPolicy, *Response, error) {
+	var policies []MFAPolicy
+	var query GetMFAPolicyOptions
+	if opt != nil {
+		query = *opt
+	}
+	startIndex := 1
+	if query.StartIndex != nil {
+		startIndex = *query.StartIndex
+	}
+	for {
+		query.StartIndex = &startIndex
+		list, resp, err := p.GetMFAPolicies(&query, options...)
+		if err != nil {
+			return policies, resp, err
+		}
+		policies = append(policies, list.Policies...)
+		if !list.HasNextPage {
+			return policies, resp, nil
+		}
+		startIndex = list.StartIndex + len(list.Policies)
+	}
+}
+
+// GetMFAPoliciesByUser returns all MFA policies attached to the given user
+func (p *MFAPoliciesService) GetMFAPoliciesByUser(userID string, options ...OptionFunc) ([]MFAPolicy, *Response, error) {
+	return p.GetAllMFAPolicies(&GetMFAPolicyOptions{
+		ResourceType: String("User"),
+		ResourceID:   &userID,
+	}, options...)
+}
+
+// GetMFAPoliciesByOrganization returns all MFA policies attached to the given organization
+func (p *MFAPoliciesService) GetMFAPoliciesByOrganization(orgID string, options ...OptionFunc) ([]MFAPolicy, *Response, error) {
+	return p.GetAllMFAPolicies(&GetMFAPolicyOptions{
+		ResourceType: String("Organization"),
+		ResourceID:   &orgID,
+	}, options...)
+}
+
+// GetEffectiveMFAPolicy resolves the MFA policy which applies to the given user.
+// An active user level policy takes precedence over an active policy
+// of the managing organization of the user. ErrNotFound is returned when
+// neither exists.
+func (p *MFAPoliciesService) GetEffectiveMFAPolicy(userID string, options ...OptionFunc) (*MFAPolicy, *Response, error) {
+	policies, resp, err := p.GetMFAPoliciesByUser(userID, options...)
+	if err != nil {
+		return nil, resp, err
+	}
+	if policy := firstActiveMFAPolicy(policies); policy != nil {
+		return policy, resp, nil
+	}
+	user, resp, err := p.client.Users.GetUserByID(userID, options...)
+	if err != nil {
+		return nil, resp, fmt.Errorf("GetEffectiveMFAPolicy: %w", err)
+	}
+	if user.ManagingOrganization == "" {
+		return nil, resp, ErrNotFound
+	}
+	policies, resp, err = p.GetMFAPoliciesByOrganization(user.ManagingOrganization, options...)
+	if err != nil {
+		return nil, resp, err
+	}
+	if policy := firstActiveMFAPolicy(policies); policy != nil {
+		return policy, resp, nil
+	}
+	return nil, resp, ErrNotFound
+}
+
+func firstActiveMFAPolicy(policies []MFAPolicy) *MFAPolicy {
+	for i := range policies {
+		if policies[i].Active == nil || *policies[i].Active {
+			return &policies[i]
+		}
+	}
+	return nil
+}
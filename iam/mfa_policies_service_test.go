@@ -2,6 +2,7 @@ package iam
 
 import (
 	"encoding/json"
+	"fmt"
 	"io"
 	"io/ioutil"
 	"net/http"
@@ -247,3 +248,146 @@ func TestDeleteMFAPolicy(t *testing.T) {
 	assert.Equal(t, http.StatusNoContent, resp.StatusCode)
 	assert.Equal(t, true, ok, "expected MFA policy deletion to succeed")
 }
+
+func mfaPolicyJSON(policyID, resourceType, resourceID string, active bool) string {
+	return `{
+		"schemas": [
+		  "urn:ietf:params:scim:schemas:core:philips:hsdp:2.0:MFAPolicy"
+		],
+		"id": "` + policyID + `",
+		"name": "TestPolicy",
+		"resource": {
+		  "type": "` + resourceType + `",
+		  "value": "` + resourceID + `"
+		},
+		"types": [
+		  "SOFT_OTP"
+		],
+		"active": ` + fmt.Sprintf("%t", active) + `,
+		"meta": {
+		  "resourceType": "MFAPolicy",
+		  "version": "W/\"-955544145\""
+		}
+	  }`
+}
+
+func TestGetMFAPolicies(t *testing.T) {
+	teardown := setup(t)
+	defer teardown()
+
+	orgID := "b23e7a82-f3b4-40b9-aaef-8111cb788ef9"
+	muxIDM.HandleFunc("/authorize/scim/v2/MFAPolicies", func(w http.ResponseWriter, r *http.Request) {
+		if ok := assert.Equal(t, "GET", r.Method); !ok {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		assert.Equal(t, `resource.type eq "Organization" and resource.value eq "`+orgID+`"`, r.URL.Query().Get("filter"))
+		w.Header().Set("Content-Type", "application/scim+json")
+		w.WriteHeader(http.StatusOK)
+		switch r.URL.Query().Get("startIndex") {
+		case "1":
+			_, _ = io.WriteString(w, `{
+				"schemas": ["urn:ietf:params:scim:api:messages:2.0:ListResponse"],
+				"totalResults": 2,
+				"startIndex": 1,
+				"itemsPerPage": 1,
+				"Resources": [`+mfaPolicyJSON("policy-1", "Organization", orgID, false)+`]
+			}`)
+		case "2":
+			_, _ = io.WriteString(w, `{
+				"schemas": ["urn:ietf:params:scim:api:messages:2.0:ListResponse"],
+				"totalResults": 2,
+				"startIndex": 2,
+				"itemsPerPage": 1,
+				"Resources": [`+mfaPolicyJSON("policy-2", "Organization", orgID, true)+`]
+			}`)
+		default:
+			t.Errorf("unexpected startIndex: %s", r.URL.Query().Get("startIndex"))
+		}
+	})
+
+	startIndex := 1
+	list, resp, err := client.MFAPolicies.GetMFAPolicies(&GetMFAPolicyOptions{
+		ResourceType: String("Organization"),
+		ResourceID:   &orgID,
+		StartIndex:   &startIndex,
+	})
+	if !assert.Nil(t, err) {
+		return
+	}
+	assert.NotNil(t, resp)
+	if assert.NotNil(t, list) {
+		assert.Len(t, list.Policies, 1)
+		assert.True(t, list.HasNextPage)
+		assert.Equal(t, 2, list.TotalResults)
+	}
+
+	policies, resp, err := client.MFAPolicies.GetMFAPoliciesByOrganization(orgID)
+	if !assert.Nil(t, err) {
+		return
+	}
+	assert.NotNil(t, resp)
+	if assert.Len(t, policies, 2) {
+		assert.Equal(t, "policy-1", policies[0].ID)
+		assert.Equal(t, "policy-2", policies[1].ID)
+	}
+}
+
+func TestGetEffectiveMFAPolicy(t *testing.T) {
+	teardown := setup(t)
+	defer teardown()
+
+	userID := "ad8a7c6a-231e-452c-8e89-9863c1005982"
+	orgID := "c29cdb88-7cda-4fc1-af8b-ee5947659958"
+	userHandler := userIDByLoginIDHandler(t, "ron", "foo@bar.com", userID)
+	muxIDM.HandleFunc("/authorize/identity/User", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Trace") != "trace" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		userHandler(w, r)
+	})
+	muxIDM.HandleFunc("/authorize/scim/v2/MFAPolicies", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/scim+json")
+		w.WriteHeader(http.StatusOK)
+		var resources string
+		switch r.URL.Query().Get("filter") {
+		case `resource.type eq "User" and resource.value eq "` + userID + `"`:
+			resources = mfaPolicyJSON("user-policy", "User", userID, false)
+		case `resource.type eq "Organization" and resource.value eq "` + orgID + `"`:
+			resources = mfaPolicyJSON("org-policy", "Organization", orgID, true)
+		}
+		_, _ = io.WriteString(w, `{
+			"schemas": ["urn:ietf:params:scim:api:messages:2.0:ListResponse"],
+			"totalResults": 1,
+			"startIndex": 1,
+			"itemsPerPage": 1,
+			"Resources": [`+resources+`]
+		}`)
+	})
+
+	trace := func(req *http.Request) error {
+		req.Header.Set("X-Trace", "trace")
+		return nil
+	}
+	policy, resp, err := client.MFAPolicies.GetEffectiveMFAPolicy(userID, trace)
+	if !assert.Nil(t, err) {
+		return
+	}
+	assert.NotNil(t, resp)
+	if assert.NotNil(t, policy) {
+		assert.Equal(t, "org-policy", policy.ID)
+		assert.Equal(t, "Organization", policy.Resource.Type)
+	}
+}
+
+func TestMFAPolicyFilterEscaping(t *testing.T) {
+	opt := &GetMFAPolicyOptions{
+		ResourceType: String("User"),
+		ResourceID:   String(`a" or resource.value pr or "\`),
+	}
+	filter := opt.filter()
+	if assert.NotNil(t, filter) {
+		assert.Equal(t, `resource.type eq "User" and resource.value eq "a\" or resource.value pr or \"\\"`, *filter)
+	}
+}
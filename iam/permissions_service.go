@@ -1,6 +1,10 @@
 package iam
 
-import "fmt"
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
 
 const permissionAPIVersion = "1"
 
@@ -23,6 +27,8 @@ type GetPermissionOptions struct {
 	ID     *string `url:"_id,omitempty"`
 	Name   *string `url:"name,omitempty"`
 	RoleID *string `url:"roleId,omitempty"`
+	Count  *int    `url:"_count,omitempty"`
+	Page   *int    `url:"_page,omitempty"`
 }
 
 // GetPermissionByID looks up a permission by ID
@@ -72,9 +78,15 @@ func (p *PermissionsService) GetPermission(opt *GetPermissionOptions, options ..
 
 // GetPermissions looks up permissions based on GetPermissionOptions
 func (p *PermissionsService) GetPermissions(opt *GetPermissionOptions, options ...OptionFunc) (*[]Permission, *Response, error) {
+	permissions, _, resp, err := p.getPermissionsPage(opt, options...)
+	return permissions, resp, err
+}
+
+// getPermissionsPage looks up a page of permissions and the total number of matching permissions
+func (p *PermissionsService) getPermissionsPage(opt *GetPermissionOptions, options ...OptionFunc) (*[]Permission, int, *Response, error) {
 	req, err := p.client.newRequest(IDM, "GET", "authorize/identity/Permission", opt, options)
 	if err != nil {
-		return nil, nil, err
+		return nil, 0, nil, err
 	}
 	req.Header.Set("api-version", permissionAPIVersion)
 
@@ -84,8 +96,89 @@ func (p *PermissionsService) GetPermissions(opt *GetPermissionOptions, options .
 	}
 
 	resp, err := p.client.do(req, &responseStruct)
+	if err != nil {
+		return nil, 0, resp, err
+	}
+	return &responseStruct.Entry, responseStruct.Total, resp, err
+}
+
+// GetAllPermissions looks up all permissions matching GetPermissionOptions, following
+// pages until the total reported by IAM has been retrieved
+func (p *PermissionsService) GetAllPermissions(opt *GetPermissionOptions, options ...OptionFunc) ([]Permission, *Response, error) {
+	var permissions []Permission
+	var query GetPermissionOptions
+	if opt != nil {
+		query = *opt
+	}
+	page := 1
+	if query.Page != nil {
+		page = *query.Page
+	}
+	for {
+		query.Page = &page
+		list, total, resp, err := p.getPermissionsPage(&query, options...)
+		if err != nil {
+			return permissions, resp, err
+		}
+		if list == nil || len(*list) == 0 {
+			return permissions, resp, nil
+		}
+		permissions = append(permissions, *list...)
+		if len(permissions) >= total {
+			return permissions, resp, nil
+		}
+		page++
+	}
+}
+
+// PermissionCatalog holds all available permissions grouped by category
+type PermissionCatalog struct {
+	Categories map[string][]Permission `json:"categories"`
+}
+
+// Has returns true if the catalog contains the named permission
+func (c *PermissionCatalog) Has(name string) bool {
+	for _, permissions := range c.Categories {
+		for _, p := range permissions {
+			if p.Name == name {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Validate checks that all named permissions exist in the catalog.
+// The returned error wraps ErrUnknownPermission and lists the unknown names.
+func (c *PermissionCatalog) Validate(names ...string) error {
+	var unknown []string
+	for _, name := range names {
+		if !c.Has(name) {
+			unknown = append(unknown, name)
+		}
+	}
+	if len(unknown) > 0 {
+		return fmt.Errorf("%w: %s", ErrUnknownPermission, strings.Join(unknown, ", "))
+	}
+	return nil
+}
+
+// GetPermissionCatalog retrieves all available permissions grouped by category
+func (p *PermissionsService) GetPermissionCatalog(options ...OptionFunc) (*PermissionCatalog, *Response, error) {
+	permissions, resp, err := p.GetAllPermissions(nil, options...)
 	if err != nil {
 		return nil, resp, err
 	}
-	return &responseStruct.Entry, resp, err
+	catalog := &PermissionCatalog{
+		Categories: make(map[string][]Permission),
+	}
+	for _, permission := range permissions {
+		catalog.Categories[permission.Category] = append(catalog.Categories[permission.Category], permission)
+	}
+	for _, list := range catalog.Categories {
+		sort.Slice(list, func(i, j int) bool {
+			return list[i].Name < list[j].Name
+		})
+	}
+	return catalog, resp, nil
 }
@@ -1,6 +1,7 @@
 package iam
 
 import (
+	"errors"
 	"io"
 	"net/http"
 	"testing"
@@ -97,3 +98,51 @@ func TestGetPermissionByID(t *testing.T) {
 		t.Errorf("Expected Permission with ID: %s, Got: %s", uuid, permission.ID)
 	}
 }
+
+func TestGetPermissionCatalog(t *testing.T) {
+	teardown := setup(t)
+	defer teardown()
+
+	// The catalog spans two pages
+	muxIDM.HandleFunc("/authorize/identity/Permission", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		switch r.URL.Query().Get("_page") {
+		case "1":
+			_, _ = io.WriteString(w, `{
+				"total": 3,
+				"entry": [
+					{ "id": "1", "name": "GROUP.WRITE", "category": "IAM" },
+					{ "id": "2", "name": "GROUP.READ", "category": "IAM" }
+				]
+			}`)
+		case "2":
+			_, _ = io.WriteString(w, `{
+				"total": 3,
+				"entry": [
+					{ "id": "3", "name": "LOG.READ", "category": "LOGGING" }
+				]
+			}`)
+		default:
+			_, _ = io.WriteString(w, `{ "total": 3, "entry": [] }`)
+		}
+	})
+
+	catalog, resp, err := client.Permissions.GetPermissionCatalog()
+	if !assert.Nil(t, err) {
+		return
+	}
+	assert.NotNil(t, resp)
+	if !assert.NotNil(t, catalog) {
+		return
+	}
+	assert.Len(t, catalog.Categories, 2)
+	if assert.Len(t, catalog.Categories["IAM"], 2) {
+		assert.Equal(t, "GROUP.READ", catalog.Categories["IAM"][0].Name)
+	}
+	assert.True(t, catalog.Has("LOG.READ"))
+	assert.Nil(t, catalog.Validate("GROUP.READ", "LOG.READ"))
+	err = catalog.Validate("GROUP.READ", "FOO.BAR")
+	assert.True(t, errors.Is(err, ErrUnknownPermission))
+	assert.Contains(t, err.Error(), "FOO.BAR")
+}
@@ -32,7 +32,8 @@ func (p *Proposition) validate() error {
 	return nil
 }
 
-// PropositionsService implements actions on IAM Proposition entities
+// PropositionsService implements actions on IAM Proposition entities.
+// IAM has no API to update or delete a Proposition
 type PropositionsService struct {
 	client *Client
 }
@@ -68,9 +69,15 @@ func (p *PropositionsService) GetProposition(opt *GetPropositionsOptions, option
 
 // GetPropositions search for an Proposition entity based on the GetPropositions values
 func (p *PropositionsService) GetPropositions(opt *GetPropositionsOptions, options ...OptionFunc) (*[]Proposition, *Response, error) {
+	list, _, resp, err := p.getPropositionsPage(opt, options...)
+	return list, resp, err
+}
+
+// getPropositionsPage returns a page of propositions and the total number of matching propositions
+func (p *PropositionsService) getPropositionsPage(opt *GetPropositionsOptions, options ...OptionFunc) (*[]Proposition, int, *Response, error) {
 	req, err := p.client.newRequest(IDM, "GET", "authorize/identity/Proposition", opt, options)
 	if err != nil {
-		return nil, nil, err
+		return nil, 0, nil, err
 	}
 	req.Header.Set("api-version", propositionAPIVersion)
 	req.Header.Set("Content-Type", "application/json")
@@ -82,9 +89,9 @@ func (p *PropositionsService) GetPropositions(opt *GetPropositionsOptions, optio
 
 	resp, err := p.client.do(req, &bundleResponse)
 	if err != nil {
-		return nil, resp, err
+		return nil, 0, resp, err
 	}
-	return &bundleResponse.Entry, resp, err
+	return &bundleResponse.Entry, bundleResponse.Total, resp, err
 }
 
 // CreateProposition creates a Proposition
@@ -119,3 +126,109 @@ func (p *PropositionsService) CreateProposition(prop Proposition) (*Proposition,
 	}
 	return p.GetPropositionByID(id)
 }
+
+// GetAllPropositions retrieves all propositions matching GetPropositionsOptions, following pages
+// until the total reported by IAM has been retrieved
+func (p *PropositionsService) GetAllPropositions(opt *GetPropositionsOptions, options ...OptionFunc) ([]Proposition, *Response, error) {
+	var props []Proposition
+	var query GetPropositionsOptions
+	if opt != nil {
+		query = *opt
+	}
+	count := 100
+	if query.Count != nil {
+		count = *query.Count
+	}
+	page := 1
+	if query.Page != nil {
+		page = *query.Page
+	}
+	query.Count = &count
+	for {
+		query.Page = &page
+		list, total, resp, err := p.getPropositionsPage(&query, options...)
+		if err != nil {
+			return props, resp, err
+		}
+		// IAM may return fewer propositions per page than requested
+		if list == nil || len(*list) == 0 {
+			return props, resp, nil
+		}
+		props = append(props, *list...)
+		if len(props) >= total {
+			return props, resp, nil
+		}
+		page++
+	}
+}
+
+// ApplicationNode holds an Application together with its services and clients
+type ApplicationNode struct {
+	Application
+	Services []Service           `json:"services"`
+	Clients  []ApplicationClient `json:"clients"`
+}
+
+// PropositionNode holds a Proposition together with its applications
+type PropositionNode struct {
+	Proposition
+	Applications []ApplicationNode `json:"applications"`
+}
+
+// OrganizationTree holds the propositions, applications, services and clients of an organization
+type OrganizationTree struct {
+	OrganizationID string            `json:"organizationId"`
+	Propositions   []PropositionNode `json:"propositions"`
+}
+
+// GetOrganizationTree retrieves the propositions of the organization
+// and for each proposition its applications, services and clients.
+// All pages are retrieved at every level
+func (p *PropositionsService) GetOrganizationTree(orgID string, options ...OptionFunc) (*OrganizationTree, *Response, error) {
+	props, resp, err := p.GetAllPropositions(&GetPropositionsOptions{
+		OrganizationID: &orgID,
+	}, options...)
+	if err != nil {
+		return nil, resp, err
+	}
+	tree := &OrganizationTree{
+		OrganizationID: orgID,
+		Propositions:   []PropositionNode{},
+	}
+	for _, prop := range props {
+		propNode := PropositionNode{
+			Proposition:  prop,
+			Applications: []ApplicationNode{},
+		}
+		apps, resp, err := p.client.Applications.GetAllApplications(&GetApplicationsOptions{
+			PropositionID: &prop.ID,
+		}, options...)
+		if err != nil {
+			return nil, resp, fmt.Errorf("GetOrganizationTree: GetAllApplications: %w", err)
+		}
+		for _, app := range apps {
+			appNode := ApplicationNode{
+				Application: *app,
+				Services:    []Service{},
+				Clients:     []ApplicationClient{},
+			}
+			services, resp, err := p.client.Services.GetAllServices(&GetServiceOptions{
+				ApplicationID: &app.ID,
+			}, options...)
+			if err != nil {
+				return nil, resp, fmt.Errorf("GetOrganizationTree: GetAllServices: %w", err)
+			}
+			appNode.Services = append(appNode.Services, services...)
+			clients, resp, err := p.client.Clients.GetAllClients(&GetClientsOptions{
+				ApplicationID: &app.ID,
+			}, options...)
+			if err != nil {
+				return nil, resp, fmt.Errorf("GetOrganizationTree: GetAllClients: %w", err)
+			}
+			appNode.Clients = append(appNode.Clients, clients...)
+			propNode.Applications = append(propNode.Applications, appNode)
+		}
+		tree.Propositions = append(tree.Propositions, propNode)
+	}
+	return tree, resp, nil
+}
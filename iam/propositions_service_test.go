@@ -108,3 +108,97 @@ func TestGetPropositions(t *testing.T) {
 		assert.Equal(t, http.StatusOK, resp.StatusCode)
 	}
 }
+
+func TestGetOrganizationTree(t *testing.T) {
+	teardown := setup(t)
+	defer teardown()
+
+	orgID := "3af7143e-de76-11e8-9681-6a0002b8cb70"
+	propID := "10dc5e2f-3940-4cd8-b0ef-297e12ad2f3c"
+	emptyPropID := "c3a3d3e6-8a0f-4c4e-a6a4-6c8f5d2c3b1a"
+	appID := "a2fd58c3-5b8b-4a4b-9b4e-1d1e3f0f7c11"
+
+	muxIDM.HandleFunc("/authorize/identity/Proposition", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, orgID, r.URL.Query().Get("organizationId"))
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		// IAM returns fewer propositions per page than requested
+		if r.URL.Query().Get("_page") == "2" {
+			_, _ = io.WriteString(w, `{
+				"total": 2,
+				"entry": [
+					{ "id": "`+emptyPropID+`", "name": "EMPTY", "organizationId": "`+orgID+`", "globalReferenceId": "empty-ref" }
+				]
+			}`)
+			return
+		}
+		_, _ = io.WriteString(w, `{
+			"total": 2,
+			"entry": [
+				{ "id": "`+propID+`", "name": "PROP", "organizationId": "`+orgID+`", "globalReferenceId": "prop-ref" }
+			]
+		}`)
+	})
+	muxIDM.HandleFunc("/authorize/identity/Application", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		if r.URL.Query().Get("propositionId") != propID {
+			_, _ = io.WriteString(w, `{ "total": 0, "entry": [] }`)
+			return
+		}
+		_, _ = io.WriteString(w, `{
+			"total": 1,
+			"entry": [
+				{ "id": "`+appID+`", "name": "APP", "propositionId": "`+propID+`", "globalReferenceId": "app-ref" }
+			]
+		}`)
+	})
+	muxIDM.HandleFunc("/authorize/identity/Service", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, appID, r.URL.Query().Get("applicationId"))
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		_, _ = io.WriteString(w, `{
+			"total": 2,
+			"entry": [
+				{ "id": "svc-`+r.URL.Query().Get("_page")+`", "name": "service", "applicationId": "`+appID+`" }
+			]
+		}`)
+	})
+	muxIDM.HandleFunc("/authorize/identity/Client", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, appID, r.URL.Query().Get("applicationId"))
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		_, _ = io.WriteString(w, `{
+			"total": 1,
+			"entry": [
+				{ "id": "client-1", "clientId": "client", "name": "client", "applicationId": "`+appID+`" }
+			]
+		}`)
+	})
+
+	tree, resp, err := client.Propositions.GetOrganizationTree(orgID)
+	if !assert.Nil(t, err) {
+		return
+	}
+	assert.NotNil(t, resp)
+	if !assert.NotNil(t, tree) {
+		return
+	}
+	assert.Equal(t, orgID, tree.OrganizationID)
+	if !assert.Len(t, tree.Propositions, 2) {
+		return
+	}
+	assert.Len(t, tree.Propositions[1].Applications, 0)
+	if !assert.Len(t, tree.Propositions[0].Applications, 1) {
+		return
+	}
+	app := tree.Propositions[0].Applications[0]
+	assert.Equal(t, "APP", app.Name)
+	if assert.Len(t, app.Services, 2) {
+		assert.Equal(t, "svc-1", app.Services[0].ID)
+		assert.Equal(t, "svc-2", app.Services[1].ID)
+	}
+	if assert.Len(t, app.Clients, 1) {
+		assert.Equal(t, "client-1", app.Clients[0].ID)
+	}
+}
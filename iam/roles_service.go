@@ -174,3 +174,72 @@ func (p *RolesService) AddRolePermission(role Role, permission string) (bool, *R
 func (p *RolesService) RemoveRolePermission(role Role, permission string) (bool, *Response, error) {
 	return p.rolePermissionAction(role, []string{permission}, "$remove-permission")
 }
+
+// UpdateRole updates the description of the Role
+func (p *RolesService) UpdateRole(role Role) (*Role, *Response, error) {
+	var updateRequest struct {
+		Description string `json:"description"`
+	}
+	updateRequest.Description = role.Description
+	req, err := p.client.newRequest(IDM, "PUT", "authorize/identity/Role/"+role.ID, &updateRequest, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	req.Header.Set("api-version", roleAPIVersion)
+
+	var updatedRole Role
+
+	resp, err := p.client.do(req, &updatedRole)
+	if err != nil {
+		return nil, resp, err
+	}
+	if updatedRole.ID == "" {
+		return &role, resp, nil
+	}
+	return &updatedRole, resp, nil
+}
+
+// RolePermissionsDiff describes the permission changes needed to bring a Role to the desired state
+type RolePermissionsDiff struct {
+	Add    []string
+	Remove []string
+}
+
+// Empty returns true if the diff contains no changes
+func (d RolePermissionsDiff) Empty() bool {
+	return len(d.Add) == 0 && len(d.Remove) == 0
+}
+
+// DiffRolePermissions returns the changes needed to bring the permissions of the Role to the desired state
+func (p *RolesService) DiffRolePermissions(role Role, desired []string) (*RolePermissionsDiff, *Response, error) {
+	current, resp, err := p.GetRolePermissions(role)
+	if err != nil {
+		return nil, resp, err
+	}
+	return &RolePermissionsDiff{
+		Add:    stringsNotIn(desired, *current),
+		Remove: stringsNotIn(*current, desired),
+	}, resp, nil
+}
+
+// SetRolePermissions brings the permissions of the Role to the desired state.
+// At most one assign and one remove call is done. The applied diff is returned.
+func (p *RolesService) SetRolePermissions(role Role, desired []string) (*RolePermissionsDiff, *Response, error) {
+	diff, resp, err := p.DiffRolePermissions(role, desired)
+	if err != nil {
+		return nil, resp, err
+	}
+	if len(diff.Add) > 0 {
+		_, resp, err = p.rolePermissionAction(role, diff.Add, "$assign-permission")
+		if err != nil {
+			return nil, resp, err
+		}
+	}
+	if len(diff.Remove) > 0 {
+		_, resp, err = p.rolePermissionAction(role, diff.Remove, "$remove-permission")
+		if err != nil {
+			return nil, resp, err
+		}
+	}
+	return diff, resp, nil
+}
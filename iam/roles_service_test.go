@@ -1,6 +1,7 @@
 package iam
 
 import (
+	"encoding/json"
 	"io"
 	"net/http"
 	"testing"
@@ -218,3 +219,87 @@ func TestGetRolePermissions(t *testing.T) {
 	}
 	assert.Contains(t, *permissions, permissionName)
 }
+
+func TestSetRolePermissions(t *testing.T) {
+	teardown := setup(t)
+	defer teardown()
+
+	roleID := "dbf1d779-ab9f-4c27-b4aa-ea75f9efbbc1"
+	calls := map[string][]string{}
+	muxIDM.HandleFunc("/authorize/identity/Permission", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, roleID, r.URL.Query().Get("roleId"))
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		_, _ = io.WriteString(w, `{
+			"total": 2,
+			"entry": [
+				{ "id": "1", "name": "GROUP.READ", "category": "IAM" },
+				{ "id": "2", "name": "GROUP.WRITE", "category": "IAM" }
+			]
+		}`)
+	})
+	permissionHandler := func(action string) func(http.ResponseWriter, *http.Request) {
+		return func(w http.ResponseWriter, r *http.Request) {
+			var body struct {
+				Permissions []string `json:"permissions"`
+			}
+			_ = json.NewDecoder(r.Body).Decode(&body)
+			calls[action] = body.Permissions
+			w.WriteHeader(http.StatusOK)
+		}
+	}
+	muxIDM.HandleFunc("/authorize/identity/Role/"+roleID+"/$assign-permission", permissionHandler("assign"))
+	muxIDM.HandleFunc("/authorize/identity/Role/"+roleID+"/$remove-permission", permissionHandler("remove"))
+
+	role := Role{ID: roleID}
+	diff, resp, err := client.Roles.SetRolePermissions(role, []string{"GROUP.READ", "USER.READ", "DEVICE.READ"})
+	if !assert.Nil(t, err) {
+		return
+	}
+	assert.NotNil(t, resp)
+	assert.Equal(t, []string{"DEVICE.READ", "USER.READ"}, diff.Add)
+	assert.Equal(t, []string{"GROUP.WRITE"}, diff.Remove)
+	assert.Equal(t, []string{"DEVICE.READ", "USER.READ"}, calls["assign"])
+	assert.Equal(t, []string{"GROUP.WRITE"}, calls["remove"])
+
+	calls = map[string][]string{}
+	diff, _, err = client.Roles.SetRolePermissions(role, []string{"GROUP.WRITE", "GROUP.READ"})
+	if !assert.Nil(t, err) {
+		return
+	}
+	assert.True(t, diff.Empty())
+	assert.Len(t, calls, 0)
+}
+
+func TestUpdateRole(t *testing.T) {
+	teardown := setup(t)
+	defer teardown()
+
+	roleID := "dbf1d779-ab9f-4c27-b4aa-ea75f9efbbc1"
+	muxIDM.HandleFunc("/authorize/identity/Role/"+roleID, func(w http.ResponseWriter, r *http.Request) {
+		if !assert.Equal(t, "PUT", r.Method) {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		var body map[string]interface{}
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		assert.Equal(t, map[string]interface{}{"description": "new description"}, body)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		_, _ = io.WriteString(w, `{
+			"id": "`+roleID+`",
+			"name": "TESTROLE",
+			"description": "new description",
+			"managingOrganization": "f5fe538f-c3b5-4454-8774-cd3789f59b9a"
+		}`)
+	})
+
+	role, resp, err := client.Roles.UpdateRole(Role{ID: roleID, Name: "TESTROLE", Description: "new description"})
+	if !assert.Nil(t, err) {
+		return
+	}
+	assert.NotNil(t, resp)
+	if assert.NotNil(t, role) {
+		assert.Equal(t, "new description", role.Description)
+	}
+}
@@ -0,0 +1,44 @@
+package iam
+
+import "sort"
+
+// ScopeDiff describes the scope changes needed to go from an actual to a desired set of scopes
+type ScopeDiff struct {
+	AddScopes           []string
+	RemoveScopes        []string
+	AddDefaultScopes    []string
+	RemoveDefaultScopes []string
+}
+
+// Empty returns true if the diff contains no changes
+func (d ScopeDiff) Empty() bool {
+	return len(d.AddScopes) == 0 && len(d.RemoveScopes) == 0 &&
+		len(d.AddDefaultScopes) == 0 && len(d.RemoveDefaultScopes) == 0
+}
+
+// DiffScopes compares the actual and desired scopes and default scopes
+func DiffScopes(actualScopes, actualDefaultScopes, desiredScopes, desiredDefaultScopes []string) ScopeDiff {
+	return ScopeDiff{
+		AddScopes:           stringsNotIn(desiredScopes, actualScopes),
+		RemoveScopes:        stringsNotIn(actualScopes, desiredScopes),
+		AddDefaultScopes:    stringsNotIn(desiredDefaultScopes, actualDefaultScopes),
+		RemoveDefaultScopes: stringsNotIn(actualDefaultScopes, desiredDefaultScopes),
+	}
+}
+
+// stringsNotIn returns the sorted, deduplicated values in a which are not in b
+func stringsNotIn(a, b []string) []string {
+	present := make(map[string]bool, len(b))
+	for _, s := range b {
+		present[s] = true
+	}
+	var missing []string
+	for _, s := range a {
+		if !present[s] {
+			missing = append(missing, s)
+			present[s] = true // dedupe
+		}
+	}
+	sort.Strings(missing)
+	return missing
+}
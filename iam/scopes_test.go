@@ -0,0 +1,22 @@
+package iam
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDiffScopes(t *testing.T) {
+	diff := DiffScopes(
+		[]string{"mail", "sn", "cn"}, []string{"cn"},
+		[]string{"cn", "tdr.contract", "mail", "mail"}, []string{"cn", "mail"})
+
+	assert.Equal(t, []string{"tdr.contract"}, diff.AddScopes)
+	assert.Equal(t, []string{"sn"}, diff.RemoveScopes)
+	assert.Equal(t, []string{"mail"}, diff.AddDefaultScopes)
+	assert.Nil(t, diff.RemoveDefaultScopes)
+	assert.False(t, diff.Empty())
+
+	diff = DiffScopes([]string{"b", "a"}, nil, []string{"a", "b"}, []string{})
+	assert.True(t, diff.Empty())
+}
@@ -41,6 +41,8 @@ type ServicesService struct {
 // GetServiceOptions describes search criteria for looking up services
 type GetServiceOptions struct {
 	ID             *string `url:"_id,omitempty"`
+	Count          *int    `url:"_count,omitempty"`
+	Page           *int    `url:"_page,omitempty"`
 	Name           *string `url:"name,omitempty"`
 	ApplicationID  *string `url:"applicationId,omitempty"`
 	OrganizationID *string `url:"organizationId,omitempty"`
@@ -163,9 +165,15 @@ func (p *ServicesService) GetService(opt *GetServiceOptions, options ...OptionFu
 
 // GetServices looks up services based on GetServiceOptions
 func (p *ServicesService) GetServices(opt *GetServiceOptions, options ...OptionFunc) (*[]Service, *Response, error) {
+	list, _, resp, err := p.getServicesPage(opt, options...)
+	return list, resp, err
+}
+
+// getServicesPage returns a page of services and the total number of matching services
+func (p *ServicesService) getServicesPage(opt *GetServiceOptions, options ...OptionFunc) (*[]Service, int, *Response, error) {
 	req, err := p.client.newRequest(IDM, "GET", "authorize/identity/Service", opt, options)
 	if err != nil {
-		return nil, nil, err
+		return nil, 0, nil, err
 	}
 	req.Header.Set("api-version", servicesAPIVersion)
 	req.Header.Set("Content-Type", "application/json")
@@ -177,9 +185,39 @@ func (p *ServicesService) GetServices(opt *GetServiceOptions, options ...OptionF
 
 	resp, err := p.client.do(req, &bundleResponse)
 	if err != nil {
-		return nil, resp, err
+		return nil, 0, resp, err
+	}
+	return &bundleResponse.Entry, bundleResponse.Total, resp, err
+}
+
+// GetAllServices retrieves all services matching GetServiceOptions, following pages
+// until the total reported by IAM has been retrieved
+func (p *ServicesService) GetAllServices(opt *GetServiceOptions, options ...OptionFunc) ([]Service, *Response, error) {
+	var services []Service
+	var query GetServiceOptions
+	if opt != nil {
+		query = *opt
+	}
+	page := 1
+	if query.Page != nil {
+		page = *query.Page
+	}
+	for {
+		query.Page = &page
+		list, total, resp, err := p.getServicesPage(&query, options...)
+		if err != nil {
+			return services, resp, err
+		}
+		// IAM may return fewer services per page than requested
+		if list == nil || len(*list) == 0 {
+			return services, resp, nil
+		}
+		services = append(services, *list...)
+		if len(services) >= total {
+			return services, resp, nil
+		}
+		page++
 	}
-	return &bundleResponse.Entry, resp, err
 }
 
 // DeleteService deletes the given Service
@@ -305,3 +343,35 @@ func publicKey(priv interface{}) interface{} {
 		return nil
 	}
 }
+
+// DiffScopes returns the changes needed to bring the scopes of the service to the desired state
+func (p *ServicesService) DiffScopes(service Service, scopes []string, defaultScopes []string) (*ScopeDiff, *Response, error) {
+	current, resp, err := p.GetServiceByID(service.ID)
+	if err != nil {
+		return nil, resp, err
+	}
+	diff := DiffScopes(current.Scopes, current.DefaultScopes, scopes, defaultScopes)
+	return &diff, resp, nil
+}
+
+// SetScopes brings the scopes of the service to the desired state using
+// the minimal AddScopes and RemoveScopes calls. The applied diff is returned.
+func (p *ServicesService) SetScopes(service Service, scopes []string, defaultScopes []string) (*ScopeDiff, *Response, error) {
+	diff, resp, err := p.DiffScopes(service, scopes, defaultScopes)
+	if err != nil {
+		return nil, resp, err
+	}
+	if len(diff.AddScopes) > 0 || len(diff.AddDefaultScopes) > 0 {
+		_, resp, err = p.AddScopes(service, diff.AddScopes, diff.AddDefaultScopes)
+		if err != nil {
+			return nil, resp, err
+		}
+	}
+	if len(diff.RemoveScopes) > 0 || len(diff.RemoveDefaultScopes) > 0 {
+		_, resp, err = p.RemoveScopes(service, diff.RemoveScopes, diff.RemoveDefaultScopes)
+		if err != nil {
+			return nil, resp, err
+		}
+	}
+	return diff, resp, nil
+}
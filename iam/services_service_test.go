@@ -2,9 +2,11 @@ package iam
 
 import (
 	"crypto/x509"
+	"encoding/json"
 	"encoding/pem"
 	"io"
 	"net/http"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -277,3 +279,46 @@ MIIEogIBAAKCAQEAg2ASjNf6XT/wPqjJlrjCCeD7aSC2JkFXkdlLIGUgrwYJdQcmP8cIfzsJmAa0Fba5
 	block, _ = pem.Decode([]byte(fixed))
 	assert.NotNil(t, block)
 }
+
+func TestSetServiceScopes(t *testing.T) {
+	teardown := setup(t)
+	defer teardown()
+
+	id := "2c266886-f918-4223-941d-437cb3cd09e8"
+	var actions []string
+	muxIDM.HandleFunc("/authorize/identity/Service", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		_, _ = io.WriteString(w, `{
+			"total": 1,
+			"entry": [
+				{
+					"id": "`+id+`",
+					"name": "testservice",
+					"serviceId": "testservice@app.prop.philips-healthsuite.com",
+					"scopes": ["openid", "cn"],
+					"defaultScopes": ["openid"]
+				}
+			]
+		}`)
+	})
+	muxIDM.HandleFunc("/authorize/identity/Service/"+id+"/$scopes", func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Action        string   `json:"action"`
+			Scopes        []string `json:"scopes"`
+			DefaultScopes []string `json:"defaultScopes"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		actions = append(actions, body.Action+":"+strings.Join(body.Scopes, ","))
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	diff, resp, err := client.Services.SetScopes(Service{ID: id}, []string{"openid", "mail"}, []string{"openid"})
+	if !assert.Nil(t, err) {
+		return
+	}
+	assert.NotNil(t, resp)
+	assert.Equal(t, []string{"mail"}, diff.AddScopes)
+	assert.Equal(t, []string{"cn"}, diff.RemoveScopes)
+	assert.Equal(t, []string{"add:mail", "remove:cn"}, actions)
+}
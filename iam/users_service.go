@@ -311,12 +311,15 @@ func (u *UsersService) GetUsers(opts *GetUserOptions, options ...OptionFunc) (*U
 }
 
 // GetUserByID looks up a user by UUID
-func (u *UsersService) GetUserByID(uuid string) (*User, *Response, error) {
+func (u *UsersService) GetUserByID(uuid string, options ...OptionFunc) (*User, *Response, error) {
 	opt := &GetUserOptions{
 		UserID:      &uuid,
 		ProfileType: String("all"),
 	}
-	req, _ := u.client.newRequest(IDM, "GET", "authorize/identity/User", opt, nil)
+	req, err := u.client.newRequest(IDM, "GET", "authorize/identity/User", opt, options)
+	if err != nil {
+		return nil, nil, err
+	}
 	req.Header.Set("api-version", userAPIVersion)
 
 	var responseStruct struct {
@@ -466,3 +469,32 @@ func (u *UsersService) SetMFAByLoginID(loginID string, activate bool) (bool, *Re
 	}
 	return u.SetMFA(userUUID, activate)
 }
+
+// MFAResult holds the outcome of a Multi-Factor-Authentication change for a single user
+type MFAResult struct {
+	UserID string
+	OK     bool
+	Err    error
+}
+
+// SetMFAByGroup (de)activates Multi-Factor-Authentication for all users in the given group.
+// Each user is processed regardless of failures for other users; the outcome
+// is reported per user. An error is only returned when the group members
+// could not be retrieved.
+func (u *UsersService) SetMFAByGroup(groupID string, activate bool, options ...OptionFunc) ([]MFAResult, *Response, error) {
+	users, resp, err := u.GetAllUsers(&GetUserOptions{
+		GroupID: &groupID,
+	}, options...)
+	if err != nil {
+		return nil, resp, err
+	}
+	results := make([]MFAResult, 0, len(users))
+	for _, userID := range users {
+		ok, _, err := u.SetMFA(userID, activate)
+		if err == nil && !ok {
+			err = ErrOperationFailed
+		}
+		results = append(results, MFAResult{UserID: userID, OK: ok, Err: err})
+	}
+	return results, resp, nil
+}
@@ -632,3 +632,49 @@ func TestLegacyPaths(t *testing.T) {
 	}
 	assert.Equal(t, "Swanson", profile.FamilyName)
 }
+
+func TestSetMFAByGroup(t *testing.T) {
+	teardown := setup(t)
+	defer teardown()
+
+	groupID := "1eec7b01-1417-4546-9c5e-088dea0a9e8b"
+	okUser := "7dbfe5fc-1320-4bc6-92a7-2be5d7f07cac"
+	failUser := "5620b687-7f67-4222-b7c2-91ff312b3066"
+
+	muxIDM.HandleFunc("/security/users", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, groupID, r.URL.Query().Get("groupId"))
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		_, _ = io.WriteString(w, `{
+			"exchange": {
+				"users": [
+					{ "userUUID": "`+okUser+`" },
+					{ "userUUID": "`+failUser+`" }
+				],
+				"nextPageExists": false
+			},
+			"responseCode": "200",
+			"responseMessage": "Success"
+		}`)
+	})
+	muxIDM.HandleFunc("/authorize/identity/User/"+okUser+"/$mfa",
+		actionRequestHandler(t, "setMFA", "", http.StatusAccepted))
+	muxIDM.HandleFunc("/authorize/identity/User/"+failUser+"/$mfa", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+	})
+
+	results, resp, err := client.Users.SetMFAByGroup(groupID, true)
+	if !assert.Nil(t, err) {
+		return
+	}
+	assert.NotNil(t, resp)
+	if !assert.Len(t, results, 2) {
+		return
+	}
+	assert.Equal(t, okUser, results[0].UserID)
+	assert.True(t, results[0].OK)
+	assert.Nil(t, results[0].Err)
+	assert.Equal(t, failUser, results[1].UserID)
+	assert.False(t, results[1].OK)
+	assert.NotNil(t, results[1].Err)
+}
@@ -0,0 +1,213 @@
+package logging
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Severity values used by the logger adapters
+const (
+	SeverityDebug    = "DEBUG"
+	SeverityInfo     = "INFO"
+	SeverityWarning  = "WARNING"
+	SeverityError    = "ERROR"
+	SeverityCritical = "CRITICAL"
+
+	defaultEventID  = "1"
+	defaultCategory = "TRACELOG"
+)
+
+// DefaultTraceIDKeys are the attribute keys which are mapped to the TransactionID
+var DefaultTraceIDKeys = []string{"transactionId", "transaction_id", "traceId", "trace_id", "traceID"}
+
+var (
+	hostnameOnce sync.Once
+	hostname     string
+)
+
+// Sink receives the resources created by the logger adapters. Shipper implements it
+type Sink interface {
+	Ship(resource Resource) error
+}
+
+// AdapterConfig holds the fields the logger adapters fill in on each resource
+type AdapterConfig struct {
+	ApplicationName     string
+	ApplicationInstance string
+	ApplicationVersion  string
+	ServiceName         string
+	Component           string
+	Category            string
+	EventID             string
+	OriginatingUser     string
+	// ServerName defaults to the hostname
+	ServerName string
+	// TraceIDKeys are the attribute keys mapped to TransactionID. Defaults to DefaultTraceIDKeys
+	TraceIDKeys []string
+	// OnError is called when a resource cannot be shipped
+	OnError func(err error)
+}
+
+func (c AdapterConfig) withDefaults() AdapterConfig {
+	if c.ServerName == "" {
+		hostnameOnce.Do(func() {
+			hostname, _ = os.Hostname()
+		})
+		c.ServerName = hostname
+	}
+	if c.Category == "" {
+		c.Category = defaultCategory
+	}
+	if c.EventID == "" {
+		c.EventID = defaultEventID
+	}
+	if len(c.TraceIDKeys) == 0 {
+		c.TraceIDKeys = DefaultTraceIDKeys
+	}
+	return c
+}
+
+// NewResource creates a LogEvent resource. Attributes matching one of the
+// TraceIDKeys, also inside a group such as "request.traceId", become the
+// TransactionID, the others are put in Custom.
+// A random TransactionID is generated when there is no trace ID attribute.
+func (c AdapterConfig) NewResource(t time.Time, severity, message string, attrs map[string]interface{}) Resource {
+	c = c.withDefaults()
+	r := Resource{
+		ResourceType:        "LogEvent",
+		ID:                  uuid.New().String(),
+		ApplicationName:     c.ApplicationName,
+		EventID:             c.EventID,
+		Category:            c.Category,
+		Component:           c.Component,
+		ServiceName:         c.ServiceName,
+		ApplicationInstance: c.ApplicationInstance,
+		ApplicationVersion:  c.ApplicationVersion,
+		OriginatingUser:     c.OriginatingUser,
+		ServerName:          c.ServerName,
+		LogTime:             t.UTC().Format(TimeFormat),
+		Severity:            severity,
+		LogData:             LogData{Message: message},
+	}
+	custom := make(map[string]interface{}, len(attrs))
+	for k, v := range attrs {
+		custom[k] = v
+	}
+	for _, key := range c.TraceIDKeys {
+		if v, ok := custom[key]; ok {
+			if r.TransactionID == "" {
+				r.TransactionID = fmt.Sprint(v)
+			}
+			delete(custom, key)
+		}
+	}
+	// Trace IDs in groups, which are flattened to "group.key", come after top level ones
+	grouped := make([]string, 0)
+	for k := range custom {
+		if i := strings.LastIndex(k, "."); i >= 0 && c.isTraceIDKey(k[i+1:]) {
+			grouped = append(grouped, k)
+		}
+	}
+	sort.Strings(grouped)
+	for _, k := range grouped {
+		if r.TransactionID == "" {
+			r.TransactionID = fmt.Sprint(custom[k])
+		}
+		delete(custom, k)
+	}
+	if r.TransactionID == "" {
+		r.TransactionID = uuid.New().String()
+	}
+	if len(custom) > 0 {
+		data, err := json.Marshal(custom)
+		if err != nil {
+			// Fall back to the string representation of the values
+			for k, v := range custom {
+				custom[k] = fmt.Sprint(v)
+			}
+			data, _ = json.Marshal(custom)
+		}
+		r.Custom = data
+	}
+	return r
+}
+
+// isTraceIDKey reports whether key is one of the TraceIDKeys
+func (c AdapterConfig) isTraceIDKey(key string) bool {
+	for _, k := range c.TraceIDKeys {
+		if k == key {
+			return true
+		}
+	}
+	return false
+}
+
+// ship sends a resource to sink, reporting failures to OnError
+func (c AdapterConfig) ship(sink Sink, r Resource) error {
+	err := sink.Ship(r)
+	if err != nil && c.OnError != nil {
+		c.OnError(err)
+	}
+	return err
+}
+
+// Writer is an io.Writer which ships every line written to it as a resource
+type Writer struct {
+	mu       sync.Mutex
+	sink     Sink
+	config   AdapterConfig
+	severity string
+	buf      bytes.Buffer
+}
+
+// NewWriter returns a Writer shipping lines with the given severity to sink
+func NewWriter(sink Sink, config AdapterConfig, severity string) *Writer {
+	return &Writer{
+		sink:     sink,
+		config:   config.withDefaults(),
+		severity: severity,
+	}
+}
+
+// Write ships each complete line in p. Incomplete lines are kept until the next Write or Close
+func (w *Writer) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.buf.Write(p)
+	for {
+		i := bytes.IndexByte(w.buf.Bytes(), '\n')
+		if i < 0 {
+			return len(p), nil
+		}
+		line := string(bytes.TrimRight(w.buf.Next(i+1), "\r\n"))
+		if err := w.shipLine(line); err != nil {
+			return len(p), err
+		}
+	}
+}
+
+// Close ships a remaining incomplete line
+func (w *Writer) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	line := w.buf.String()
+	w.buf.Reset()
+	return w.shipLine(line)
+}
+
+func (w *Writer) shipLine(line string) error {
+	if line == "" {
+		return nil
+	}
+	return w.config.ship(w.sink, w.config.NewResource(time.Now(), w.severity, line, nil))
+}
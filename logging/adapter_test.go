@@ -0,0 +1,91 @@
+package logging
+
+import (
+	"encoding/json"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type recordingSink struct {
+	sync.Mutex
+	resources []Resource
+	err       error
+}
+
+func (s *recordingSink) Ship(resource Resource) error {
+	s.Lock()
+	defer s.Unlock()
+	if s.err != nil {
+		return s.err
+	}
+	s.resources = append(s.resources, resource)
+	return nil
+}
+
+func TestAdapterNewResource(t *testing.T) {
+	config := AdapterConfig{
+		ApplicationName: "app",
+		ServerName:      "server",
+	}
+	logTime := time.Date(2021, 6, 1, 12, 0, 0, 0, time.FixedZone("CEST", 7200))
+	r := config.NewResource(logTime, SeverityWarning, "hello", map[string]interface{}{
+		"trace_id": "abc",
+		"user":     "ron",
+		"count":    3,
+	})
+	assert.True(t, r.Valid())
+	assert.Equal(t, "LogEvent", r.ResourceType)
+	assert.Equal(t, "app", r.ApplicationName)
+	assert.Equal(t, "server", r.ServerName)
+	assert.Equal(t, "2021-06-01T10:00:00.000Z", r.LogTime)
+	assert.Equal(t, SeverityWarning, r.Severity)
+	assert.Equal(t, "abc", r.TransactionID)
+
+	var custom map[string]interface{}
+	assert.Nil(t, json.Unmarshal(r.Custom, &custom))
+	assert.Equal(t, map[string]interface{}{"user": "ron", "count": float64(3)}, custom)
+
+	// A top level trace ID wins over one in a group
+	r = config.NewResource(logTime, SeverityInfo, "grouped", map[string]interface{}{
+		"req.traceId": "def",
+		"traceId":     "abc",
+	})
+	assert.Equal(t, "abc", r.TransactionID)
+	assert.Empty(t, r.Custom)
+
+	r = config.NewResource(logTime, SeverityInfo, "no trace", nil)
+	assert.NotEmpty(t, r.TransactionID)
+	assert.Empty(t, r.Custom)
+}
+
+func TestWriter(t *testing.T) {
+	sink := &recordingSink{}
+	w := NewWriter(sink, AdapterConfig{ApplicationName: "app"}, SeverityInfo)
+
+	n, err := w.Write([]byte("first line\nsecond "))
+	assert.Nil(t, err)
+	assert.Equal(t, 18, n)
+	_, _ = w.Write([]byte("line\r\n\npartial"))
+	if !assert.Len(t, sink.resources, 2) {
+		return
+	}
+	assert.Equal(t, "first line", sink.resources[0].LogData.Message)
+	assert.Equal(t, "second line", sink.resources[1].LogData.Message)
+	assert.NotEmpty(t, sink.resources[0].ServerName)
+
+	assert.Nil(t, w.Close())
+	if assert.Len(t, sink.resources, 3) {
+		assert.Equal(t, "partial", sink.resources[2].LogData.Message)
+	}
+
+	var reported error
+	sink.err = ErrQueueFull
+	w = NewWriter(sink, AdapterConfig{OnError: func(err error) { reported = err }}, SeverityInfo)
+	_, err = w.Write([]byte("dropped\n"))
+	assert.True(t, errors.Is(err, ErrQueueFull))
+	assert.Equal(t, ErrQueueFull, reported)
+}
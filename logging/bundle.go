@@ -38,6 +38,8 @@ type Resource struct {
 	LogData             LogData                `json:"logData"`             // Log data
 	Custom              json.RawMessage        `json:"custom,omitempty"`    // Custom log fields
 	Meta                map[string]interface{} `json:"-"`
+	// ProductKey routes the resource to a tenant. The client ProductKey is used when empty
+	ProductKey string `json:"-"`
 }
 
 // LogData is the payload of a log message
@@ -15,6 +15,7 @@ import (
 	"regexp"
 	"strconv"
 	"strings"
+	"sync"
 
 	"github.com/philips-software/go-hsdp-api/internal"
 
@@ -57,6 +58,11 @@ type Config struct {
 	BaseURL      string
 	ProductKey   string
 	Debug        bool
+	// Sanitizer is applied to each resource before it is stored. Defaults to DefaultSanitizer
+	Sanitizer Sanitizer
+	// Spool, when set, stores batches which StoreResources could not deliver. They are
+	// replayed before the next batch is stored or when ReplaySpool is called
+	Spool *Spool
 }
 
 // Valid returns if all required config fields are present, false otherwise
@@ -83,6 +89,11 @@ type Client struct {
 	httpClient *http.Client
 	iamClient  *iam.Client
 	httpSigner *signer.Signer
+
+	tenantsMu sync.RWMutex
+	tenants   map[string]*credentials
+
+	CustomIndex *CustomIndexService
 }
 
 // StoreResponse holds a LogEvent response
@@ -90,10 +101,15 @@ type StoreResponse struct {
 	*http.Response
 	Message string
 	Failed  map[int]Resource
+	// Sanitized holds the fields altered by the Sanitizer per resource index
+	Sanitized map[int][]string
 }
 
 // CustomIndexBody describes the custom index request payload
-type CustomIndexBody []struct {
+type CustomIndexBody []CustomIndexField
+
+// CustomIndexField describes a single custom index field
+type CustomIndexField struct {
 	Fieldname string `json:"fieldname"`
 	Fieldtype string `json:"fieldtype"`
 }
@@ -142,6 +158,7 @@ func NewClient(httpClient *http.Client, config *Config) (*Client, error) {
 	}
 
 	logger.url = parsedURL
+	logger.CustomIndex = &CustomIndexService{client: &logger}
 	if os.Getenv("DEBUG") == "true" {
 		logger.config.Debug = true
 	}
@@ -203,19 +220,90 @@ func (e *ErrorResponse) Error() string {
 // This also happens in case the HSDP Ingestor API flags resources. In both cases
 // the complete batch should be considered as not persisted and the LogEvents should
 // be resubmitted for storage
+//
+// Resources with a ProductKey are stored for that tenant, using its credentials when
+// registered with AddTenant. A batch spanning several product keys is split and
+// stored per product key. The response and error are those of the first failing
+// tenant, use StoreResourcesByTenant to get the outcome of each tenant.
+//
+// With a Spool configured, spooled batches are replayed first. A batch which fails
+// with a transport error or a 429 or 5xx response is spooled and ErrSpooled is
+// returned; it must not be resubmitted.
 func (c *Client) StoreResources(msgs []Resource, count int) (*StoreResponse, error) {
+	if c.config.Spool == nil {
+		return c.store(msgs, count)
+	}
+	if c.config.Spool.Pending() > 0 {
+		_, _ = c.ReplaySpool()
+	}
+	resp, err := c.store(msgs, count)
+	if err == nil || !undelivered(resp) {
+		return resp, err
+	}
+	if spoolErr := c.config.Spool.Append(msgs[:count]); spoolErr != nil {
+		return resp, err
+	}
+	return resp, fmt.Errorf("%v: %w", err, ErrSpooled)
+}
+
+// ReplaySpool stores the batches spooled by StoreResources. It returns the number
+// of resources delivered. Nothing is replayed when no Spool is configured
+func (c *Client) ReplaySpool() (int, error) {
+	if c.config.Spool == nil {
+		return 0, nil
+	}
+	return c.config.Spool.Replay(unspooledStorer{c})
+}
+
+// unspooledStorer stores through c without spooling so replays do not spool again
+type unspooledStorer struct {
+	c *Client
+}
+
+func (s unspooledStorer) StoreResources(msgs []Resource, count int) (*StoreResponse, error) {
+	return s.c.store(msgs, count)
+}
+
+// undelivered reports whether a failed store may succeed when it is retried later
+func undelivered(resp *StoreResponse) bool {
+	if resp == nil || resp.Response == nil {
+		return true
+	}
+	return resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= http.StatusInternalServerError
+}
+
+func (c *Client) store(msgs []Resource, count int) (*StoreResponse, error) {
+	groups := groupByProductKey(msgs[:count], c.config.ProductKey)
+	if len(groups) > 1 {
+		return c.storeGroups(groups)
+	}
+	productKey := c.config.ProductKey
+	if len(groups) == 1 {
+		productKey = groups[0].productKey
+	}
+	return c.storeResources(c.credentials(productKey), productKey, msgs, count)
+}
+
+func (c *Client) storeResources(creds *credentials, productKey string, msgs []Resource, count int) (*StoreResponse, error) {
 	b := Bundle{
 		ResourceType: "Bundle",
 		Entry:        make([]Element, count),
 		Type:         "transaction",
-		ProductKey:   c.config.ProductKey,
+		ProductKey:   productKey,
 	}
 	invalid := make(map[int]Resource)
+	sanitized := make(map[int][]string)
+	sanitizer := c.config.Sanitizer
+	if sanitizer == nil {
+		sanitizer = DefaultSanitizer
+	}
 
 	j := 0
 	for i := 0; i < count; i++ {
 		msg := msgs[i]
-		replaceScaryCharacters(&msg)
+		if fields := sanitizer.Sanitize(&msg); len(fields) > 0 {
+			sanitized[i] = fields
+		}
 		if !msg.Valid() {
 			invalid[i] = msg
 			continue
@@ -231,7 +319,8 @@ func (c *Client) StoreResources(msgs []Resource, count int) (*StoreResponse, err
 	}
 	if len(invalid) > 0 { // Don't even POST anything due to errors in the batch
 		resp := StoreResponse{
-			Failed: invalid,
+			Failed:    invalid,
+			Sanitized: sanitized,
 			Response: &http.Response{
 				StatusCode: http.StatusBadRequest,
 			},
@@ -260,14 +349,19 @@ func (c *Client) StoreResources(msgs []Resource, count int) (*StoreResponse, err
 	req.Header.Set("Content-Type", "application/json")
 	req.Header.Set("Api-Version", "1")
 	req.Header.Set("User-Agent", userAgent)
-	if c.httpSigner != nil {
-		if err := c.httpSigner.SignRequest(req); err != nil {
-			return nil, err
-		}
-	} else {
-		req.Header.Set("Authorization", "Bearer "+c.iamClient.Token())
+	if err := creds.authorize(req); err != nil {
+		return nil, err
+	}
+	resp, err := c.performAndParseResponse(req, msgs)
+	if resp != nil && len(sanitized) > 0 {
+		resp.Sanitized = sanitized
 	}
-	return c.performAndParseResponse(req, msgs)
+	return resp, err
+}
+
+// authorize signs req with the client credentials
+func (c *Client) authorize(req *http.Request) error {
+	return c.credentials(c.config.ProductKey).authorize(req)
 }
 
 func (c *Client) performAndParseResponse(req *http.Request, msgs []Resource) (*StoreResponse, error) {
@@ -280,6 +374,9 @@ func (c *Client) performAndParseResponse(req *http.Request, msgs []Resource) (*S
 		return nil, err
 	}
 	storeResp := &StoreResponse{Response: resp}
+	if resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden {
+		return storeResp, ErrNotAuthorized
+	}
 	if resp.StatusCode != http.StatusCreated { // Only good outcome
 		var errResponse bundleErrorResponse
 		err := json.Unmarshal(serverResponse.Bytes(), &errResponse)
@@ -7,6 +7,7 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"os"
+	"sync"
 	"testing"
 
 	"github.com/philips-software/go-hsdp-api/iam"
@@ -444,3 +445,76 @@ func TestStoreResourceWithBearerToken(t *testing.T) {
 		t.Errorf("Expected HTTP 201, Got: %d", resp.StatusCode)
 	}
 }
+
+func TestStoreResourcesSpool(t *testing.T) {
+	var mu sync.Mutex
+	status := http.StatusServiceUnavailable
+	var stored []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		if status != http.StatusCreated {
+			w.WriteHeader(status)
+			return
+		}
+		var bundle Bundle
+		body, _ := ioutil.ReadAll(r.Body)
+		if !assert.Nil(t, json.Unmarshal(body, &bundle)) {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		for _, e := range bundle.Entry {
+			stored = append(stored, e.Resource.ID)
+		}
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer server.Close()
+
+	spool := openTestSpool(t, SpoolConfig{})
+	defer spool.Close()
+	spooling, err := NewClient(nil, &Config{
+		SharedKey:    sharedKey,
+		SharedSecret: sharedSecret,
+		ProductKey:   productKey,
+		BaseURL:      server.URL,
+		Spool:        spool,
+	})
+	if !assert.Nil(t, err) {
+		return
+	}
+
+	first := validResource
+	first.ID = "first"
+	resp, err := spooling.StoreResources([]Resource{first}, 1)
+	assert.ErrorIs(t, err, ErrSpooled)
+	if assert.NotNil(t, resp) {
+		assert.Equal(t, http.StatusServiceUnavailable, resp.StatusCode)
+	}
+	assert.Greater(t, spool.Pending(), int64(0))
+
+	// Rejected batches are not spooled as they would fail again
+	mu.Lock()
+	status = http.StatusForbidden
+	mu.Unlock()
+	pending := spool.Pending()
+	_, err = spooling.StoreResources([]Resource{first}, 1)
+	assert.ErrorIs(t, err, ErrNotAuthorized)
+	assert.Equal(t, pending, spool.Pending())
+
+	// The spooled batch is replayed before the next one
+	mu.Lock()
+	status = http.StatusCreated
+	mu.Unlock()
+	second := validResource
+	second.ID = "second"
+	_, err = spooling.StoreResources([]Resource{second}, 1)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(0), spool.Pending())
+	mu.Lock()
+	assert.Equal(t, []string{"first", "second"}, stored)
+	mu.Unlock()
+
+	sent, err := spooling.ReplaySpool()
+	assert.Nil(t, err)
+	assert.Equal(t, 0, sent)
+}
@@ -0,0 +1,212 @@
+package logging
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Custom index field types
+const (
+	FieldTypeString  = "string"
+	FieldTypeLong    = "long"
+	FieldTypeDouble  = "double"
+	FieldTypeBoolean = "boolean"
+	FieldTypeDate    = "date"
+
+	customIndexPath    = "/core/log/CustomIndex"
+	maxFieldNameLength = 64
+)
+
+var (
+	fieldNameRegex = regexp.MustCompile(`^[a-zA-Z][a-zA-Z0-9_]*(\.[a-zA-Z][a-zA-Z0-9_]*)*$`)
+
+	fieldTypes = map[string]bool{
+		FieldTypeString:  true,
+		FieldTypeLong:    true,
+		FieldTypeDouble:  true,
+		FieldTypeBoolean: true,
+		FieldTypeDate:    true,
+	}
+)
+
+// Validate checks the field name and type
+func (f CustomIndexField) Validate() error {
+	if len(f.Fieldname) > maxFieldNameLength || !fieldNameRegex.MatchString(f.Fieldname) {
+		return fmt.Errorf("%w: %q", ErrInvalidFieldName, f.Fieldname)
+	}
+	if !fieldTypes[f.Fieldtype] {
+		return fmt.Errorf("%w: %q for field %q", ErrInvalidFieldType, f.Fieldtype, f.Fieldname)
+	}
+	return nil
+}
+
+// Validate checks all fields and rejects duplicate field names
+func (b CustomIndexBody) Validate() error {
+	if len(b) == 0 {
+		return ErrNothingToPost
+	}
+	seen := make(map[string]bool, len(b))
+	for _, f := range b {
+		if err := f.Validate(); err != nil {
+			return err
+		}
+		if seen[f.Fieldname] {
+			return fmt.Errorf("%w: %q", ErrDuplicateFieldName, f.Fieldname)
+		}
+		seen[f.Fieldname] = true
+	}
+	return nil
+}
+
+// InferCustomIndex derives index fields from a sample Custom payload. Nested objects
+// are flattened using dotted names, arrays take the type of their first element
+// and null values are skipped. Strings in RFC 3339 format are indexed as dates.
+func InferCustomIndex(custom json.RawMessage) (CustomIndexBody, error) {
+	var sample map[string]interface{}
+	decoder := json.NewDecoder(bytes.NewReader(custom))
+	decoder.UseNumber()
+	if err := decoder.Decode(&sample); err != nil {
+		return nil, err
+	}
+	var body CustomIndexBody
+	inferFields(&body, "", sample)
+	sort.Slice(body, func(i, j int) bool { return body[i].Fieldname < body[j].Fieldname })
+	if err := body.Validate(); err != nil {
+		return nil, err
+	}
+	return body, nil
+}
+
+func inferFields(body *CustomIndexBody, prefix string, object map[string]interface{}) {
+	for key, value := range object {
+		name := key
+		if prefix != "" {
+			name = prefix + "." + key
+		}
+		if array, ok := value.([]interface{}); ok {
+			if len(array) == 0 {
+				continue
+			}
+			value = array[0]
+		}
+		if nested, ok := value.(map[string]interface{}); ok {
+			inferFields(body, name, nested)
+			continue
+		}
+		if fieldType := inferFieldType(value); fieldType != "" {
+			*body = append(*body, CustomIndexField{Fieldname: name, Fieldtype: fieldType})
+		}
+	}
+}
+
+func inferFieldType(value interface{}) string {
+	switch v := value.(type) {
+	case bool:
+		return FieldTypeBoolean
+	case json.Number:
+		if _, err := v.Int64(); err == nil {
+			return FieldTypeLong
+		}
+		return FieldTypeDouble
+	case string:
+		if _, err := time.Parse(time.RFC3339, v); err == nil {
+			return FieldTypeDate
+		}
+		return FieldTypeString
+	default:
+		return ""
+	}
+}
+
+// CustomIndexService manages the custom index fields of the configured product key
+type CustomIndexService struct {
+	client *Client
+}
+
+// CreateCustomIndex adds custom index fields
+func (s *CustomIndexService) CreateCustomIndex(body CustomIndexBody) (*http.Response, error) {
+	if err := body.Validate(); err != nil {
+		return nil, err
+	}
+	return s.client.customIndexRequest(http.MethodPost, nil, body, nil)
+}
+
+// GetCustomIndex lists the custom index fields
+func (s *CustomIndexService) GetCustomIndex() (CustomIndexBody, *http.Response, error) {
+	var body CustomIndexBody
+	resp, err := s.client.customIndexRequest(http.MethodGet, nil, nil, &body)
+	if err != nil {
+		return nil, resp, err
+	}
+	return body, resp, nil
+}
+
+// DeleteCustomIndex removes the custom index field named fieldname
+func (s *CustomIndexService) DeleteCustomIndex(fieldname string) (*http.Response, error) {
+	if len(fieldname) > maxFieldNameLength || !fieldNameRegex.MatchString(fieldname) {
+		return nil, fmt.Errorf("%w: %q", ErrInvalidFieldName, fieldname)
+	}
+	return s.client.customIndexRequest(http.MethodDelete, url.Values{"fieldname": {fieldname}}, nil, nil)
+}
+
+func (c *Client) customIndexRequest(method string, query url.Values, body interface{}, v interface{}) (*http.Response, error) {
+	u, err := url.Parse(strings.TrimSuffix(c.config.BaseURL, "/") + customIndexPath)
+	if err != nil {
+		return nil, err
+	}
+	if query == nil {
+		query = url.Values{}
+	}
+	query.Set("productKey", c.config.ProductKey)
+	u.RawQuery = query.Encode()
+	req := &http.Request{
+		Method:     method,
+		URL:        u,
+		Proto:      "HTTP/1.1",
+		ProtoMajor: 1,
+		ProtoMinor: 1,
+		Header:     make(http.Header),
+		Host:       u.Host,
+	}
+	if body != nil {
+		bodyBytes, err := json.Marshal(body)
+		if err != nil {
+			return nil, err
+		}
+		bodyReader := bytes.NewReader(bodyBytes)
+		req.Body = ioutil.NopCloser(bodyReader)
+		req.ContentLength = int64(bodyReader.Len())
+		req.Header.Set("Content-Type", "application/json")
+	}
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("Api-Version", "1")
+	req.Header.Set("User-Agent", userAgent)
+	if err := c.authorize(req); err != nil {
+		return nil, err
+	}
+
+	var serverResponse bytes.Buffer
+	resp, err := c.do(req, &serverResponse)
+	if err != nil {
+		return resp, err
+	}
+	switch resp.StatusCode {
+	case http.StatusOK, http.StatusCreated, http.StatusNoContent:
+	default:
+		return resp, &ErrorResponse{Response: resp, Message: serverResponse.String()}
+	}
+	if v != nil && serverResponse.Len() > 0 {
+		if err := json.Unmarshal(serverResponse.Bytes(), v); err != nil {
+			return resp, err
+		}
+	}
+	return resp, nil
+}
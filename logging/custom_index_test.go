@@ -0,0 +1,139 @@
+package logging
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"testing"
+
+	signer "github.com/philips-software/go-hsdp-signer"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCustomIndex(t *testing.T) {
+	teardown, err := setup(t, &Config{
+		SharedKey:    sharedKey,
+		SharedSecret: sharedSecret,
+		ProductKey:   productKey,
+		BaseURL:      "http://foo",
+	}, "POST", http.StatusCreated, "")
+	if teardown != nil {
+		defer teardown()
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var stored CustomIndexBody
+	muxLogger.HandleFunc("/core/log/CustomIndex", func(w http.ResponseWriter, r *http.Request) {
+		s, _ := signer.New(sharedKey, sharedSecret)
+		if ok, _ := s.ValidateRequest(r); !ok {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		if r.URL.Query().Get("productKey") != productKey {
+			w.WriteHeader(http.StatusUnprocessableEntity)
+			return
+		}
+		switch r.Method {
+		case http.MethodPost:
+			body, _ := ioutil.ReadAll(r.Body)
+			if err := json.Unmarshal(body, &stored); err != nil {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			w.WriteHeader(http.StatusCreated)
+		case http.MethodGet:
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusOK)
+			_ = json.NewEncoder(w).Encode(stored)
+		case http.MethodDelete:
+			fieldname := r.URL.Query().Get("fieldname")
+			for i, f := range stored {
+				if f.Fieldname == fieldname {
+					stored = append(stored[:i], stored[i+1:]...)
+					w.WriteHeader(http.StatusNoContent)
+					return
+				}
+			}
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = io.WriteString(w, `{"issue": [{"diagnostics": "not found"}]}`)
+		default:
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		}
+	})
+
+	body := CustomIndexBody{
+		{Fieldname: "user.id", Fieldtype: FieldTypeString},
+		{Fieldname: "duration", Fieldtype: FieldTypeLong},
+	}
+	resp, err := client.CustomIndex.CreateCustomIndex(body)
+	if assert.Nil(t, err) && assert.NotNil(t, resp) {
+		assert.Equal(t, http.StatusCreated, resp.StatusCode)
+	}
+
+	fields, _, err := client.CustomIndex.GetCustomIndex()
+	assert.Nil(t, err)
+	assert.Equal(t, body, fields)
+
+	resp, err = client.CustomIndex.DeleteCustomIndex("user.id")
+	if assert.Nil(t, err) {
+		assert.Equal(t, http.StatusNoContent, resp.StatusCode)
+	}
+	fields, _, err = client.CustomIndex.GetCustomIndex()
+	assert.Nil(t, err)
+	assert.Equal(t, CustomIndexBody{{Fieldname: "duration", Fieldtype: FieldTypeLong}}, fields)
+
+	resp, err = client.CustomIndex.DeleteCustomIndex("user.id")
+	assert.NotNil(t, err)
+	if assert.NotNil(t, resp) {
+		assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+	}
+	_, err = client.CustomIndex.DeleteCustomIndex("")
+	assert.True(t, errors.Is(err, ErrInvalidFieldName))
+
+	_, err = client.CustomIndex.CreateCustomIndex(CustomIndexBody{{Fieldname: "1bad", Fieldtype: FieldTypeString}})
+	assert.True(t, errors.Is(err, ErrInvalidFieldName))
+}
+
+func TestCustomIndexValidate(t *testing.T) {
+	assert.Equal(t, ErrNothingToPost, CustomIndexBody{}.Validate())
+	assert.Nil(t, CustomIndexField{Fieldname: "a.b_c", Fieldtype: FieldTypeDate}.Validate())
+	assert.True(t, errors.Is(CustomIndexField{Fieldname: "a..b", Fieldtype: FieldTypeDate}.Validate(), ErrInvalidFieldName))
+	assert.True(t, errors.Is(CustomIndexField{Fieldname: "a", Fieldtype: "keyword"}.Validate(), ErrInvalidFieldType))
+	err := CustomIndexBody{
+		{Fieldname: "a", Fieldtype: FieldTypeLong},
+		{Fieldname: "a", Fieldtype: FieldTypeString},
+	}.Validate()
+	assert.True(t, errors.Is(err, ErrDuplicateFieldName))
+}
+
+func TestInferCustomIndex(t *testing.T) {
+	body, err := InferCustomIndex(json.RawMessage(`{
+		"user": {"id": "u1", "admin": false},
+		"duration": 12,
+		"ratio": 0.5,
+		"at": "2021-06-01T10:00:00Z",
+		"tags": ["a", "b"],
+		"empty": [],
+		"missing": null
+	}`))
+	if !assert.Nil(t, err) {
+		return
+	}
+	assert.Equal(t, CustomIndexBody{
+		{Fieldname: "at", Fieldtype: FieldTypeDate},
+		{Fieldname: "duration", Fieldtype: FieldTypeLong},
+		{Fieldname: "ratio", Fieldtype: FieldTypeDouble},
+		{Fieldname: "tags", Fieldtype: FieldTypeString},
+		{Fieldname: "user.admin", Fieldtype: FieldTypeBoolean},
+		{Fieldname: "user.id", Fieldtype: FieldTypeString},
+	}, body)
+
+	_, err = InferCustomIndex(json.RawMessage(`{"bad key": 1}`))
+	assert.True(t, errors.Is(err, ErrInvalidFieldName))
+	_, err = InferCustomIndex(json.RawMessage(`[1]`))
+	assert.NotNil(t, err)
+}
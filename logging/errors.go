@@ -11,4 +11,15 @@ var (
 	ErrMissingProductKey             = errors.New("missing ProductKey")
 	ErrBatchErrors                   = errors.New("batch errors. check Invalid map for details")
 	ErrResponseError                 = errors.New("unexpected HSDP response error")
+	ErrMissingStorer                 = errors.New("missing storer")
+	ErrQueueFull                     = errors.New("queue full, resource dropped")
+	ErrShipperClosed                 = errors.New("shipper closed")
+	ErrMissingSpoolDir               = errors.New("missing spool directory")
+	ErrSpoolFull                     = errors.New("spool full, batch dropped")
+	ErrSpoolCorrupt                  = errors.New("spool record checksum mismatch")
+	ErrSpooled                       = errors.New("batch not delivered, spooled for replay")
+	ErrInvalidFieldName              = errors.New("invalid custom index field name")
+	ErrInvalidFieldType              = errors.New("invalid custom index field type")
+	ErrDuplicateFieldName            = errors.New("duplicate custom index field name")
+	ErrNotAuthorized                 = errors.New("not authorized")
 )
@@ -0,0 +1,11 @@
+module github.com/philips-software/go-hsdp-api/logging/logrushook
+
+go 1.16
+
+require (
+	github.com/philips-software/go-hsdp-api v0.40.1
+	github.com/sirupsen/logrus v1.8.1
+	github.com/stretchr/testify v1.7.0
+)
+
+replace github.com/philips-software/go-hsdp-api => ../..
@@ -0,0 +1,65 @@
+// Package logrushook provides a logrus hook which ships entries to HSDP logging
+package logrushook
+
+import (
+	"github.com/philips-software/go-hsdp-api/logging"
+	"github.com/sirupsen/logrus"
+)
+
+// Hook is a logrus.Hook which ships entries as resources
+type Hook struct {
+	sink   logging.Sink
+	config logging.AdapterConfig
+	levels []logrus.Level
+}
+
+// New returns a Hook shipping entries at the given levels to sink.
+// All levels are shipped when none are given
+func New(sink logging.Sink, config logging.AdapterConfig, levels ...logrus.Level) *Hook {
+	if len(levels) == 0 {
+		levels = logrus.AllLevels
+	}
+	return &Hook{
+		sink:   sink,
+		config: config,
+		levels: levels,
+	}
+}
+
+// Severity maps a logrus level to a Severity
+func Severity(level logrus.Level) string {
+	switch level {
+	case logrus.PanicLevel, logrus.FatalLevel:
+		return logging.SeverityCritical
+	case logrus.ErrorLevel:
+		return logging.SeverityError
+	case logrus.WarnLevel:
+		return logging.SeverityWarning
+	case logrus.InfoLevel:
+		return logging.SeverityInfo
+	default:
+		return logging.SeverityDebug
+	}
+}
+
+// Levels implements logrus.Hook
+func (h *Hook) Levels() []logrus.Level {
+	return h.levels
+}
+
+// Fire implements logrus.Hook
+func (h *Hook) Fire(entry *logrus.Entry) error {
+	attrs := make(map[string]interface{}, len(entry.Data))
+	for k, v := range entry.Data {
+		if err, ok := v.(error); ok {
+			v = err.Error()
+		}
+		attrs[k] = v
+	}
+	r := h.config.NewResource(entry.Time, Severity(entry.Level), entry.Message, attrs)
+	err := h.sink.Ship(r)
+	if err != nil && h.config.OnError != nil {
+		h.config.OnError(err)
+	}
+	return err
+}
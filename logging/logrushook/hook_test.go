@@ -0,0 +1,49 @@
+package logrushook
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"sync"
+	"testing"
+
+	"github.com/philips-software/go-hsdp-api/logging"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+type recordingSink struct {
+	sync.Mutex
+	resources []logging.Resource
+}
+
+func (s *recordingSink) Ship(resource logging.Resource) error {
+	s.Lock()
+	defer s.Unlock()
+	s.resources = append(s.resources, resource)
+	return nil
+}
+
+func TestHook(t *testing.T) {
+	sink := &recordingSink{}
+	logger := logrus.New()
+	logger.SetOutput(ioutil.Discard)
+	logger.AddHook(New(sink, logging.AdapterConfig{ApplicationName: "app"}, logrus.WarnLevel, logrus.ErrorLevel))
+
+	logger.Info("ignored")
+	logger.WithField("transactionId", "abc").WithField("user", "ron").Warn("careful")
+
+	if !assert.Len(t, sink.resources, 1) {
+		return
+	}
+	r := sink.resources[0]
+	assert.Equal(t, logging.SeverityWarning, r.Severity)
+	assert.Equal(t, "careful", r.LogData.Message)
+	assert.Equal(t, "abc", r.TransactionID)
+	assert.Equal(t, "app", r.ApplicationName)
+	var custom map[string]interface{}
+	assert.Nil(t, json.Unmarshal(r.Custom, &custom))
+	assert.Equal(t, map[string]interface{}{"user": "ron"}, custom)
+
+	assert.Equal(t, logging.SeverityCritical, Severity(logrus.FatalLevel))
+	assert.Equal(t, logging.SeverityDebug, Severity(logrus.TraceLevel))
+}
@@ -0,0 +1,284 @@
+package logging
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// SanitizeMode determines how characters rejected by the ingestor are handled
+type SanitizeMode int
+
+const (
+	// SanitizeReplace replaces characters in Custom using a fixed, lossy mapping, e.g. ";" becomes "[sc]"
+	SanitizeReplace SanitizeMode = iota
+	// SanitizeEncode percent-encodes characters in the message and Custom keys and string values. Use DecodeResource to reverse it
+	SanitizeEncode
+	// SanitizeNone leaves characters as they are
+	SanitizeNone
+)
+
+const (
+	// DefaultMask replaces masked values
+	DefaultMask = "[masked]"
+
+	messageField = "logData.message"
+	customField  = "custom"
+)
+
+var (
+	// EmailPattern matches email addresses
+	EmailPattern = regexp.MustCompile(`[A-Za-z0-9._%+\-]+@[A-Za-z0-9.\-]+\.[A-Za-z]{2,}`)
+	// SSNPattern matches US social security numbers
+	SSNPattern = regexp.MustCompile(`\b\d{3}-\d{2}-\d{4}\b`)
+
+	// DefaultSanitizer replaces characters in Custom, as done before sanitizers were configurable
+	DefaultSanitizer Sanitizer = &SanitizePolicy{Mode: SanitizeReplace}
+)
+
+// Sanitizer modifies a resource before it is stored and returns the names of the
+// fields it altered. The message is reported as "logData.message" and Custom
+// values by their dotted path, e.g. "custom.user.email"
+type Sanitizer interface {
+	Sanitize(r *Resource) []string
+}
+
+// SanitizePolicy is a configurable Sanitizer. Masking is applied first,
+// then length limits and finally the SanitizeMode.
+type SanitizePolicy struct {
+	Mode SanitizeMode
+	// MaskEmails and MaskSSNs mask email addresses and social security numbers
+	MaskEmails bool
+	MaskSSNs   bool
+	// MaskPatterns are additional patterns to mask
+	MaskPatterns []*regexp.Regexp
+	// Mask replaces masked values. Defaults to DefaultMask
+	Mask string
+	// MaxMessageLength limits the message length in bytes. Zero means no limit
+	MaxMessageLength int
+	// MaxCustomValueLength limits the length of Custom string values in bytes. Zero means no limit
+	MaxCustomValueLength int
+}
+
+// Sanitize implements Sanitizer
+func (p *SanitizePolicy) Sanitize(r *Resource) []string {
+	var altered []string
+
+	message := p.sanitizeValue(r.LogData.Message, p.MaxMessageLength, p.Mode == SanitizeEncode)
+	if message != r.LogData.Message {
+		r.LogData.Message = message
+		altered = append(altered, messageField)
+	}
+	if len(r.Custom) == 0 {
+		return altered
+	}
+	if p.MaskEmails || p.MaskSSNs || len(p.MaskPatterns) > 0 || p.MaxCustomValueLength > 0 || p.Mode == SanitizeEncode {
+		if custom, fields, ok := p.sanitizeCustom(r.Custom); ok && len(fields) > 0 {
+			r.Custom = custom
+			altered = append(altered, fields...)
+		}
+	}
+	if p.Mode == SanitizeReplace {
+		before := string(r.Custom)
+		replaceScaryCharacters(r)
+		if string(r.Custom) != before && !containsPrefix(altered, customField) {
+			altered = append(altered, customField)
+		}
+	}
+	return altered
+}
+
+// sanitizeCustom applies the policy to every string value of custom
+func (p *SanitizePolicy) sanitizeCustom(custom json.RawMessage) (json.RawMessage, []string, bool) {
+	var value interface{}
+	decoder := json.NewDecoder(bytes.NewReader(custom))
+	decoder.UseNumber()
+	if err := decoder.Decode(&value); err != nil {
+		// Leave invalid JSON for Resource.Valid to report
+		return nil, nil, false
+	}
+	var fields []string
+	value = p.walk(value, customField, &fields)
+	if len(fields) == 0 {
+		return nil, nil, true
+	}
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(value); err != nil {
+		return nil, nil, false
+	}
+	return bytes.TrimRight(buf.Bytes(), "\n"), uniqueSorted(fields), true
+}
+
+func (p *SanitizePolicy) walk(value interface{}, path string, fields *[]string) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		sanitized := make(map[string]interface{}, len(v))
+		for k, e := range v {
+			key := k
+			if p.Mode == SanitizeEncode {
+				if key = EncodeValue(k); key != k {
+					*fields = append(*fields, path+"."+k)
+				}
+			}
+			sanitized[key] = p.walk(e, path+"."+k, fields)
+		}
+		return sanitized
+	case []interface{}:
+		for i, e := range v {
+			v[i] = p.walk(e, fmt.Sprintf("%s.%d", path, i), fields)
+		}
+		return v
+	case string:
+		sanitized := p.sanitizeValue(v, p.MaxCustomValueLength, p.Mode == SanitizeEncode)
+		if sanitized != v {
+			*fields = append(*fields, path)
+		}
+		return sanitized
+	default:
+		return value
+	}
+}
+
+func (p *SanitizePolicy) sanitizeValue(s string, maxLength int, encode bool) string {
+	mask := p.Mask
+	if mask == "" {
+		mask = DefaultMask
+	}
+	if p.MaskEmails {
+		s = EmailPattern.ReplaceAllString(s, mask)
+	}
+	if p.MaskSSNs {
+		s = SSNPattern.ReplaceAllString(s, mask)
+	}
+	for _, pattern := range p.MaskPatterns {
+		s = pattern.ReplaceAllString(s, mask)
+	}
+	if maxLength > 0 {
+		s = truncate(s, maxLength)
+	}
+	if encode {
+		s = EncodeValue(s)
+	}
+	return s
+}
+
+// truncate shortens s to at most n bytes without splitting a character
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n]
+}
+
+// EncodeValue percent-encodes "%", the characters rejected by the ingestor and
+// control characters. DecodeValue reverses it
+func EncodeValue(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		switch {
+		case r == '%', r == ';', r == '&', r == '<', r == '>', r == '"', r == '\\',
+			r < 0x20, r == 0x7f, r == '\u2028', r == '\u2029':
+			var buf [utf8.UTFMax]byte
+			n := utf8.EncodeRune(buf[:], r)
+			for _, c := range buf[:n] {
+				fmt.Fprintf(&b, "%%%02X", c)
+			}
+		default:
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// DecodeValue reverses EncodeValue
+func DecodeValue(s string) (string, error) {
+	return url.PathUnescape(s)
+}
+
+// DecodeResource reverses SanitizeEncode on the message and Custom keys and string values of r
+func DecodeResource(r *Resource) error {
+	message, err := DecodeValue(r.LogData.Message)
+	if err != nil {
+		return err
+	}
+	r.LogData.Message = message
+	if len(r.Custom) == 0 {
+		return nil
+	}
+	var value interface{}
+	decoder := json.NewDecoder(bytes.NewReader(r.Custom))
+	decoder.UseNumber()
+	if err := decoder.Decode(&value); err != nil {
+		return err
+	}
+	value, err = decodeValues(value)
+	if err != nil {
+		return err
+	}
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(value); err != nil {
+		return err
+	}
+	r.Custom = bytes.TrimRight(buf.Bytes(), "\n")
+	return nil
+}
+
+func decodeValues(value interface{}) (interface{}, error) {
+	var err error
+	switch v := value.(type) {
+	case map[string]interface{}:
+		decoded := make(map[string]interface{}, len(v))
+		for k, e := range v {
+			key, err := DecodeValue(k)
+			if err != nil {
+				return nil, err
+			}
+			if decoded[key], err = decodeValues(e); err != nil {
+				return nil, err
+			}
+		}
+		return decoded, nil
+	case []interface{}:
+		for i, e := range v {
+			if v[i], err = decodeValues(e); err != nil {
+				return nil, err
+			}
+		}
+	case string:
+		return DecodeValue(v)
+	}
+	return value, nil
+}
+
+// uniqueSorted sorts fields and removes duplicates, e.g. a field whose key and value were both altered
+func uniqueSorted(fields []string) []string {
+	sort.Strings(fields)
+	unique := fields[:0]
+	for i, f := range fields {
+		if i == 0 || f != fields[i-1] {
+			unique = append(unique, f)
+		}
+	}
+	return unique
+}
+
+func containsPrefix(fields []string, prefix string) bool {
+	for _, f := range fields {
+		if strings.HasPrefix(f, prefix) {
+			return true
+		}
+	}
+	return false
+}
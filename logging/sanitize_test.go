@@ -0,0 +1,94 @@
+package logging
+
+import (
+	"encoding/json"
+	"net/http"
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSanitizeReplace(t *testing.T) {
+	r := validResource
+	r.LogData.Message = "a;b"
+	r.Custom = json.RawMessage(`{"q": "x;y"}`)
+
+	altered := DefaultSanitizer.Sanitize(&r)
+	assert.Equal(t, []string{"custom"}, altered)
+	assert.Equal(t, "a;b", r.LogData.Message)
+	assert.Equal(t, `{"q": "x[sc]y"}`, string(r.Custom))
+
+	r.Custom = json.RawMessage(`{"q": "clean"}`)
+	assert.Empty(t, DefaultSanitizer.Sanitize(&r))
+}
+
+func TestSanitizeEncode(t *testing.T) {
+	policy := &SanitizePolicy{Mode: SanitizeEncode}
+	r := validResource
+	r.LogData.Message = `100% <done>; "ok" & \ fine`
+	r.Custom = json.RawMessage(`{"user": {"query": "a=1&b=2", "<tag>": 1}, "list": ["x;y", 1], "n": 3, "a;b": "c;d"}`)
+
+	altered := policy.Sanitize(&r)
+	assert.Equal(t, []string{"logData.message", "custom.a;b", "custom.list.0", "custom.user.<tag>", "custom.user.query"}, altered)
+	assert.NotContains(t, r.LogData.Message, ";")
+	assert.NotContains(t, string(r.Custom), "&")
+	assert.NotContains(t, string(r.Custom), ";")
+	assert.NotContains(t, string(r.Custom), "<")
+	assert.True(t, r.Valid())
+
+	assert.Nil(t, DecodeResource(&r))
+	assert.Equal(t, `100% <done>; "ok" & \ fine`, r.LogData.Message)
+	assert.JSONEq(t, `{"user": {"query": "a=1&b=2", "<tag>": 1}, "list": ["x;y", 1], "n": 3, "a;b": "c;d"}`, string(r.Custom))
+
+	for _, s := range []string{"", "plain", "%%", "line\nbreak ", "ünïcode €"} {
+		decoded, err := DecodeValue(EncodeValue(s))
+		assert.Nil(t, err)
+		assert.Equal(t, s, decoded)
+	}
+}
+
+func TestSanitizeMaskAndTruncate(t *testing.T) {
+	policy := &SanitizePolicy{
+		Mode:                 SanitizeNone,
+		MaskEmails:           true,
+		MaskSSNs:             true,
+		MaskPatterns:         []*regexp.Regexp{regexp.MustCompile(`token=\w+`)},
+		MaxMessageLength:     40,
+		MaxCustomValueLength: 4,
+	}
+	r := validResource
+	r.LogData.Message = "mail ron@example.com ssn 123-45-6789 token=abc"
+	r.Custom = json.RawMessage(`{"name": "Ronald", "id": 42, "ok": "yes"}`)
+
+	altered := policy.Sanitize(&r)
+	assert.Equal(t, []string{"logData.message", "custom.name"}, altered)
+	assert.Equal(t, "mail [masked] ssn [masked] [masked]", r.LogData.Message)
+	assert.JSONEq(t, `{"name": "Rona", "id": 42, "ok": "yes"}`, string(r.Custom))
+
+	assert.Equal(t, "é", truncate("éé", 3))
+}
+
+func TestStoreResourcesSanitized(t *testing.T) {
+	teardown, err := setup(t, &Config{
+		SharedKey:    sharedKey,
+		SharedSecret: sharedSecret,
+		ProductKey:   productKey,
+		BaseURL:      "http://foo",
+		Sanitizer:    &SanitizePolicy{Mode: SanitizeEncode, MaskEmails: true},
+	}, "POST", http.StatusCreated, "")
+	if teardown != nil {
+		defer teardown()
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	clean := validResource
+	dirty := validResource
+	dirty.LogData.Message = "user ron@example.com logged in"
+	resp, err := client.StoreResources([]Resource{clean, dirty}, 2)
+	if !assert.Nil(t, err) {
+		return
+	}
+	assert.Equal(t, map[int][]string{1: {"logData.message"}}, resp.Sanitized)
+}
@@ -0,0 +1,424 @@
+package logging
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// DefaultQueueSize is the default number of resources the Shipper buffers
+	DefaultQueueSize = 10000
+	// DefaultBatchSize is the default maximum number of resources per ingestor call
+	DefaultBatchSize = 25
+	// DefaultMaxBatchBytes is the default maximum encoded size of a single batch
+	DefaultMaxBatchBytes = 1024 * 1024
+	// DefaultFlushInterval is the default interval at which buffered resources are shipped
+	DefaultFlushInterval = 5 * time.Second
+	// DefaultMaxRetries is the default number of times a batch is resubmitted after a failure
+	DefaultMaxRetries = 3
+	// DefaultRetryBackoff is the default wait time before the first resubmission
+	DefaultRetryBackoff = 500 * time.Millisecond
+
+	// bundleOverhead is a conservative estimate of the size of the Bundle wrapper
+	bundleOverhead = 256
+)
+
+// DropPolicy determines what happens when a resource is shipped while the queue is full
+type DropPolicy int
+
+const (
+	// DropNewest rejects the resource being shipped
+	DropNewest DropPolicy = iota
+	// DropOldest evicts the oldest queued resource to make room
+	DropOldest
+	// Block makes Ship wait until there is room in the queue
+	Block
+)
+
+// ShipperConfig configures a Shipper. Zero values are replaced by their defaults
+type ShipperConfig struct {
+	QueueSize     int
+	BatchSize     int
+	MaxBatchBytes int
+	FlushInterval time.Duration
+	DropPolicy    DropPolicy
+	// MaxRetries is the number of resubmissions after a failed call. Use a negative value to disable
+	MaxRetries   int
+	RetryBackoff time.Duration
+	// Spool, when set, stores resources which could not be delivered after
+	// all retries. They are replayed at each flush interval.
+	Spool *Spool
+	// OnFailure is called with resources which could not be delivered
+	// after all retries and could not be spooled. Resources flagged as invalid
+	// by the ingestor are passed with ErrBatchErrors; they are not retried.
+	OnFailure func(resources []Resource, err error)
+}
+
+// ShipperStats holds the counters of a Shipper
+type ShipperStats struct {
+	Sent    uint64
+	Dropped uint64
+	Failed  uint64
+	Spooled uint64
+	Queued  int
+}
+
+// Shipper asynchronously ships resources to a Storer in batches
+type Shipper struct {
+	// counters are accessed atomically and kept first for alignment
+	sent    uint64
+	dropped uint64
+	failed  uint64
+	spooled uint64
+
+	storer Storer
+	config ShipperConfig
+
+	mu      sync.Mutex
+	notFull *sync.Cond
+	queue   []Resource
+	closed  bool
+
+	notify  chan struct{}
+	hurry   chan struct{}
+	flushes chan chan struct{}
+	done    chan struct{}
+	stopped chan struct{}
+}
+
+// NewShipper starts a Shipper which ships resources through storer
+func NewShipper(storer Storer, config ShipperConfig) (*Shipper, error) {
+	if storer == nil {
+		return nil, ErrMissingStorer
+	}
+	if config.QueueSize <= 0 {
+		config.QueueSize = DefaultQueueSize
+	}
+	if config.BatchSize <= 0 {
+		config.BatchSize = DefaultBatchSize
+	}
+	if config.MaxBatchBytes <= 0 {
+		config.MaxBatchBytes = DefaultMaxBatchBytes
+	}
+	if config.FlushInterval <= 0 {
+		config.FlushInterval = DefaultFlushInterval
+	}
+	if config.MaxRetries < 0 {
+		config.MaxRetries = 0
+	} else if config.MaxRetries == 0 {
+		config.MaxRetries = DefaultMaxRetries
+	}
+	if config.RetryBackoff <= 0 {
+		config.RetryBackoff = DefaultRetryBackoff
+	}
+	s := &Shipper{
+		storer:  storer,
+		config:  config,
+		notify:  make(chan struct{}, 1),
+		hurry:   make(chan struct{}, 1),
+		flushes: make(chan chan struct{}),
+		done:    make(chan struct{}),
+		stopped: make(chan struct{}),
+	}
+	s.notFull = sync.NewCond(&s.mu)
+	go s.run()
+	return s, nil
+}
+
+// Ship queues a resource for shipping. It does not block unless the
+// DropPolicy is Block and the queue is full. ErrQueueFull is returned
+// when the resource was dropped and ErrShipperClosed after Close.
+func (s *Shipper) Ship(resource Resource) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return ErrShipperClosed
+	}
+	for len(s.queue) >= s.config.QueueSize {
+		switch s.config.DropPolicy {
+		case DropOldest:
+			s.dequeue(1)
+			atomic.AddUint64(&s.dropped, 1)
+		case Block:
+			s.notFull.Wait()
+			if s.closed {
+				return ErrShipperClosed
+			}
+			continue
+		default:
+			atomic.AddUint64(&s.dropped, 1)
+			return ErrQueueFull
+		}
+	}
+	s.queue = append(s.queue, resource)
+	if len(s.queue) >= s.config.BatchSize {
+		select {
+		case s.notify <- struct{}{}:
+		default:
+		}
+	}
+	return nil
+}
+
+// Flush ships all queued resources and waits until they are processed or ctx is done
+func (s *Shipper) Flush(ctx context.Context) error {
+	flushed := make(chan struct{})
+	// Cut a pending retry backoff short
+	select {
+	case s.hurry <- struct{}{}:
+	default:
+	}
+	select {
+	case s.flushes <- flushed:
+	case <-s.stopped:
+		return ErrShipperClosed
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	select {
+	case <-flushed:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Close stops accepting resources, ships what is queued and stops the Shipper.
+// If ctx is done before all resources are shipped its error is returned and
+// the remaining resources are shipped in the background.
+func (s *Shipper) Close(ctx context.Context) error {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return ErrShipperClosed
+	}
+	s.closed = true
+	s.notFull.Broadcast()
+	s.mu.Unlock()
+
+	close(s.done)
+	select {
+	case <-s.stopped:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Stats returns a snapshot of the Shipper counters
+func (s *Shipper) Stats() ShipperStats {
+	s.mu.Lock()
+	queued := len(s.queue)
+	s.mu.Unlock()
+	return ShipperStats{
+		Sent:    atomic.LoadUint64(&s.sent),
+		Dropped: atomic.LoadUint64(&s.dropped),
+		Failed:  atomic.LoadUint64(&s.failed),
+		Spooled: atomic.LoadUint64(&s.spooled),
+		Queued:  queued,
+	}
+}
+
+func (s *Shipper) run() {
+	defer close(s.stopped)
+
+	ticker := time.NewTicker(s.config.FlushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.notify:
+			s.ship(false)
+		case <-ticker.C:
+			s.replay()
+			s.ship(true)
+		case flushed := <-s.flushes:
+			select {
+			case <-s.hurry:
+			default:
+			}
+			s.replay()
+			s.ship(true)
+			close(flushed)
+		case <-s.done:
+			s.replay()
+			s.ship(true)
+			return
+		}
+	}
+}
+
+// ship sends queued resources in batches, one per product key. Unless all is set
+// only complete batches are sent.
+func (s *Shipper) ship(all bool) {
+	for {
+		s.mu.Lock()
+		n := len(s.queue)
+		if n == 0 || (!all && n < s.config.BatchSize) {
+			s.mu.Unlock()
+			return
+		}
+		if n > s.config.BatchSize {
+			n = s.config.BatchSize
+		}
+		batch := make([]Resource, n)
+		copy(batch, s.queue)
+		s.dequeue(n)
+		s.notFull.Broadcast()
+		s.mu.Unlock()
+
+		for _, group := range groupByProductKey(batch, "") {
+			for _, b := range splitBatch(group.resources, s.config.MaxBatchBytes) {
+				s.send(b)
+			}
+		}
+	}
+}
+
+// send stores a batch, retrying with backoff on errors other than flagged entries.
+// Batches which cannot be delivered are spooled when a Spool is configured.
+func (s *Shipper) send(batch []Resource) {
+	backoff := s.config.RetryBackoff
+	retries := 0
+	for {
+		remaining, flagged, err := deliver(s.storer, batch)
+		atomic.AddUint64(&s.failed, uint64(len(flagged)))
+		atomic.AddUint64(&s.sent, uint64(len(batch)-len(remaining)-len(flagged)))
+		if len(flagged) > 0 && s.config.OnFailure != nil {
+			s.config.OnFailure(flagged, ErrBatchErrors)
+		}
+		if err == nil {
+			return
+		}
+		batch = remaining
+		// The Storer spooled the batch itself, see Config.Spool
+		if errors.Is(err, ErrSpooled) {
+			atomic.AddUint64(&s.spooled, uint64(len(batch)))
+			return
+		}
+		if retries >= s.config.MaxRetries {
+			if s.config.Spool != nil && s.config.Spool.Append(batch) == nil {
+				atomic.AddUint64(&s.spooled, uint64(len(batch)))
+				return
+			}
+			atomic.AddUint64(&s.failed, uint64(len(batch)))
+			if s.config.OnFailure != nil {
+				s.config.OnFailure(batch, err)
+			}
+			return
+		}
+		retries++
+		s.wait(backoff)
+		backoff *= 2
+	}
+}
+
+// dequeue removes the first n resources from the queue. The queue is resliced
+// rather than copied; append compacts it when it runs out of capacity. The
+// slots are cleared so the resources can be collected.
+func (s *Shipper) dequeue(n int) {
+	for i := 0; i < n; i++ {
+		s.queue[i] = Resource{}
+	}
+	s.queue = s.queue[n:]
+	if len(s.queue) == 0 {
+		s.queue = nil
+	}
+}
+
+// wait sleeps for d unless the Shipper is flushed or closed in the meantime
+func (s *Shipper) wait(d time.Duration) {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+	case <-s.hurry:
+	case <-s.done:
+	}
+}
+
+// replay ships spooled batches once the ingestor is reachable again
+func (s *Shipper) replay() {
+	if s.config.Spool == nil || s.config.Spool.Pending() == 0 {
+		return
+	}
+	sent, _ := s.config.Spool.Replay(s.storer)
+	atomic.AddUint64(&s.sent, uint64(sent))
+}
+
+// deliver stores batch, resubmitting the remainder when entries are flagged.
+// It returns the resources which were not delivered, the flagged resources
+// and the error which stopped delivery.
+func deliver(storer Storer, batch []Resource) ([]Resource, []Resource, error) {
+	var flagged []Resource
+	for len(batch) > 0 {
+		resp, err := storer.StoreResources(batch, len(batch))
+		if err == nil {
+			return nil, flagged, nil
+		}
+		if errors.Is(err, ErrBatchErrors) && resp != nil && len(resp.Failed) > 0 {
+			var failed []Resource
+			batch, failed = withoutFailed(batch, resp.Failed)
+			flagged = append(flagged, failed...)
+			continue
+		}
+		return batch, flagged, err
+	}
+	return nil, flagged, nil
+}
+
+// withoutFailed splits batch in the resources which are not in failed and those which are
+func withoutFailed(batch []Resource, failed map[int]Resource) ([]Resource, []Resource) {
+	var indices []int
+	for i := range failed {
+		indices = append(indices, i)
+	}
+	sort.Ints(indices)
+	remaining := make([]Resource, 0, len(batch))
+	flagged := make([]Resource, 0, len(indices))
+	for i, r := range batch {
+		if j := sort.SearchInts(indices, i); j < len(indices) && indices[j] == i {
+			flagged = append(flagged, r)
+			continue
+		}
+		remaining = append(remaining, r)
+	}
+	return remaining, flagged
+}
+
+// splitBatch splits resources so the encoded size of each batch stays below maxBytes.
+// A single resource exceeding maxBytes is put in a batch of its own.
+func splitBatch(resources []Resource, maxBytes int) [][]Resource {
+	var batches [][]Resource
+	var current []Resource
+	size := bundleOverhead
+	for _, r := range resources {
+		rs := resourceSize(r)
+		if len(current) > 0 && size+rs > maxBytes {
+			batches = append(batches, current)
+			current = nil
+			size = bundleOverhead
+		}
+		current = append(current, r)
+		size += rs
+	}
+	if len(current) > 0 {
+		batches = append(batches, current)
+	}
+	return batches
+}
+
+// resourceSize estimates the encoded size of a resource including base64 encoding of the message
+func resourceSize(r Resource) int {
+	data, err := json.Marshal(r)
+	if err != nil {
+		return 0
+	}
+	return len(data) + len(r.LogData.Message)/3 + 4
+}
@@ -0,0 +1,290 @@
+package logging
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeStorer struct {
+	sync.Mutex
+	calls   [][]Resource
+	flag    func(msgs []Resource) map[int]Resource
+	failFor int
+	err     error
+}
+
+func (f *fakeStorer) StoreResources(msgs []Resource, count int) (*StoreResponse, error) {
+	f.Lock()
+	defer f.Unlock()
+	batch := make([]Resource, count)
+	copy(batch, msgs[:count])
+	f.calls = append(f.calls, batch)
+	if f.failFor > 0 {
+		f.failFor--
+		return nil, f.err
+	}
+	if f.flag != nil {
+		if failed := f.flag(batch); len(failed) > 0 {
+			return &StoreResponse{
+				Response: &http.Response{StatusCode: http.StatusBadRequest},
+				Failed:   failed,
+			}, ErrBatchErrors
+		}
+	}
+	return &StoreResponse{Response: &http.Response{StatusCode: http.StatusCreated}}, nil
+}
+
+func (f *fakeStorer) Calls() [][]Resource {
+	f.Lock()
+	defer f.Unlock()
+	return f.calls
+}
+
+func resourceWithID(id string) Resource {
+	r := validResource
+	r.ID = id
+	return r
+}
+
+func TestShipperBatching(t *testing.T) {
+	storer := &fakeStorer{}
+	shipper, err := NewShipper(storer, ShipperConfig{
+		BatchSize:     3,
+		FlushInterval: time.Hour,
+	})
+	if !assert.Nil(t, err) {
+		return
+	}
+	for i := 0; i < 7; i++ {
+		assert.Nil(t, shipper.Ship(resourceWithID(string(rune('a'+i)))))
+	}
+	assert.Nil(t, shipper.Flush(context.Background()))
+
+	calls := storer.Calls()
+	total := 0
+	for _, c := range calls {
+		assert.LessOrEqual(t, len(c), 3)
+		total += len(c)
+	}
+	assert.Equal(t, 7, total)
+	stats := shipper.Stats()
+	assert.Equal(t, uint64(7), stats.Sent)
+	assert.Equal(t, 0, stats.Queued)
+
+	assert.Nil(t, shipper.Close(context.Background()))
+	assert.Equal(t, ErrShipperClosed, shipper.Ship(validResource))
+	assert.Equal(t, ErrShipperClosed, shipper.Flush(context.Background()))
+}
+
+func TestShipperFlushInterval(t *testing.T) {
+	storer := &fakeStorer{}
+	shipper, err := NewShipper(storer, ShipperConfig{
+		FlushInterval: 10 * time.Millisecond,
+	})
+	if !assert.Nil(t, err) {
+		return
+	}
+	defer shipper.Close(context.Background())
+
+	assert.Nil(t, shipper.Ship(validResource))
+	assert.Eventually(t, func() bool {
+		return shipper.Stats().Sent == 1
+	}, time.Second, 5*time.Millisecond)
+}
+
+func TestShipperResubmitsRemainder(t *testing.T) {
+	storer := &fakeStorer{
+		flag: func(msgs []Resource) map[int]Resource {
+			failed := make(map[int]Resource)
+			for i, m := range msgs {
+				if m.ID == "bad" {
+					failed[i] = m
+				}
+			}
+			return failed
+		},
+	}
+	var failed []Resource
+	var failedErr error
+	shipper, err := NewShipper(storer, ShipperConfig{
+		FlushInterval: time.Hour,
+		OnFailure: func(resources []Resource, err error) {
+			failed = append(failed, resources...)
+			failedErr = err
+		},
+	})
+	if !assert.Nil(t, err) {
+		return
+	}
+	_ = shipper.Ship(resourceWithID("one"))
+	_ = shipper.Ship(resourceWithID("bad"))
+	_ = shipper.Ship(resourceWithID("two"))
+	assert.Nil(t, shipper.Close(context.Background()))
+
+	calls := storer.Calls()
+	if !assert.Len(t, calls, 2) {
+		return
+	}
+	assert.Len(t, calls[0], 3)
+	if assert.Len(t, calls[1], 2) {
+		assert.Equal(t, "one", calls[1][0].ID)
+		assert.Equal(t, "two", calls[1][1].ID)
+	}
+	stats := shipper.Stats()
+	assert.Equal(t, uint64(2), stats.Sent)
+	assert.Equal(t, uint64(1), stats.Failed)
+	if assert.Len(t, failed, 1) {
+		assert.Equal(t, "bad", failed[0].ID)
+	}
+	assert.Equal(t, ErrBatchErrors, failedErr)
+}
+
+func TestShipperRetries(t *testing.T) {
+	storer := &fakeStorer{failFor: 10, err: errors.New("connection refused")}
+	var failed []Resource
+	shipper, err := NewShipper(storer, ShipperConfig{
+		FlushInterval: time.Hour,
+		MaxRetries:    2,
+		RetryBackoff:  time.Millisecond,
+		OnFailure: func(resources []Resource, err error) {
+			failed = append(failed, resources...)
+		},
+	})
+	if !assert.Nil(t, err) {
+		return
+	}
+	_ = shipper.Ship(validResource)
+	assert.Nil(t, shipper.Close(context.Background()))
+
+	assert.Len(t, storer.Calls(), 3)
+	assert.Len(t, failed, 1)
+	assert.Equal(t, uint64(1), shipper.Stats().Failed)
+}
+
+func TestShipperStorerSpooled(t *testing.T) {
+	storer := &fakeStorer{failFor: 1, err: fmt.Errorf("unavailable: %w", ErrSpooled)}
+	shipper, err := NewShipper(storer, ShipperConfig{
+		FlushInterval: time.Hour,
+		MaxRetries:    2,
+		RetryBackoff:  time.Millisecond,
+	})
+	if !assert.Nil(t, err) {
+		return
+	}
+	_ = shipper.Ship(validResource)
+	assert.Nil(t, shipper.Close(context.Background()))
+
+	// Batches spooled by the Storer are not retried
+	assert.Len(t, storer.Calls(), 1)
+	stats := shipper.Stats()
+	assert.Equal(t, uint64(1), stats.Spooled)
+	assert.Equal(t, uint64(0), stats.Failed)
+}
+
+func TestShipperCloseSkipsBackoff(t *testing.T) {
+	storer := &fakeStorer{failFor: 100, err: errors.New("unavailable")}
+	var failed []Resource
+	shipper, err := NewShipper(storer, ShipperConfig{
+		BatchSize:     1,
+		FlushInterval: time.Hour,
+		MaxRetries:    2,
+		RetryBackoff:  time.Hour,
+		OnFailure: func(resources []Resource, err error) {
+			failed = append(failed, resources...)
+		},
+	})
+	if !assert.Nil(t, err) {
+		return
+	}
+	assert.Nil(t, shipper.Ship(resourceWithID("a")))
+	assert.Eventually(t, func() bool { return len(storer.Calls()) == 1 }, time.Second, time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	assert.Nil(t, shipper.Close(ctx))
+	assert.Len(t, storer.Calls(), 3)
+	assert.Len(t, failed, 1)
+}
+
+func TestShipperDropPolicies(t *testing.T) {
+	storer := &fakeStorer{}
+	shipper, err := NewShipper(storer, ShipperConfig{
+		QueueSize:     2,
+		BatchSize:     10,
+		FlushInterval: time.Hour,
+	})
+	if !assert.Nil(t, err) {
+		return
+	}
+	assert.Nil(t, shipper.Ship(resourceWithID("1")))
+	assert.Nil(t, shipper.Ship(resourceWithID("2")))
+	assert.Equal(t, ErrQueueFull, shipper.Ship(resourceWithID("3")))
+	assert.Nil(t, shipper.Close(context.Background()))
+	assert.Equal(t, uint64(1), shipper.Stats().Dropped)
+	calls := storer.Calls()
+	if assert.Len(t, calls, 1) {
+		assert.Equal(t, "1", calls[0][0].ID)
+	}
+
+	storer = &fakeStorer{}
+	shipper, err = NewShipper(storer, ShipperConfig{
+		QueueSize:     2,
+		BatchSize:     10,
+		FlushInterval: time.Hour,
+		DropPolicy:    DropOldest,
+	})
+	if !assert.Nil(t, err) {
+		return
+	}
+	assert.Nil(t, shipper.Ship(resourceWithID("1")))
+	assert.Nil(t, shipper.Ship(resourceWithID("2")))
+	assert.Nil(t, shipper.Ship(resourceWithID("3")))
+	assert.Nil(t, shipper.Close(context.Background()))
+	assert.Equal(t, uint64(1), shipper.Stats().Dropped)
+	calls = storer.Calls()
+	if assert.Len(t, calls, 1) {
+		assert.Equal(t, "2", calls[0][0].ID)
+		assert.Equal(t, "3", calls[0][1].ID)
+	}
+}
+
+func TestShipperBlock(t *testing.T) {
+	storer := &fakeStorer{}
+	shipper, err := NewShipper(storer, ShipperConfig{
+		QueueSize:     1,
+		BatchSize:     1,
+		FlushInterval: time.Hour,
+		DropPolicy:    Block,
+	})
+	if !assert.Nil(t, err) {
+		return
+	}
+	for i := 0; i < 5; i++ {
+		assert.Nil(t, shipper.Ship(validResource))
+	}
+	assert.Nil(t, shipper.Close(context.Background()))
+	assert.Equal(t, uint64(5), shipper.Stats().Sent)
+	assert.Equal(t, uint64(0), shipper.Stats().Dropped)
+}
+
+func TestSplitBatch(t *testing.T) {
+	large := validResource
+	large.LogData.Message = strings.Repeat("x", 1000)
+	batches := splitBatch([]Resource{large, large, validResource, large}, 4000)
+	if !assert.Len(t, batches, 2) {
+		return
+	}
+	assert.Len(t, batches[0], 2)
+	assert.Len(t, batches[1], 2)
+
+	_, err := NewShipper(nil, ShipperConfig{})
+	assert.Equal(t, ErrMissingStorer, err)
+}
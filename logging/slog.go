@@ -0,0 +1,125 @@
+//go:build go1.21
+// +build go1.21
+
+package logging
+
+import (
+	"context"
+	"log/slog"
+)
+
+// SlogHandler is a slog.Handler which ships records as resources
+type SlogHandler struct {
+	sink   Sink
+	config AdapterConfig
+	level  slog.Leveler
+	attrs  map[string]interface{}
+	group  string
+}
+
+// NewSlogHandler returns a slog.Handler shipping records at or above level to sink.
+// A nil level defaults to slog.LevelInfo
+func NewSlogHandler(sink Sink, config AdapterConfig, level slog.Leveler) *SlogHandler {
+	if level == nil {
+		level = slog.LevelInfo
+	}
+	return &SlogHandler{
+		sink:   sink,
+		config: config.withDefaults(),
+		level:  level,
+		attrs:  make(map[string]interface{}),
+	}
+}
+
+// SlogSeverity maps a slog level to a Severity
+func SlogSeverity(level slog.Level) string {
+	switch {
+	case level >= slog.LevelError+4:
+		return SeverityCritical
+	case level >= slog.LevelError:
+		return SeverityError
+	case level >= slog.LevelWarn:
+		return SeverityWarning
+	case level >= slog.LevelInfo:
+		return SeverityInfo
+	default:
+		return SeverityDebug
+	}
+}
+
+// Enabled implements slog.Handler
+func (h *SlogHandler) Enabled(_ context.Context, level slog.Level) bool {
+	return level >= h.level.Level()
+}
+
+// Handle implements slog.Handler
+func (h *SlogHandler) Handle(_ context.Context, record slog.Record) error {
+	attrs := make(map[string]interface{}, len(h.attrs)+record.NumAttrs())
+	for k, v := range h.attrs {
+		attrs[k] = v
+	}
+	record.Attrs(func(a slog.Attr) bool {
+		addSlogAttr(attrs, h.group, a)
+		return true
+	})
+	r := h.config.NewResource(record.Time, SlogSeverity(record.Level), record.Message, attrs)
+	return h.config.ship(h.sink, r)
+}
+
+// WithAttrs implements slog.Handler
+func (h *SlogHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	clone := h.clone()
+	for _, a := range attrs {
+		addSlogAttr(clone.attrs, clone.group, a)
+	}
+	return clone
+}
+
+// WithGroup implements slog.Handler
+func (h *SlogHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	clone := h.clone()
+	clone.group = joinKey(h.group, name)
+	return clone
+}
+
+func (h *SlogHandler) clone() *SlogHandler {
+	clone := *h
+	clone.attrs = make(map[string]interface{}, len(h.attrs))
+	for k, v := range h.attrs {
+		clone.attrs[k] = v
+	}
+	return &clone
+}
+
+// addSlogAttr flattens a into attrs, joining group keys with a dot
+func addSlogAttr(attrs map[string]interface{}, group string, a slog.Attr) {
+	a.Value = a.Value.Resolve()
+	if a.Equal(slog.Attr{}) {
+		return
+	}
+	if a.Value.Kind() == slog.KindGroup {
+		prefix := group
+		if a.Key != "" {
+			prefix = joinKey(group, a.Key)
+		}
+		for _, ga := range a.Value.Group() {
+			addSlogAttr(attrs, prefix, ga)
+		}
+		return
+	}
+	value := a.Value.Any()
+	if err, ok := value.(error); ok {
+		value = err.Error()
+	}
+	attrs[joinKey(group, a.Key)] = value
+}
+
+func joinKey(group, key string) string {
+	if group == "" {
+		return key
+	}
+	return group + "." + key
+}
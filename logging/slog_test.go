@@ -0,0 +1,55 @@
+//go:build go1.21
+// +build go1.21
+
+package logging
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSlogHandler(t *testing.T) {
+	sink := &recordingSink{}
+	logger := slog.New(NewSlogHandler(sink, AdapterConfig{ApplicationName: "app"}, slog.LevelInfo))
+
+	logger.Debug("ignored")
+	logger.With("trace_id", "abc").
+		WithGroup("req").
+		Error("failed", "path", "/x", "err", errors.New("boom"), slog.Group("user", "id", 1))
+	logger.Warn("careful")
+
+	if !assert.Len(t, sink.resources, 2) {
+		return
+	}
+	r := sink.resources[0]
+	assert.Equal(t, SeverityError, r.Severity)
+	assert.Equal(t, "failed", r.LogData.Message)
+	assert.Equal(t, "abc", r.TransactionID)
+	assert.Equal(t, "app", r.ApplicationName)
+	var custom map[string]interface{}
+	assert.Nil(t, json.Unmarshal(r.Custom, &custom))
+	assert.Equal(t, map[string]interface{}{
+		"req.path":    "/x",
+		"req.err":     "boom",
+		"req.user.id": float64(1),
+	}, custom)
+	assert.Equal(t, SeverityWarning, sink.resources[1].Severity)
+
+	// Trace IDs inside groups are the TransactionID too
+	logger.WithGroup("req").Info("grouped", "traceId", "def", "path", "/y")
+	if !assert.Len(t, sink.resources, 3) {
+		return
+	}
+	r = sink.resources[2]
+	assert.Equal(t, "def", r.TransactionID)
+	custom = nil
+	assert.Nil(t, json.Unmarshal(r.Custom, &custom))
+	assert.Equal(t, map[string]interface{}{"req.path": "/y"}, custom)
+
+	assert.Equal(t, SeverityCritical, SlogSeverity(slog.LevelError+4))
+	assert.Equal(t, SeverityDebug, SlogSeverity(slog.LevelDebug))
+}
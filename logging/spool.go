@@ -0,0 +1,529 @@
+package logging
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultSpoolSegmentBytes is the default size at which a new segment file is started
+	DefaultSpoolSegmentBytes = 8 * 1024 * 1024
+	// DefaultSpoolMaxBytes is the default maximum size of all segment files together
+	DefaultSpoolMaxBytes = 256 * 1024 * 1024
+
+	spoolSegmentExt  = ".seg"
+	spoolCursorFile  = "cursor"
+	spoolHeaderBytes = 8
+)
+
+// SpoolConfig configures a Spool. Zero values are replaced by their defaults
+type SpoolConfig struct {
+	// Dir is the directory holding the segment files. It is created when missing
+	Dir string
+	// MaxSegmentBytes is the size at which a new segment file is started
+	MaxSegmentBytes int64
+	// MaxBytes caps the size of the spool. The oldest segments are evicted to make room
+	MaxBytes int64
+	// MaxAge is the retention period of segments. Zero keeps segments until they are replayed
+	MaxAge time.Duration
+}
+
+// SpoolStats holds the counters of a Spool
+type SpoolStats struct {
+	Segments     int
+	Bytes        int64
+	PendingBytes int64
+	// Evicted is the number of segments removed by the size cap or retention before being replayed
+	Evicted uint64
+	// Corrupt is the number of records skipped or truncated because they failed their checksum
+	Corrupt uint64
+}
+
+// Spool is a persistent queue of resource batches. Batches are appended to
+// segment files as checksummed records and replayed in the order they were written.
+//
+// Each record consists of a 4 byte big endian payload length, a 4 byte
+// CRC-32 (IEEE) of the payload and the JSON encoded batch with its product keys.
+type Spool struct {
+	// replayMu serializes replays. mu guards the segments and cursor but is not
+	// held while records are delivered so Append is not blocked by a replay
+	replayMu sync.Mutex
+	mu       sync.Mutex
+	config   SpoolConfig
+
+	segments []spoolSegment
+	active   *os.File
+	cursor   spoolCursor
+
+	evicted uint64
+	corrupt uint64
+}
+
+type spoolSegment struct {
+	seq     uint64
+	size    int64
+	modTime time.Time
+}
+
+// spoolRecord is the payload of a record. ProductKey is not part of the
+// JSON encoding of a Resource so it is kept per resource
+type spoolRecord struct {
+	ProductKeys []string   `json:"productKeys,omitempty"`
+	Resources   []Resource `json:"resources"`
+}
+
+func newSpoolRecord(batch []Resource) spoolRecord {
+	rec := spoolRecord{Resources: batch}
+	for i, r := range batch {
+		if r.ProductKey == "" {
+			continue
+		}
+		if rec.ProductKeys == nil {
+			rec.ProductKeys = make([]string, len(batch))
+		}
+		rec.ProductKeys[i] = r.ProductKey
+	}
+	return rec
+}
+
+func (rec spoolRecord) batch() []Resource {
+	for i := range rec.Resources {
+		if i < len(rec.ProductKeys) {
+			rec.Resources[i].ProductKey = rec.ProductKeys[i]
+		}
+	}
+	return rec.Resources
+}
+
+// spoolCursor is the replay position. Records before it have been delivered
+type spoolCursor struct {
+	Segment uint64 `json:"segment"`
+	Offset  int64  `json:"offset"`
+}
+
+// OpenSpool opens or creates the spool in config.Dir. Segments left behind by a
+// previous process are recovered: a partially written record at the end of the
+// last segment is truncated and delivered segments are removed.
+func OpenSpool(config SpoolConfig) (*Spool, error) {
+	if config.Dir == "" {
+		return nil, ErrMissingSpoolDir
+	}
+	if config.MaxSegmentBytes <= 0 {
+		config.MaxSegmentBytes = DefaultSpoolSegmentBytes
+	}
+	if config.MaxBytes <= 0 {
+		config.MaxBytes = DefaultSpoolMaxBytes
+	}
+	if config.MaxSegmentBytes > config.MaxBytes {
+		config.MaxSegmentBytes = config.MaxBytes
+	}
+	if err := os.MkdirAll(config.Dir, 0700); err != nil {
+		return nil, err
+	}
+	s := &Spool{config: config}
+	if err := s.recover(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Append writes a batch to the spool, one record per product key. The oldest
+// segments are evicted when the spool would exceed its size cap. ErrSpoolFull
+// is returned when the batch does not fit even after eviction.
+func (s *Spool) Append(batch []Resource) error {
+	for _, group := range groupByProductKey(batch, "") {
+		if err := s.appendRecord(group.resources); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *Spool) appendRecord(batch []Resource) error {
+	payload, err := json.Marshal(newSpoolRecord(batch))
+	if err != nil {
+		return err
+	}
+	record := make([]byte, spoolHeaderBytes+len(payload))
+	binary.BigEndian.PutUint32(record[0:4], uint32(len(payload)))
+	binary.BigEndian.PutUint32(record[4:8], crc32.ChecksumIEEE(payload))
+	copy(record[spoolHeaderBytes:], payload)
+	size := int64(len(record))
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if size > s.config.MaxBytes {
+		return ErrSpoolFull
+	}
+	s.expire()
+	if s.active != nil && s.last().size > 0 && s.last().size+size > s.config.MaxSegmentBytes {
+		if err := s.closeActive(); err != nil {
+			return err
+		}
+	}
+	for s.total()+size > s.config.MaxBytes {
+		if len(s.segments) == 0 || (s.active != nil && len(s.segments) == 1) {
+			return ErrSpoolFull
+		}
+		if err := s.evict(); err != nil {
+			return err
+		}
+	}
+	if s.active == nil {
+		if err := s.openActive(); err != nil {
+			return err
+		}
+	}
+	if _, err := s.active.Write(record); err != nil {
+		// Drop the partial record so the segment stays readable
+		_ = s.active.Truncate(s.last().size)
+		_, _ = s.active.Seek(s.last().size, io.SeekStart)
+		return err
+	}
+	if err := s.active.Sync(); err != nil {
+		return err
+	}
+	s.last().size += size
+	s.last().modTime = time.Now()
+	return nil
+}
+
+// Replay stores spooled batches through storer in the order they were written.
+// Delivered records are removed from the spool. Replay stops at the first batch
+// which cannot be delivered and returns the number of resources delivered so far.
+// Resources flagged as invalid by the ingestor are discarded.
+func (s *Spool) Replay(storer Storer) (int, error) {
+	s.replayMu.Lock()
+	defer s.replayMu.Unlock()
+
+	sent := 0
+	for {
+		s.mu.Lock()
+		s.expire()
+		if len(s.segments) == 0 {
+			s.mu.Unlock()
+			return sent, nil
+		}
+		seg := s.segments[0]
+		if s.cursor.Segment != seg.seq {
+			s.cursor = spoolCursor{Segment: seg.seq}
+		}
+		if s.cursor.Offset >= seg.size {
+			if s.active != nil && len(s.segments) == 1 {
+				s.mu.Unlock()
+				return sent, nil
+			}
+			err := s.removeFirst()
+			s.mu.Unlock()
+			if err != nil {
+				return sent, err
+			}
+			continue
+		}
+		offset := s.cursor.Offset
+		s.mu.Unlock()
+
+		n, err := s.replaySegment(storer, seg, offset)
+		sent += n
+		if err != nil {
+			return sent, err
+		}
+	}
+}
+
+// Pending returns the number of bytes waiting to be replayed
+func (s *Spool) Pending() int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.pending()
+}
+
+// Stats returns a snapshot of the Spool counters
+func (s *Spool) Stats() SpoolStats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return SpoolStats{
+		Segments:     len(s.segments),
+		Bytes:        s.total(),
+		PendingBytes: s.pending(),
+		Evicted:      s.evicted,
+		Corrupt:      s.corrupt,
+	}
+}
+
+// Close closes the active segment. Spooled records are kept for the next OpenSpool
+func (s *Spool) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.closeActive()
+}
+
+// replaySegment delivers the records of seg from offset up to the size seg had
+// when the replay started. It returns early when seg is evicted meanwhile.
+func (s *Spool) replaySegment(storer Storer, seg spoolSegment, offset int64) (int, error) {
+	f, err := os.Open(s.path(seg.seq))
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+	if _, err := f.Seek(offset, io.SeekStart); err != nil {
+		return 0, err
+	}
+	r := bufio.NewReader(io.LimitReader(f, seg.size-offset))
+	sent := 0
+	for offset < seg.size {
+		payload, err := readRecord(r)
+		if err != nil {
+			// The remainder of the segment cannot be trusted
+			_, err := s.advance(seg.seq, seg.size, true)
+			return sent, err
+		}
+		var rec spoolRecord
+		corrupt := json.Unmarshal(payload, &rec) != nil
+		if !corrupt {
+			batch := rec.batch()
+			// Flagged resources are never stored so an undelivered
+			// record is replayed as a whole next time
+			remaining, flagged, err := deliver(storer, batch)
+			if err != nil {
+				return sent, err
+			}
+			sent += len(batch) - len(remaining) - len(flagged)
+		}
+		offset += int64(spoolHeaderBytes + len(payload))
+		if ok, err := s.advance(seg.seq, offset, corrupt); !ok || err != nil {
+			return sent, err
+		}
+	}
+	return sent, nil
+}
+
+// advance moves the cursor to offset within segment seq and counts a corrupt
+// record. It returns false when the segment has been evicted.
+func (s *Spool) advance(seq uint64, offset int64, corrupt bool) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if corrupt {
+		s.corrupt++
+	}
+	if s.cursor.Segment != seq {
+		return false, nil
+	}
+	s.cursor.Offset = offset
+	return true, s.saveCursor()
+}
+
+// recover loads the segments and cursor from disk
+func (s *Spool) recover() error {
+	entries, err := ioutil.ReadDir(s.config.Dir)
+	if err != nil {
+		return err
+	}
+	for _, e := range entries {
+		if e.IsDir() || !strings.HasSuffix(e.Name(), spoolSegmentExt) {
+			continue
+		}
+		seq, err := strconv.ParseUint(strings.TrimSuffix(e.Name(), spoolSegmentExt), 10, 64)
+		if err != nil {
+			continue
+		}
+		s.segments = append(s.segments, spoolSegment{seq: seq, size: e.Size(), modTime: e.ModTime()})
+	}
+	sort.Slice(s.segments, func(i, j int) bool { return s.segments[i].seq < s.segments[j].seq })
+
+	if data, err := ioutil.ReadFile(filepath.Join(s.config.Dir, spoolCursorFile)); err == nil {
+		_ = json.Unmarshal(data, &s.cursor)
+	}
+	for len(s.segments) > 0 && s.segments[0].seq < s.cursor.Segment {
+		if err := s.removeFirst(); err != nil {
+			return err
+		}
+	}
+	if len(s.segments) == 0 {
+		return nil
+	}
+	if s.cursor.Segment != s.segments[0].seq || s.cursor.Offset < 0 {
+		s.cursor = spoolCursor{Segment: s.segments[0].seq}
+	}
+	// Only the last segment can have been interrupted while being written
+	last := s.last()
+	valid, err := validLength(s.path(last.seq))
+	if err != nil {
+		return err
+	}
+	if valid < last.size {
+		if err := os.Truncate(s.path(last.seq), valid); err != nil {
+			return err
+		}
+		s.corrupt++
+		last.size = valid
+	}
+	s.expire()
+	return nil
+}
+
+// expire removes segments older than MaxAge
+func (s *Spool) expire() {
+	if s.config.MaxAge <= 0 {
+		return
+	}
+	cutoff := time.Now().Add(-s.config.MaxAge)
+	for len(s.segments) > 0 && s.segments[0].modTime.Before(cutoff) {
+		if s.active != nil && len(s.segments) == 1 {
+			return
+		}
+		if s.evict() != nil {
+			return
+		}
+	}
+}
+
+// evict removes the oldest segment regardless of whether it was replayed. It
+// is counted as evicted when it still holds records which were not replayed.
+func (s *Spool) evict() error {
+	seg := s.segments[0]
+	replayed := int64(0)
+	if s.cursor.Segment == seg.seq {
+		replayed = s.cursor.Offset
+	}
+	if replayed < seg.size {
+		s.evicted++
+	}
+	return s.removeFirst()
+}
+
+func (s *Spool) removeFirst() error {
+	seg := s.segments[0]
+	if err := os.Remove(s.path(seg.seq)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	s.segments = s.segments[1:]
+	if len(s.segments) > 0 {
+		s.cursor = spoolCursor{Segment: s.segments[0].seq}
+	} else {
+		s.cursor = spoolCursor{Segment: seg.seq + 1}
+	}
+	return s.saveCursor()
+}
+
+func (s *Spool) openActive() error {
+	seq := s.cursor.Segment
+	if len(s.segments) > 0 {
+		seq = s.last().seq + 1
+	}
+	f, err := os.OpenFile(s.path(seq), os.O_CREATE|os.O_EXCL|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return err
+	}
+	s.active = f
+	s.segments = append(s.segments, spoolSegment{seq: seq, modTime: time.Now()})
+	return nil
+}
+
+func (s *Spool) closeActive() error {
+	if s.active == nil {
+		return nil
+	}
+	err := s.active.Close()
+	s.active = nil
+	return err
+}
+
+func (s *Spool) saveCursor() error {
+	data, err := json.Marshal(s.cursor)
+	if err != nil {
+		return err
+	}
+	path := filepath.Join(s.config.Dir, spoolCursorFile)
+	if err := writeFileSync(path+".tmp", data); err != nil {
+		return err
+	}
+	return os.Rename(path+".tmp", path)
+}
+
+func (s *Spool) last() *spoolSegment {
+	return &s.segments[len(s.segments)-1]
+}
+
+func (s *Spool) total() int64 {
+	var total int64
+	for _, seg := range s.segments {
+		total += seg.size
+	}
+	return total
+}
+
+func (s *Spool) pending() int64 {
+	pending := s.total()
+	if len(s.segments) > 0 && s.cursor.Segment == s.segments[0].seq {
+		pending -= s.cursor.Offset
+	}
+	return pending
+}
+
+func (s *Spool) path(seq uint64) string {
+	return filepath.Join(s.config.Dir, fmt.Sprintf("%020d%s", seq, spoolSegmentExt))
+}
+
+// readRecord reads a single record and verifies its checksum
+func readRecord(r io.Reader) ([]byte, error) {
+	var header [spoolHeaderBytes]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		return nil, err
+	}
+	payload := make([]byte, binary.BigEndian.Uint32(header[0:4]))
+	if _, err := io.ReadFull(r, payload); err != nil {
+		return nil, err
+	}
+	if crc32.ChecksumIEEE(payload) != binary.BigEndian.Uint32(header[4:8]) {
+		return nil, ErrSpoolCorrupt
+	}
+	return payload, nil
+}
+
+// validLength returns the length of the leading run of intact records in a segment file
+func validLength(path string) (int64, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+	r := bufio.NewReader(f)
+	var valid int64
+	for {
+		payload, err := readRecord(r)
+		if err != nil {
+			return valid, nil
+		}
+		valid += int64(spoolHeaderBytes + len(payload))
+	}
+}
+
+func writeFileSync(path string, data []byte) error {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,234 @@
+package logging
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func openTestSpool(t *testing.T, config SpoolConfig) *Spool {
+	t.Helper()
+	if config.Dir == "" {
+		config.Dir = t.TempDir()
+	}
+	spool, err := OpenSpool(config)
+	if err != nil {
+		t.Fatalf("OpenSpool: %v", err)
+	}
+	return spool
+}
+
+func replayedIDs(storer *fakeStorer) []string {
+	var ids []string
+	for _, c := range storer.Calls() {
+		for _, r := range c {
+			ids = append(ids, r.ID)
+		}
+	}
+	return ids
+}
+
+func TestSpoolReplayInOrder(t *testing.T) {
+	spool := openTestSpool(t, SpoolConfig{MaxSegmentBytes: 1024})
+	defer spool.Close()
+
+	for _, id := range []string{"1", "2", "3", "4", "5"} {
+		assert.Nil(t, spool.Append([]Resource{resourceWithID(id)}))
+	}
+	assert.Greater(t, spool.Stats().Segments, 1)
+
+	offline := &fakeStorer{failFor: 1, err: errors.New("connection refused")}
+	sent, err := spool.Replay(offline)
+	assert.NotNil(t, err)
+	assert.Equal(t, 0, sent)
+
+	storer := &fakeStorer{}
+	sent, err = spool.Replay(storer)
+	assert.Nil(t, err)
+	assert.Equal(t, 5, sent)
+	assert.Equal(t, []string{"1", "2", "3", "4", "5"}, replayedIDs(storer))
+	assert.Equal(t, int64(0), spool.Pending())
+
+	sent, err = spool.Replay(storer)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, sent)
+}
+
+func TestSpoolRecovery(t *testing.T) {
+	dir := t.TempDir()
+	spool := openTestSpool(t, SpoolConfig{Dir: dir})
+	assert.Nil(t, spool.Append([]Resource{resourceWithID("1")}))
+	assert.Nil(t, spool.Append([]Resource{resourceWithID("2")}))
+	assert.Nil(t, spool.Append([]Resource{resourceWithID("3")}))
+
+	// Deliver the first record only
+	storer := &fakeStorer{}
+	failing := &onceStorer{storer: storer}
+	_, _ = spool.Replay(failing)
+	assert.Nil(t, spool.Close())
+
+	// Simulate a crash halfway through writing a record
+	segments, _ := filepath.Glob(filepath.Join(dir, "*"+spoolSegmentExt))
+	if !assert.Len(t, segments, 1) {
+		return
+	}
+	f, err := os.OpenFile(segments[0], os.O_APPEND|os.O_WRONLY, 0600)
+	if !assert.Nil(t, err) {
+		return
+	}
+	_, _ = f.Write([]byte{0, 0, 1, 0, 1, 2, 3, 4, '['})
+	_ = f.Close()
+
+	spool = openTestSpool(t, SpoolConfig{Dir: dir})
+	defer spool.Close()
+	assert.Equal(t, uint64(1), spool.Stats().Corrupt)
+	assert.Nil(t, spool.Append([]Resource{resourceWithID("4")}))
+
+	storer = &fakeStorer{}
+	sent, err := spool.Replay(storer)
+	assert.Nil(t, err)
+	assert.Equal(t, 3, sent)
+	assert.Equal(t, []string{"2", "3", "4"}, replayedIDs(storer))
+}
+
+func TestSpoolSizeCapAndRetention(t *testing.T) {
+	spool := openTestSpool(t, SpoolConfig{MaxSegmentBytes: 600, MaxBytes: 1500})
+	defer spool.Close()
+	for _, id := range []string{"1", "2", "3", "4", "5", "6"} {
+		assert.Nil(t, spool.Append([]Resource{resourceWithID(id)}))
+	}
+	stats := spool.Stats()
+	assert.LessOrEqual(t, stats.Bytes, int64(1500))
+	assert.Greater(t, stats.Evicted, uint64(0))
+
+	storer := &fakeStorer{}
+	_, err := spool.Replay(storer)
+	assert.Nil(t, err)
+	ids := replayedIDs(storer)
+	if assert.NotEmpty(t, ids) {
+		assert.Equal(t, "6", ids[len(ids)-1])
+		assert.NotEqual(t, "1", ids[0])
+	}
+
+	large := validResource
+	large.LogData.Message = string(make([]byte, 2000))
+	assert.Equal(t, ErrSpoolFull, spool.Append([]Resource{large}))
+
+	expiring := openTestSpool(t, SpoolConfig{MaxAge: time.Millisecond})
+	defer expiring.Close()
+	assert.Nil(t, expiring.Append([]Resource{resourceWithID("old")}))
+	assert.Nil(t, expiring.Close())
+	time.Sleep(5 * time.Millisecond)
+	storer = &fakeStorer{}
+	sent, err := expiring.Replay(storer)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, sent)
+	assert.Equal(t, uint64(1), expiring.Stats().Evicted)
+
+	_, err = OpenSpool(SpoolConfig{})
+	assert.Equal(t, ErrMissingSpoolDir, err)
+}
+
+func TestSpoolEvictReplayedSegment(t *testing.T) {
+	spool := openTestSpool(t, SpoolConfig{MaxSegmentBytes: 600, MaxBytes: 1500})
+	defer spool.Close()
+	assert.Nil(t, spool.Append([]Resource{resourceWithID("1")}))
+	sent, err := spool.Replay(&fakeStorer{})
+	assert.Nil(t, err)
+	assert.Equal(t, 1, sent)
+
+	// The replayed active segment is kept until it is evicted to make room
+	first := spool.segments[0].seq
+	for i := 2; spool.segments[0].seq == first; i++ {
+		assert.Nil(t, spool.Append([]Resource{resourceWithID(strconv.Itoa(i))}))
+	}
+	assert.Equal(t, uint64(0), spool.Stats().Evicted)
+}
+
+// blockingStorer blocks StoreResources until release is closed
+type blockingStorer struct {
+	fakeStorer
+	started chan struct{}
+	release chan struct{}
+}
+
+func (b *blockingStorer) StoreResources(msgs []Resource, count int) (*StoreResponse, error) {
+	select {
+	case b.started <- struct{}{}:
+	default:
+	}
+	<-b.release
+	return b.fakeStorer.StoreResources(msgs, count)
+}
+
+func TestSpoolAppendDuringReplay(t *testing.T) {
+	spool := openTestSpool(t, SpoolConfig{})
+	defer spool.Close()
+	assert.Nil(t, spool.Append([]Resource{resourceWithID("1")}))
+
+	storer := &blockingStorer{started: make(chan struct{}, 1), release: make(chan struct{})}
+	replayed := make(chan int)
+	go func() {
+		sent, _ := spool.Replay(storer)
+		replayed <- sent
+	}()
+	<-storer.started
+
+	appended := make(chan error)
+	go func() { appended <- spool.Append([]Resource{resourceWithID("2")}) }()
+	select {
+	case err := <-appended:
+		assert.Nil(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("Append blocked by Replay")
+	}
+	close(storer.release)
+	assert.Equal(t, 2, <-replayed)
+	assert.Equal(t, []string{"1", "2"}, replayedIDs(&storer.fakeStorer))
+}
+
+func TestShipperSpool(t *testing.T) {
+	spool := openTestSpool(t, SpoolConfig{})
+	defer spool.Close()
+
+	storer := &fakeStorer{failFor: 1, err: errors.New("connection refused")}
+	shipper, err := NewShipper(storer, ShipperConfig{
+		FlushInterval: time.Hour,
+		MaxRetries:    -1,
+		Spool:         spool,
+	})
+	if !assert.Nil(t, err) {
+		return
+	}
+	assert.Nil(t, shipper.Ship(resourceWithID("spooled")))
+	assert.Nil(t, shipper.Flush(context.Background()))
+	assert.Equal(t, uint64(1), shipper.Stats().Spooled)
+	assert.Equal(t, uint64(0), shipper.Stats().Failed)
+	assert.Greater(t, spool.Pending(), int64(0))
+
+	assert.Nil(t, shipper.Ship(resourceWithID("fresh")))
+	assert.Nil(t, shipper.Close(context.Background()))
+	assert.Equal(t, uint64(2), shipper.Stats().Sent)
+	assert.Equal(t, int64(0), spool.Pending())
+	assert.Equal(t, []string{"spooled", "spooled", "fresh"}, replayedIDs(storer))
+}
+
+// onceStorer delivers a single call and fails afterwards
+type onceStorer struct {
+	storer *fakeStorer
+	used   bool
+}
+
+func (o *onceStorer) StoreResources(msgs []Resource, count int) (*StoreResponse, error) {
+	if o.used {
+		return nil, errors.New("connection refused")
+	}
+	o.used = true
+	return o.storer.StoreResources(msgs, count)
+}
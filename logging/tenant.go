@@ -0,0 +1,175 @@
+package logging
+
+import (
+	"net/http"
+	"sync"
+
+	"github.com/philips-software/go-hsdp-api/iam"
+	signer "github.com/philips-software/go-hsdp-signer"
+)
+
+// Tenant holds the credentials used to store resources for a product key.
+// Either SharedKey and SharedSecret or IAMClient must be set
+type Tenant struct {
+	ProductKey   string
+	SharedKey    string
+	SharedSecret string
+	IAMClient    *iam.Client
+}
+
+// TenantResponse holds the outcome of storing the resources of a single tenant
+type TenantResponse struct {
+	ProductKey string
+	// Resources are the resources of the tenant in their original order.
+	// The indices in Response.Failed refer to this slice
+	Resources []Resource
+	Response  *StoreResponse
+	Err       error
+}
+
+// credentials authorize requests using API signing or an IAM token
+type credentials struct {
+	httpSigner *signer.Signer
+	iamClient  *iam.Client
+}
+
+func (c *credentials) authorize(req *http.Request) error {
+	if c.httpSigner != nil {
+		return c.httpSigner.SignRequest(req)
+	}
+	req.Header.Set("Authorization", "Bearer "+c.iamClient.Token())
+	return nil
+}
+
+// AddTenant registers the credentials of a tenant. Resources with a ProductKey
+// of an unregistered tenant are stored using the client credentials.
+func (c *Client) AddTenant(tenant Tenant) error {
+	if tenant.ProductKey == "" {
+		return ErrMissingProductKey
+	}
+	creds := &credentials{}
+	var err error
+	creds.httpSigner, err = signer.New(tenant.SharedKey, tenant.SharedSecret)
+	if err != nil {
+		if tenant.IAMClient == nil {
+			return ErrMissingCredentialsOrIAMClient
+		}
+		creds.httpSigner = nil
+		creds.iamClient = tenant.IAMClient
+	}
+	c.tenantsMu.Lock()
+	defer c.tenantsMu.Unlock()
+	if c.tenants == nil {
+		c.tenants = make(map[string]*credentials)
+	}
+	c.tenants[tenant.ProductKey] = creds
+	return nil
+}
+
+// RemoveTenant removes the credentials of a tenant
+func (c *Client) RemoveTenant(productKey string) {
+	c.tenantsMu.Lock()
+	defer c.tenantsMu.Unlock()
+	delete(c.tenants, productKey)
+}
+
+// StoreResourcesByTenant groups msgs by product key and stores each group
+// concurrently using the credentials of its tenant. A failing tenant does not
+// affect the others; its outcome is reported in its TenantResponse.
+// Responses are ordered by the first occurrence of each product key in msgs.
+func (c *Client) StoreResourcesByTenant(msgs []Resource) []TenantResponse {
+	groups := groupByProductKey(msgs, c.config.ProductKey)
+	responses := make([]TenantResponse, len(groups))
+	var wg sync.WaitGroup
+	for i, group := range groups {
+		responses[i] = TenantResponse{ProductKey: group.productKey, Resources: group.resources}
+		wg.Add(1)
+		go func(r *TenantResponse) {
+			defer wg.Done()
+			r.Response, r.Err = c.storeResources(c.credentials(r.ProductKey), r.ProductKey, r.Resources, len(r.Resources))
+		}(&responses[i])
+	}
+	wg.Wait()
+	return responses
+}
+
+// credentials returns the credentials of the tenant of productKey
+func (c *Client) credentials(productKey string) *credentials {
+	c.tenantsMu.RLock()
+	creds, ok := c.tenants[productKey]
+	c.tenantsMu.RUnlock()
+	if ok {
+		return creds
+	}
+	return &credentials{httpSigner: c.httpSigner, iamClient: c.iamClient}
+}
+
+// productKeyGroup holds the resources of a single product key and their index in the original batch
+type productKeyGroup struct {
+	productKey string
+	resources  []Resource
+	indices    []int
+}
+
+// groupByProductKey splits resources by their effective product key, which is their
+// ProductKey or else defaultKey, keeping the order within each group
+func groupByProductKey(resources []Resource, defaultKey string) []productKeyGroup {
+	var groups []productKeyGroup
+	index := make(map[string]int)
+	for n, r := range resources {
+		key := r.ProductKey
+		if key == "" {
+			key = defaultKey
+		}
+		i, ok := index[key]
+		if !ok {
+			i = len(groups)
+			index[key] = i
+			groups = append(groups, productKeyGroup{productKey: key})
+		}
+		groups[i].resources = append(groups[i].resources, r)
+		groups[i].indices = append(groups[i].indices, n)
+	}
+	return groups
+}
+
+// storeGroups stores each group in turn and merges the outcomes. The indices in
+// Failed and Sanitized refer to the original batch. The response and error are
+// those of the first failing group, later groups are still stored.
+func (c *Client) storeGroups(groups []productKeyGroup) (*StoreResponse, error) {
+	merged := &StoreResponse{
+		Failed:    make(map[int]Resource),
+		Sanitized: make(map[int][]string),
+	}
+	var firstErr error
+	for _, g := range groups {
+		resp, err := c.storeResources(c.credentials(g.productKey), g.productKey, g.resources, len(g.resources))
+		failedBefore := firstErr != nil
+		if err != nil && firstErr == nil {
+			firstErr = err
+		}
+		if resp == nil {
+			continue
+		}
+		if !failedBefore {
+			merged.Response = resp.Response
+			merged.Message = resp.Message
+		}
+		for i, r := range resp.Failed {
+			merged.Failed[g.indices[i]] = r
+		}
+		for i, fields := range resp.Sanitized {
+			merged.Sanitized[g.indices[i]] = fields
+		}
+	}
+	if merged.Response == nil {
+		return nil, firstErr
+	}
+	if len(merged.Failed) == 0 {
+		merged.Failed = nil
+	}
+	if len(merged.Sanitized) == 0 {
+		merged.Sanitized = nil
+	}
+	return merged, firstErr
+}
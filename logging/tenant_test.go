@@ -0,0 +1,158 @@
+package logging
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	signer "github.com/philips-software/go-hsdp-signer"
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	tenantProductKey = "2d6fb9e5-77d4-4f2e-8a8e-5e4f0c1a2b3c"
+	tenantSharedKey  = "TenantKey"
+	tenantSecret     = "TenantSecret"
+)
+
+func tenantServer(t *testing.T) *httptest.Server {
+	keys := map[string][2]string{
+		productKey:       {sharedKey, sharedSecret},
+		tenantProductKey: {tenantSharedKey, tenantSecret},
+	}
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		var bundle Bundle
+		if err := json.Unmarshal(body, &bundle); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		pair, ok := keys[bundle.ProductKey]
+		if !ok {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		s, _ := signer.New(pair[0], pair[1])
+		if ok, _ := s.ValidateRequest(r); !ok {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+	}))
+}
+
+func withProductKey(r Resource, productKey string) Resource {
+	r.ProductKey = productKey
+	return r
+}
+
+func TestStoreResourcesByTenant(t *testing.T) {
+	server := tenantServer(t)
+	defer server.Close()
+
+	client, err := NewClient(nil, &Config{
+		SharedKey:    sharedKey,
+		SharedSecret: sharedSecret,
+		ProductKey:   productKey,
+		BaseURL:      server.URL,
+	})
+	if !assert.Nil(t, err) {
+		return
+	}
+	assert.Equal(t, ErrMissingProductKey, client.AddTenant(Tenant{SharedKey: "a", SharedSecret: "b"}))
+	assert.Equal(t, ErrMissingCredentialsOrIAMClient, client.AddTenant(Tenant{ProductKey: tenantProductKey}))
+
+	mixed := []Resource{
+		validResource,
+		withProductKey(validResource, tenantProductKey),
+		withProductKey(validResource, "unknown"),
+		withProductKey(validResource, tenantProductKey),
+	}
+	// Resources without a ProductKey belong to the configured product key
+	sameKey := []Resource{validResource, withProductKey(validResource, productKey)}
+	assert.Len(t, groupByProductKey(sameKey, productKey), 1)
+	resp, err := client.StoreResources(sameKey, len(sameKey))
+	if assert.Nil(t, err) {
+		assert.Equal(t, http.StatusCreated, resp.StatusCode)
+	}
+
+	// Without registered credentials the tenant is rejected
+	tenantOnly := []Resource{withProductKey(validResource, tenantProductKey)}
+	_, err = client.StoreResources(tenantOnly, 1)
+	assert.Equal(t, ErrNotAuthorized, err)
+
+	assert.Nil(t, client.AddTenant(Tenant{
+		ProductKey:   tenantProductKey,
+		SharedKey:    tenantSharedKey,
+		SharedSecret: tenantSecret,
+	}))
+	resp, err = client.StoreResources(tenantOnly, 1)
+	if assert.Nil(t, err) {
+		assert.Equal(t, http.StatusCreated, resp.StatusCode)
+	}
+
+	// Mixed batches are split per product key
+	resp, err = client.StoreResources(mixed[:2], 2)
+	if assert.Nil(t, err) {
+		assert.Equal(t, http.StatusCreated, resp.StatusCode)
+	}
+	resp, err = client.StoreResources(mixed, len(mixed))
+	assert.Equal(t, ErrNotAuthorized, err)
+	if assert.NotNil(t, resp) {
+		assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+	}
+
+	responses := client.StoreResourcesByTenant(mixed)
+	if !assert.Len(t, responses, 3) {
+		return
+	}
+	assert.Equal(t, productKey, responses[0].ProductKey)
+	assert.Nil(t, responses[0].Err)
+	assert.Equal(t, tenantProductKey, responses[1].ProductKey)
+	assert.Len(t, responses[1].Resources, 2)
+	assert.Nil(t, responses[1].Err)
+	assert.Equal(t, "unknown", responses[2].ProductKey)
+	assert.Equal(t, ErrNotAuthorized, responses[2].Err)
+
+	client.RemoveTenant(tenantProductKey)
+	_, err = client.StoreResources(tenantOnly, 1)
+	assert.Equal(t, ErrNotAuthorized, err)
+}
+
+func TestShipperGroupsByProductKey(t *testing.T) {
+	storer := &fakeStorer{}
+	shipper, err := NewShipper(storer, ShipperConfig{FlushInterval: time.Hour})
+	if !assert.Nil(t, err) {
+		return
+	}
+	_ = shipper.Ship(withProductKey(resourceWithID("1"), "a"))
+	_ = shipper.Ship(withProductKey(resourceWithID("2"), "b"))
+	_ = shipper.Ship(withProductKey(resourceWithID("3"), "a"))
+	_ = shipper.Close(context.Background())
+
+	calls := storer.Calls()
+	if assert.Len(t, calls, 2) {
+		assert.Equal(t, []string{"1", "3"}, []string{calls[0][0].ID, calls[0][1].ID})
+		assert.Equal(t, "b", calls[1][0].ProductKey)
+	}
+
+	spool := openTestSpool(t, SpoolConfig{})
+	defer spool.Close()
+	assert.Nil(t, spool.Append([]Resource{
+		withProductKey(resourceWithID("1"), "a"),
+		withProductKey(resourceWithID("2"), "b"),
+	}))
+	storer = &fakeStorer{}
+	sent, err := spool.Replay(storer)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, sent)
+	calls = storer.Calls()
+	if assert.Len(t, calls, 2) {
+		assert.Equal(t, "a", calls[0][0].ProductKey)
+		assert.Equal(t, "b", calls[1][0].ProductKey)
+	}
+}
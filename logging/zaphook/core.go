@@ -0,0 +1,85 @@
+// Package zaphook provides a zapcore.Core which ships entries to HSDP logging
+package zaphook
+
+import (
+	"github.com/philips-software/go-hsdp-api/logging"
+	"go.uber.org/zap/zapcore"
+)
+
+// Core is a zapcore.Core which ships entries as resources
+type Core struct {
+	zapcore.LevelEnabler
+	sink   logging.Sink
+	config logging.AdapterConfig
+	fields []zapcore.Field
+}
+
+// New returns a Core shipping entries enabled by level to sink. Combine it
+// with other cores using zapcore.NewTee to keep local output
+func New(sink logging.Sink, config logging.AdapterConfig, level zapcore.LevelEnabler) *Core {
+	return &Core{
+		LevelEnabler: level,
+		sink:         sink,
+		config:       config,
+	}
+}
+
+// Severity maps a zap level to a Severity
+func Severity(level zapcore.Level) string {
+	switch {
+	case level >= zapcore.DPanicLevel:
+		return logging.SeverityCritical
+	case level == zapcore.ErrorLevel:
+		return logging.SeverityError
+	case level == zapcore.WarnLevel:
+		return logging.SeverityWarning
+	case level == zapcore.InfoLevel:
+		return logging.SeverityInfo
+	default:
+		return logging.SeverityDebug
+	}
+}
+
+// With implements zapcore.Core
+func (c *Core) With(fields []zapcore.Field) zapcore.Core {
+	clone := *c
+	clone.fields = append(c.fields[:len(c.fields):len(c.fields)], fields...)
+	return &clone
+}
+
+// Check implements zapcore.Core
+func (c *Core) Check(entry zapcore.Entry, checked *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(entry.Level) {
+		return checked.AddCore(entry, c)
+	}
+	return checked
+}
+
+// Write implements zapcore.Core
+func (c *Core) Write(entry zapcore.Entry, fields []zapcore.Field) error {
+	enc := zapcore.NewMapObjectEncoder()
+	for _, f := range c.fields {
+		f.AddTo(enc)
+	}
+	for _, f := range fields {
+		f.AddTo(enc)
+	}
+	attrs := enc.Fields
+	if entry.LoggerName != "" {
+		attrs["logger"] = entry.LoggerName
+	}
+	if entry.Caller.Defined {
+		attrs["caller"] = entry.Caller.TrimmedPath()
+	}
+	r := c.config.NewResource(entry.Time, Severity(entry.Level), entry.Message, attrs)
+	err := c.sink.Ship(r)
+	if err != nil && c.config.OnError != nil {
+		c.config.OnError(err)
+	}
+	return err
+}
+
+// Sync implements zapcore.Core. Use Shipper.Flush to wait for delivery
+func (c *Core) Sync() error {
+	return nil
+}
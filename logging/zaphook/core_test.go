@@ -0,0 +1,46 @@
+package zaphook
+
+import (
+	"encoding/json"
+	"sync"
+	"testing"
+
+	"github.com/philips-software/go-hsdp-api/logging"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+type recordingSink struct {
+	sync.Mutex
+	resources []logging.Resource
+}
+
+func (s *recordingSink) Ship(resource logging.Resource) error {
+	s.Lock()
+	defer s.Unlock()
+	s.resources = append(s.resources, resource)
+	return nil
+}
+
+func TestCore(t *testing.T) {
+	sink := &recordingSink{}
+	logger := zap.New(New(sink, logging.AdapterConfig{ApplicationName: "app"}, zapcore.InfoLevel)).Named("svc")
+
+	logger.Debug("ignored")
+	logger.With(zap.String("trace_id", "abc")).Error("failed", zap.Int("attempt", 2))
+
+	if !assert.Len(t, sink.resources, 1) {
+		return
+	}
+	r := sink.resources[0]
+	assert.Equal(t, logging.SeverityError, r.Severity)
+	assert.Equal(t, "failed", r.LogData.Message)
+	assert.Equal(t, "abc", r.TransactionID)
+	var custom map[string]interface{}
+	assert.Nil(t, json.Unmarshal(r.Custom, &custom))
+	assert.Equal(t, map[string]interface{}{"attempt": float64(2), "logger": "svc"}, custom)
+
+	assert.Equal(t, logging.SeverityCritical, Severity(zapcore.FatalLevel))
+	assert.Equal(t, logging.SeverityWarning, Severity(zapcore.WarnLevel))
+}
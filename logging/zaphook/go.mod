@@ -0,0 +1,11 @@
+module github.com/philips-software/go-hsdp-api/logging/zaphook
+
+go 1.16
+
+require (
+	github.com/philips-software/go-hsdp-api v0.40.1
+	github.com/stretchr/testify v1.7.0
+	go.uber.org/zap v1.17.0
+)
+
+replace github.com/philips-software/go-hsdp-api => ../..
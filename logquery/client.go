@@ -0,0 +1,235 @@
+// Package logquery provides support for querying HSDP Logging
+package logquery
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+
+	autoconf "github.com/philips-software/go-hsdp-api/config"
+	"github.com/philips-software/go-hsdp-api/internal"
+
+	"github.com/google/go-querystring/query"
+	"github.com/philips-software/go-hsdp-api/iam"
+)
+
+const (
+	userAgent  = "go-hsdp-api/logquery/" + internal.LibraryVersion
+	apiVersion = "1"
+)
+
+// OptionFunc is the function signature function for options
+type OptionFunc func(*http.Request) error
+
+// Config contains the configuration of a client
+type Config struct {
+	BaseURL     string
+	Region      string
+	Environment string
+	// DecodeSanitized reverses logging.SanitizeEncode on the returned resources
+	DecodeSanitized bool
+	// RawMessages returns log messages as stored. Messages are ingested base64
+	// encoded and decoded by default, failing with ErrMessageNotEncoded when
+	// a message is not valid base64
+	RawMessages bool
+}
+
+// A Client manages communication with the HSDP Log query API
+type Client struct {
+	iamClient *iam.Client
+
+	config *Config
+
+	baseURL *url.URL
+
+	// User agent used when communicating with the HSDP Log query API.
+	UserAgent string
+
+	LogEvents *LogEventsService
+}
+
+// NewClient returns a new HSDP Log query API client. A configured IAM
+// client must be provided
+func NewClient(iamClient *iam.Client, config *Config) (*Client, error) {
+	if iamClient == nil {
+		return nil, ErrMissingIAMClient
+	}
+	if config == nil {
+		return nil, ErrMissingConfig
+	}
+	c := &Client{iamClient: iamClient, config: config, UserAgent: userAgent}
+	doAutoconf(config)
+	if err := c.SetBaseURL(c.config.BaseURL); err != nil {
+		return nil, err
+	}
+	c.LogEvents = &LogEventsService{client: c}
+	return c, nil
+}
+
+func doAutoconf(config *Config) {
+	if config.Region == "" || config.Environment == "" {
+		return
+	}
+	ac, err := autoconf.New(
+		autoconf.WithRegion(config.Region),
+		autoconf.WithEnv(config.Environment))
+	if err == nil {
+		queryService := ac.Service("logquery")
+		if queryService.URL != "" && config.BaseURL == "" {
+			config.BaseURL = queryService.URL
+		}
+	}
+}
+
+// SetBaseURL sets the base URL for API requests to a custom endpoint
+func (c *Client) SetBaseURL(urlStr string) error {
+	if urlStr == "" {
+		return ErrBaseURLCannotBeEmpty
+	}
+	// Make sure the given URL end with a slash
+	if !strings.HasSuffix(urlStr, "/") {
+		urlStr += "/"
+	}
+
+	var err error
+	c.baseURL, err = url.Parse(urlStr)
+	return err
+}
+
+// newRequest creates a new Log query API request. A relative URL path can be provided in
+// path, in which case it is resolved relative to the base URL of the Client.
+func (c *Client) newRequest(method, path string, opt interface{}, options []OptionFunc) (*http.Request, error) {
+	u := *c.baseURL
+	// Set the encoded opaque data
+	u.Opaque = c.baseURL.Path + path
+
+	if opt != nil {
+		q, err := query.Values(opt)
+		if err != nil {
+			return nil, err
+		}
+		u.RawQuery = q.Encode()
+	}
+
+	req := &http.Request{
+		Method:     method,
+		URL:        &u,
+		Proto:      "HTTP/1.1",
+		ProtoMajor: 1,
+		ProtoMinor: 1,
+		Header:     make(http.Header),
+		Host:       u.Host,
+	}
+
+	for _, fn := range options {
+		if fn == nil {
+			continue
+		}
+		if err := fn(req); err != nil {
+			return nil, err
+		}
+	}
+
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("Api-Version", apiVersion)
+	req.Header.Set("Authorization", "Bearer "+c.iamClient.Token())
+
+	if c.UserAgent != "" {
+		req.Header.Set("User-Agent", c.UserAgent)
+	}
+	return req, nil
+}
+
+// Response is a HSDP Log query API response. This wraps the standard http.Response
+type Response struct {
+	*http.Response
+}
+
+// Do executes a http request. If v implements the io.Writer
+// interface, the raw response body will be written to v, without attempting to
+// first decode it.
+func (c *Client) Do(req *http.Request, v interface{}) (*Response, error) {
+	resp, err := c.iamClient.HttpClient().Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	response := &Response{Response: resp}
+
+	err = checkResponse(resp)
+	if err != nil {
+		// even though there was an error, we still return the response
+		// in case the caller wants to inspect it further
+		return response, err
+	}
+
+	if v != nil {
+		if w, ok := v.(io.Writer); ok {
+			_, err = io.Copy(w, resp.Body)
+		} else {
+			err = json.NewDecoder(resp.Body).Decode(v)
+		}
+	}
+	return response, err
+}
+
+// ErrorResponse represents a Log query error response
+type ErrorResponse struct {
+	Response *http.Response `json:"-"`
+	Message  string
+}
+
+func (e *ErrorResponse) Error() string {
+	path, _ := url.QueryUnescape(e.Response.Request.URL.Opaque)
+	u := fmt.Sprintf("%s://%s%s", e.Response.Request.URL.Scheme, e.Response.Request.URL.Host, path)
+	return fmt.Sprintf("%s %s: %d %s", e.Response.Request.Method, u, e.Response.StatusCode, e.Message)
+}
+
+func checkResponse(r *http.Response) error {
+	switch r.StatusCode {
+	case 200, 201, 202, 204, 304:
+		return nil
+	}
+
+	errorResponse := &ErrorResponse{Response: r}
+	data, err := ioutil.ReadAll(r.Body)
+	if err == nil && data != nil {
+		var raw interface{}
+		if err := json.Unmarshal(data, &raw); err != nil {
+			errorResponse.Message = "failed to parse unknown error format"
+		} else {
+			errorResponse.Message = parseError(raw)
+		}
+	}
+	return errorResponse
+}
+
+func parseError(raw interface{}) string {
+	switch raw := raw.(type) {
+	case string:
+		return raw
+	case []interface{}:
+		var errs []string
+		for _, v := range raw {
+			errs = append(errs, parseError(v))
+		}
+		return fmt.Sprintf("[%s]", strings.Join(errs, ", "))
+	case map[string]interface{}:
+		var errs []string
+		for k, v := range raw {
+			errs = append(errs, fmt.Sprintf("{%s: %s}", k, parseError(v)))
+		}
+		sort.Strings(errs)
+		return strings.Join(errs, ", ")
+	case float64:
+		return fmt.Sprintf("%d", int64(raw))
+	default:
+		return fmt.Sprintf("failed to parse unexpected error type: %T", raw)
+	}
+}
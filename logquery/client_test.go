@@ -0,0 +1,93 @@
+package logquery
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/philips-software/go-hsdp-api/iam"
+	"github.com/stretchr/testify/assert"
+)
+
+var (
+	muxIAM      *http.ServeMux
+	serverIAM   *httptest.Server
+	muxIDM      *http.ServeMux
+	serverIDM   *httptest.Server
+	muxQuery    *http.ServeMux
+	serverQuery *httptest.Server
+
+	iamClient   *iam.Client
+	queryClient *Client
+)
+
+func setup(t *testing.T) func() {
+	muxIAM = http.NewServeMux()
+	serverIAM = httptest.NewServer(muxIAM)
+	muxIDM = http.NewServeMux()
+	serverIDM = httptest.NewServer(muxIDM)
+	muxQuery = http.NewServeMux()
+	serverQuery = httptest.NewServer(muxQuery)
+
+	var err error
+	iamClient, err = iam.NewClient(nil, &iam.Config{
+		OAuth2ClientID: "TestClient",
+		OAuth2Secret:   "Secret",
+		SharedKey:      "SharedKey",
+		SecretKey:      "SecretKey",
+		IAMURL:         serverIAM.URL,
+		IDMURL:         serverIDM.URL,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create iamClient: %v", err)
+	}
+	token := "44d20214-7879-4e35-923d-f9d4e01c9746"
+
+	muxIAM.HandleFunc("/authorize/oauth2/token", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "POST" {
+			t.Errorf("Expected ‘POST’ request")
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		_, _ = io.WriteString(w, `{
+    "scope": "mail",
+    "access_token": "`+token+`",
+    "refresh_token": "31f1a449-ef8e-4bfc-a227-4f2353fde547",
+    "expires_in": 1799,
+    "token_type": "Bearer"
+}`)
+	})
+
+	err = iamClient.Login("username", "password")
+	assert.Nil(t, err)
+
+	queryClient, err = NewClient(iamClient, &Config{
+		BaseURL: serverQuery.URL,
+	})
+	assert.Nilf(t, err, "failed to create queryClient: %v", err)
+
+	return func() {
+		serverIAM.Close()
+		serverIDM.Close()
+		serverQuery.Close()
+	}
+}
+
+func TestNewClient(t *testing.T) {
+	_, err := NewClient(nil, &Config{BaseURL: "https://example.com"})
+	assert.Equal(t, ErrMissingIAMClient, err)
+
+	teardown := setup(t)
+	defer teardown()
+
+	_, err = NewClient(iamClient, &Config{})
+	assert.Equal(t, ErrBaseURLCannotBeEmpty, err)
+	_, err = NewClient(iamClient, nil)
+	assert.Equal(t, ErrMissingConfig, err)
+
+	client, err := NewClient(iamClient, &Config{Region: "eu-west", Environment: "client-test"})
+	if assert.Nil(t, err) {
+		assert.Equal(t, "https://logquery-client-test.eu-west.philips-healthsuite.com/", client.baseURL.String())
+	}
+}
@@ -0,0 +1,14 @@
+package logquery
+
+import (
+	"errors"
+)
+
+// Exported Errors
+var (
+	ErrBaseURLCannotBeEmpty = errors.New("log query base URL cannot be empty")
+	ErrMissingIAMClient     = errors.New("missing IAM client")
+	ErrNoMorePages          = errors.New("no more pages")
+	ErrMissingConfig        = errors.New("missing config")
+	ErrMessageNotEncoded    = errors.New("log message is not base64 encoded")
+)
@@ -0,0 +1,176 @@
+package logquery
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/philips-software/go-hsdp-api/logging"
+)
+
+const logEventPath = "core/log/LogEvent"
+
+// LogEventsService searches ingested log events
+type LogEventsService struct {
+	client *Client
+}
+
+// SearchOptions describes the fields on which you can search for log events
+type SearchOptions struct {
+	// From and To limit the search to log events with a LogTime in [From, To]
+	From                *time.Time `url:"-"`
+	To                  *time.Time `url:"-"`
+	ApplicationName     *string    `url:"applicationName,omitempty"`
+	ApplicationInstance *string    `url:"applicationInstance,omitempty"`
+	ServerName          *string    `url:"serverName,omitempty"`
+	ServiceName         *string    `url:"serviceName,omitempty"`
+	Component           *string    `url:"component,omitempty"`
+	Severity            *string    `url:"severity,omitempty"`
+	TransactionID       *string    `url:"transactionId,omitempty"`
+	EventID             *string    `url:"eventId,omitempty"`
+	// Text searches the log message
+	Text  *string `url:"_content,omitempty"`
+	Count *int    `url:"_count,omitempty"`
+	Sort  *string `url:"_sort,omitempty"`
+}
+
+// searchQuery adds the logTime range to the SearchOptions query parameters
+type searchQuery struct {
+	SearchOptions
+	LogTime []string `url:"logTime,omitempty"`
+}
+
+// SearchResult is a page of log events
+type SearchResult struct {
+	Total     int
+	Resources []logging.Resource
+	// NextLink is the link to the next page, empty on the last page
+	NextLink string
+}
+
+// HasNextPage returns true if there are more results
+func (r *SearchResult) HasNextPage() bool {
+	return r != nil && r.NextLink != ""
+}
+
+type searchBundle struct {
+	ResourceType string `json:"resourceType"`
+	Total        int    `json:"total"`
+	Link         []struct {
+		Relation string `json:"relation"`
+		URL      string `json:"url"`
+	} `json:"link"`
+	Entry []struct {
+		Resource logging.Resource `json:"resource"`
+	} `json:"entry"`
+}
+
+// Search returns the first page of log events matching opt
+func (l *LogEventsService) Search(opt *SearchOptions, options ...OptionFunc) (*SearchResult, *Response, error) {
+	q := searchQuery{}
+	if opt != nil {
+		q.SearchOptions = *opt
+		if opt.From != nil {
+			q.LogTime = append(q.LogTime, "ge"+opt.From.UTC().Format(logging.TimeFormat))
+		}
+		if opt.To != nil {
+			q.LogTime = append(q.LogTime, "le"+opt.To.UTC().Format(logging.TimeFormat))
+		}
+	}
+	req, err := l.client.newRequest(http.MethodGet, logEventPath, &q, options)
+	if err != nil {
+		return nil, nil, err
+	}
+	return l.do(req)
+}
+
+// Next returns the page following result. ErrNoMorePages is returned on the last page
+func (l *LogEventsService) Next(result *SearchResult, options ...OptionFunc) (*SearchResult, *Response, error) {
+	if !result.HasNextPage() {
+		return nil, nil, ErrNoMorePages
+	}
+	next, err := url.Parse(result.NextLink)
+	if err != nil {
+		return nil, nil, err
+	}
+	req, err := l.client.newRequest(http.MethodGet, logEventPath, nil, options)
+	if err != nil {
+		return nil, nil, err
+	}
+	req.URL.RawQuery = next.RawQuery
+	return l.do(req)
+}
+
+// SearchAll pages through all log events matching opt. Use max to limit the
+// number of returned resources, zero means no limit
+func (l *LogEventsService) SearchAll(opt *SearchOptions, max int, options ...OptionFunc) ([]logging.Resource, *Response, error) {
+	result, resp, err := l.Search(opt, options...)
+	var resources []logging.Resource
+	for err == nil {
+		resources = append(resources, result.Resources...)
+		if max > 0 && len(resources) >= max {
+			return resources[:max], resp, nil
+		}
+		if !result.HasNextPage() {
+			return resources, resp, nil
+		}
+		result, resp, err = l.Next(result, options...)
+	}
+	return resources, resp, err
+}
+
+// WaitFor polls until a log event matching opt is found or ctx is done.
+// It is meant for tests asserting that a log event was ingested
+func (l *LogEventsService) WaitFor(ctx context.Context, opt *SearchOptions, interval time.Duration, options ...OptionFunc) (*logging.Resource, error) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		result, _, err := l.Search(opt, options...)
+		if err != nil {
+			return nil, err
+		}
+		if len(result.Resources) > 0 {
+			return &result.Resources[0], nil
+		}
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+func (l *LogEventsService) do(req *http.Request) (*SearchResult, *Response, error) {
+	var bundle searchBundle
+	resp, err := l.client.Do(req, &bundle)
+	if err != nil {
+		return nil, resp, err
+	}
+	result := &SearchResult{Total: bundle.Total}
+	for _, link := range bundle.Link {
+		if link.Relation == "next" {
+			result.NextLink = link.URL
+		}
+	}
+	for _, e := range bundle.Entry {
+		r := e.Resource
+		// Messages are ingested base64 encoded
+		if !l.client.config.RawMessages {
+			decoded, err := base64.StdEncoding.DecodeString(r.LogData.Message)
+			if err != nil {
+				return nil, resp, fmt.Errorf("resource %s: %w", r.ID, ErrMessageNotEncoded)
+			}
+			r.LogData.Message = string(decoded)
+		}
+		if l.client.config.DecodeSanitized {
+			if err := logging.DecodeResource(&r); err != nil {
+				return nil, resp, err
+			}
+		}
+		result.Resources = append(result.Resources, r)
+	}
+	return result, resp, nil
+}
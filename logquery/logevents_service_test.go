@@ -0,0 +1,180 @@
+package logquery
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func logEventJSON(id, message string) string {
+	return fmt.Sprintf(`{
+        "resourceType": "LogEvent",
+        "id": "%s",
+        "applicationName": "app",
+        "eventId": "1",
+        "transactionId": "abc",
+        "logTime": "2021-06-01T10:00:00.000Z",
+        "severity": "ERROR",
+        "logData": {"message": "%s"}
+      }`, id, base64.StdEncoding.EncodeToString([]byte(message)))
+}
+
+func TestSearch(t *testing.T) {
+	teardown := setup(t)
+	defer teardown()
+
+	muxQuery.HandleFunc("/core/log/LogEvent", func(w http.ResponseWriter, r *http.Request) {
+		if !assert.Equal(t, http.MethodGet, r.Method) {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		assert.Equal(t, "1", r.Header.Get("Api-Version"))
+		q := r.URL.Query()
+		w.Header().Set("Content-Type", "application/json")
+		if q.Get("_page") == "2" {
+			_, _ = io.WriteString(w, `{"resourceType": "Bundle", "total": 2, "entry": [{"resource": `+logEventJSON("2", "second")+`}]}`)
+			return
+		}
+		assert.Equal(t, "app", q.Get("applicationName"))
+		assert.Equal(t, "ERROR", q.Get("severity"))
+		assert.Equal(t, "abc", q.Get("transactionId"))
+		assert.Equal(t, "timeout", q.Get("_content"))
+		assert.Equal(t, []string{"ge2021-06-01T00:00:00.000Z", "le2021-06-02T00:00:00.000Z"}, q["logTime"])
+		_, _ = io.WriteString(w, `{
+  "resourceType": "Bundle",
+  "total": 2,
+  "link": [{"relation": "next", "url": "`+serverQuery.URL+`/core/log/LogEvent?_page=2"}],
+  "entry": [{"resource": `+logEventJSON("1", "first timeout")+`}]
+}`)
+	})
+
+	from := time.Date(2021, 6, 1, 0, 0, 0, 0, time.UTC)
+	to := from.Add(24 * time.Hour)
+	app := "app"
+	severity := "ERROR"
+	transactionID := "abc"
+	text := "timeout"
+	opt := &SearchOptions{
+		From:            &from,
+		To:              &to,
+		ApplicationName: &app,
+		Severity:        &severity,
+		TransactionID:   &transactionID,
+		Text:            &text,
+	}
+	result, resp, err := queryClient.LogEvents.Search(opt)
+	if !assert.Nil(t, err) || !assert.NotNil(t, resp) {
+		return
+	}
+	assert.Equal(t, 2, result.Total)
+	assert.True(t, result.HasNextPage())
+	if assert.Len(t, result.Resources, 1) {
+		assert.Equal(t, "first timeout", result.Resources[0].LogData.Message)
+		assert.Equal(t, "abc", result.Resources[0].TransactionID)
+	}
+
+	next, _, err := queryClient.LogEvents.Next(result)
+	if assert.Nil(t, err) {
+		assert.False(t, next.HasNextPage())
+		_, _, err = queryClient.LogEvents.Next(next)
+		assert.Equal(t, ErrNoMorePages, err)
+	}
+
+	all, _, err := queryClient.LogEvents.SearchAll(opt, 0)
+	if assert.Nil(t, err) && assert.Len(t, all, 2) {
+		assert.Equal(t, "second", all[1].LogData.Message)
+	}
+	all, _, err = queryClient.LogEvents.SearchAll(opt, 1)
+	assert.Nil(t, err)
+	assert.Len(t, all, 1)
+
+	found, err := queryClient.LogEvents.WaitFor(context.Background(), opt, time.Millisecond)
+	if assert.Nil(t, err) {
+		assert.Equal(t, "1", found.ID)
+	}
+}
+
+func TestWaitForTimeout(t *testing.T) {
+	teardown := setup(t)
+	defer teardown()
+
+	muxQuery.HandleFunc("/core/log/LogEvent", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = io.WriteString(w, `{"resourceType": "Bundle", "total": 0}`)
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	_, err := queryClient.LogEvents.WaitFor(ctx, &SearchOptions{}, 5*time.Millisecond)
+	assert.Equal(t, context.DeadlineExceeded, err)
+
+}
+
+func TestSearchError(t *testing.T) {
+	teardown := setup(t)
+	defer teardown()
+
+	muxQuery.HandleFunc("/core/log/LogEvent", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusForbidden)
+		_, _ = io.WriteString(w, `{"issue": [{"severity": "error", "diagnostics": "forbidden"}]}`)
+	})
+	_, resp, err := queryClient.LogEvents.Search(nil)
+	assert.NotNil(t, err)
+	if assert.NotNil(t, resp) {
+		assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+	}
+}
+
+func TestSearchDecodeSanitized(t *testing.T) {
+	teardown := setup(t)
+	defer teardown()
+
+	muxQuery.HandleFunc("/core/log/LogEvent", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = io.WriteString(w, `{"resourceType": "Bundle", "total": 1, "entry": [{"resource": {
+        "resourceType": "LogEvent",
+        "id": "1",
+        "logData": {"message": "`+base64.StdEncoding.EncodeToString([]byte("a%3Bb"))+`"},
+        "custom": {"q": "x%26y"}
+      }}]}`)
+	})
+
+	queryClient.config.DecodeSanitized = true
+	result, _, err := queryClient.LogEvents.Search(nil)
+	if !assert.Nil(t, err) || !assert.Len(t, result.Resources, 1) {
+		return
+	}
+	assert.Equal(t, "a;b", result.Resources[0].LogData.Message)
+	assert.JSONEq(t, `{"q": "x&y"}`, string(result.Resources[0].Custom))
+}
+
+func TestSearchMessageEncoding(t *testing.T) {
+	teardown := setup(t)
+	defer teardown()
+
+	muxQuery.HandleFunc("/core/log/LogEvent", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = io.WriteString(w, `{"resourceType": "Bundle", "total": 1, "entry": [{"resource": {
+        "resourceType": "LogEvent",
+        "id": "1",
+        "logData": {"message": "not encoded!"}
+      }}]}`)
+	})
+
+	_, _, err := queryClient.LogEvents.Search(nil)
+	assert.ErrorIs(t, err, ErrMessageNotEncoded)
+
+	queryClient.config.RawMessages = true
+	result, _, err := queryClient.LogEvents.Search(nil)
+	if !assert.Nil(t, err) || !assert.Len(t, result.Resources, 1) {
+		return
+	}
+	assert.Equal(t, "not encoded!", result.Resources[0].LogData.Message)
+}